@@ -1,3 +1,98 @@
+// Command tis-100 is the command line front end of the TIS-100 simulator.
+//
+// Usage:
+//
+//	tis-100 <command> [flags] [arguments]
+//
+// The commands are:
+//
+//	run    load a puzzle and a solution, execute it and report PASS/FAIL
+//	score  print the cycles, nodes and instructions of a passing solution
+//	tui    step through a solution interactively in a terminal view of the grid
+//	profile report how every node spends its cycles with a heatmap of the executed lines
+//	replay play back a trace recorded with `run -trace` in the terminal view
 package main
 
-func main() {}
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/lekomish/tis-100/internal/engine"
+)
+
+// Exit codes returned by the commands.
+const (
+	exitPass  = 0 // the command succeeded (e.g., the solution passed)
+	exitFail  = 1 // the solution was executed but did not pass
+	exitError = 2 // wrong usage or the puzzle/solution could not be loaded
+)
+
+// stdin is the input interactive commands read from, replaceable in tests.
+var stdin io.Reader = os.Stdin
+
+// command describes a single subcommand of the CLI.
+type command struct {
+	name    string
+	summary string
+	run     func(args []string, stdout, stderr io.Writer) int
+}
+
+// commands lists all available subcommands in the order they appear in the usage message.
+var commands = []command{
+	{name: "run", summary: "load a puzzle and a solution, execute it and report PASS/FAIL", run: runCommand},
+	{name: "score", summary: "print the cycles, nodes and instructions of a passing solution", run: scoreCommand},
+	{name: "tui", summary: "step through a solution interactively in a terminal view of the grid", run: tuiCommand},
+	{name: "profile", summary: "report how every node spends its cycles with a heatmap of the executed lines", run: profileCommand},
+	{name: "replay", summary: "play back a trace recorded with `run -trace` in the terminal view", run: replayCommand},
+}
+
+func main() {
+	os.Exit(execute(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// execute dispatches the arguments to the matching subcommand and returns its exit code.
+func execute(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		usage(stderr)
+		return exitError
+	}
+
+	name := args[0]
+	if name == "help" || name == "-h" || name == "--help" {
+		usage(stdout)
+		return exitPass
+	}
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd.run(args[1:], stdout, stderr)
+		}
+	}
+
+	fmt.Fprintf(stderr, "tis-100: unknown command %q\n", name)
+	usage(stderr)
+	return exitError
+}
+
+// usage prints the list of available commands.
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: tis-100 <command> [flags] [arguments]")
+	fmt.Fprintln(w, "Commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-8s %s\n", cmd.name, cmd.summary)
+	}
+}
+
+// reportError prints an error preventing a command from running a solution. Compile errors
+// are printed one per line, located in the solution file like compiler diagnostics.
+func reportError(w io.Writer, codePath string, err error) {
+	var errs engine.CompileErrors
+	if !errors.As(err, &errs) {
+		fmt.Fprintf(w, "tis-100: %v\n", err)
+		return
+	}
+	for _, e := range errs {
+		fmt.Fprintf(w, "%s:%v\n", codePath, e)
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/lekomish/tis-100/internal/engine"
+	"github.com/lekomish/tis-100/internal/loader"
+	"github.com/lekomish/tis-100/internal/profile"
+	"github.com/lekomish/tis-100/internal/tui"
+)
+
+// profileCommand implements `tis-100 profile`: it runs a solution against a single test set
+// and reports how every node spent its cycles, with a heatmap of the executed lines.
+func profileCommand(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("profile", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	seed := flags.Int64("seed", 0, "seed of the test set")
+	maxCycles := flags.Int("max-cycles", engine.DefaultMaxCycles, "maximum number of cycles to simulate")
+	var mode engine.Mode
+	addModeFlag(flags, &mode)
+	plain := flags.Bool("plain", false, "do not use colours")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: tis-100 profile [flags] <puzzle.lua> <solution.tis>")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return exitError
+	}
+	if flags.NArg() != 2 {
+		flags.Usage()
+		return exitError
+	}
+
+	puzzle, err := loader.LoadPuzzleWithSeed(flags.Arg(0), *seed)
+	if err != nil {
+		fmt.Fprintf(stderr, "tis-100: %v\n", err)
+		return exitError
+	}
+	code, err := loader.LoadCode(flags.Arg(1))
+	if err != nil {
+		fmt.Fprintf(stderr, "tis-100: %v\n", err)
+		return exitError
+	}
+	eng, err := engine.NewEngineWithMode(puzzle.Streams, puzzle.Layout, code, mode)
+	if err != nil {
+		reportError(stderr, flags.Arg(1), err)
+		return exitError
+	}
+
+	profiler := profile.NewProfiler(eng)
+	res := eng.Run(engine.RunOptions{MaxCycles: *maxCycles})
+	stats := profiler.Stats()
+
+	frame := tui.NewProfileFrame(eng, puzzle, code, stats)
+	frame.Status = fmt.Sprintf("stopped: %s", res.Reason)
+	if res.Err != nil {
+		frame.Status += fmt.Sprintf(": %v", res.Err)
+	}
+	fmt.Fprint(stdout, tui.Render(frame, tui.Options{ANSI: !*plain}))
+	fmt.Fprintln(stdout)
+	writeProfileTable(stdout, stats)
+
+	if !res.Passed() {
+		return exitFail
+	}
+	return exitPass
+}
+
+// writeProfileTable prints the statistics of every active node and names the busiest one.
+func writeProfileTable(w io.Writer, stats *profile.Stats) {
+	fmt.Fprintf(w, "%-5s %6s %6s %6s %6s %6s %6s %6s\n", "node", "exec", "read", "write", "up", "down", "left", "right")
+	for i := range stats.Nodes {
+		s := &stats.Nodes[i]
+		if !s.Active() {
+			continue
+		}
+		fmt.Fprintf(
+			w, "%-5s %6d %6d %6d %6d %6d %6d %6d\n",
+			fmt.Sprintf("@%d", i+1), s.Executing, s.BlockedRead, s.BlockedWrite,
+			s.Sent[engine.PortUp], s.Sent[engine.PortDown], s.Sent[engine.PortLeft], s.Sent[engine.PortRight],
+		)
+	}
+	if busiest := stats.Busiest(); busiest != -1 {
+		fmt.Fprintf(
+			w, "busiest node: @%d (executing %.0f%% of %d cycles)\n",
+			busiest+1, 100*stats.Nodes[busiest].Utilisation(), stats.Cycles,
+		)
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+/* TESTS */
+
+// --- profileCommand ---
+func TestProfileCommand(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	code := execute([]string{"profile", "-plain", selfTestPuzzle, selfTestSolution}, &stdout, &stderr)
+	require.Equal(t, exitPass, code, stderr.String())
+	require.Contains(t, stdout.String(), "stopped: completed")
+	require.Contains(t, stdout.String(), "│█MOV UP DOWN")
+	require.Contains(t, stdout.String(), "node    exec   read  write     up   down   left  right")
+	require.Contains(t, stdout.String(), "@1        20      5     20      0     20      0      0")
+	require.Contains(t, stdout.String(), "busiest node: @1 (executing 44% of 45 cycles)")
+	require.NotContains(t, stdout.String(), "\x1b")
+}
+
+func TestProfileCommandFailingSolution(t *testing.T) {
+	solution := setupSolution(t, map[int][]string{0: {"MOV UP DOWN"}})
+
+	var stdout, stderr bytes.Buffer
+	code := execute([]string{"profile", "-plain", selfTestPuzzle, solution}, &stdout, &stderr)
+	require.Equal(t, exitFail, code, stderr.String())
+	require.Contains(t, stdout.String(), "stopped: deadlock")
+}
+
+func TestProfileCommandWrongArguments(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	code := execute([]string{"profile", selfTestPuzzle}, &stdout, &stderr)
+	require.Equal(t, exitError, code)
+	require.Contains(t, stderr.String(), "Usage: tis-100 profile")
+}
+
+// writeProfileTable -> covered in previous tests
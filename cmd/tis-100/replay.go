@@ -0,0 +1,61 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/lekomish/tis-100/internal/trace"
+	"github.com/lekomish/tis-100/internal/tui"
+)
+
+// replayCommand implements `tis-100 replay`: it plays a trace recorded with `tis-100 run -trace`
+// back in the terminal view of the grid, without the puzzle's Lua file.
+func replayCommand(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("replay", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	delay := flags.Duration("delay", tui.DefaultDelay, "pause between two frames while running")
+	plain := flags.Bool("plain", false, "do not use colours or clear the screen")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: tis-100 replay [flags] <trace>")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return exitError
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return exitError
+	}
+
+	t, err := readTrace(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintf(stderr, "tis-100: %v\n", err)
+		return exitError
+	}
+	player, err := tui.NewPlayer(t)
+	if err != nil {
+		fmt.Fprintf(stderr, "tis-100: %v\n", err)
+		return exitError
+	}
+	player.Delay = *delay
+	player.Options = tui.Options{ANSI: !*plain}
+	player.Loop(stdin, stdout)
+	return exitPass
+}
+
+// readTrace reads the trace file at path.
+func readTrace(path string) (*trace.Trace, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	t, err := trace.Read(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return t, nil
+}
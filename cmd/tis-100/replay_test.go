@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+/* TESTS */
+
+// --- replayCommand ---
+func TestReplayCommand(t *testing.T) {
+	tracePath := filepath.Join(t.TempDir(), "run.trace")
+
+	var stdout, stderr bytes.Buffer
+	code := execute([]string{"run", "-trace", tracePath, selfTestPuzzle, selfTestSolution}, &stdout, &stderr)
+	require.Equal(t, exitPass, code, stderr.String())
+
+	setupStdin(t, "s 45\nq\n")
+	stdout.Reset()
+	code = execute([]string{"replay", "-plain", tracePath}, &stdout, &stderr)
+	require.Equal(t, exitPass, code, stderr.String())
+	require.Contains(t, stdout.String(), "SELF-TEST DIAGNOSTIC - cycle 0")
+	require.Contains(t, stdout.String(), "SELF-TEST DIAGNOSTIC - cycle 45")
+	require.Contains(t, stdout.String(), "stopped: completed")
+}
+
+func TestReplayCommandFailingTest(t *testing.T) {
+	tracePath := filepath.Join(t.TempDir(), "run.trace")
+	solution := setupSolution(t, map[int][]string{0: {"MOV UP DOWN"}})
+
+	var stdout, stderr bytes.Buffer
+	code := execute([]string{"run", "-max-cycles", "50", "-trace", tracePath, selfTestPuzzle, solution}, &stdout, &stderr)
+	require.Equal(t, exitFail, code, stderr.String())
+
+	setupStdin(t, "s 100\n")
+	stdout.Reset()
+	code = execute([]string{"replay", "-plain", tracePath}, &stdout, &stderr)
+	require.Equal(t, exitPass, code, stderr.String())
+	require.Contains(t, stdout.String(), "stopped: deadlock")
+}
+
+func TestReplayCommandInvalidTrace(t *testing.T) {
+	tracePath := filepath.Join(t.TempDir(), "run.trace")
+	require.NoError(t, os.WriteFile(tracePath, []byte(`{"version":2}`), 0o600))
+
+	var stdout, stderr bytes.Buffer
+	code := execute([]string{"replay", tracePath}, &stdout, &stderr)
+	require.Equal(t, exitError, code)
+	require.Contains(t, stderr.String(), "unsupported trace version 2")
+
+	code = execute([]string{"replay", filepath.Join(t.TempDir(), "missing.trace")}, &stdout, &stderr)
+	require.Equal(t, exitError, code)
+	require.Contains(t, stderr.String(), "no such file or directory")
+}
+
+func TestReplayCommandWrongArguments(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	code := execute([]string{"replay"}, &stdout, &stderr)
+	require.Equal(t, exitError, code)
+	require.Contains(t, stderr.String(), "Usage: tis-100 replay")
+}
+
+// readTrace -> covered in previous tests
+// writeTrace -> covered in previous tests
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/lekomish/tis-100/internal/engine"
+	"github.com/lekomish/tis-100/internal/model"
+	"github.com/lekomish/tis-100/internal/runner"
+	"github.com/lekomish/tis-100/internal/tui"
+)
+
+// reportContext is the number of values shown on each side of the first difference.
+const reportContext = 3
+
+// describeFailure explains in a single line why a test run did not pass.
+// Mismatches are described by the failed check of the puzzle or the first wrong or extra value.
+func describeFailure(run *runner.TestRun) string {
+	res := run.Result
+	switch res.Reason {
+	case engine.RuntimeError, engine.Deadlock, engine.Livelock:
+		return fmt.Sprintf("%s: %v", res.Reason, res.Err)
+	case engine.Mismatch:
+		if v := run.FailedCheck(); v != nil {
+			return v.String()
+		}
+		for _, c := range run.Engine.CompareOutputs() {
+			if wrong := c.Wrong(); len(wrong) > 0 {
+				return c.Stream + ": " + describeDifference(wrong[0])
+			}
+		}
+	}
+	return res.Reason.String()
+}
+
+// describeDifference explains a single difference between an output and its expected stream.
+func describeDifference(diff engine.Difference) string {
+	switch diff.Kind {
+	case engine.ExtraValue:
+		return fmt.Sprintf("unexpected extra value %d at cycle %d", diff.Actual, diff.Cycle)
+	case engine.MissingValue:
+		return fmt.Sprintf("value #%d is missing, expected %d", diff.Index+1, diff.Expected)
+	default:
+		return fmt.Sprintf(
+			"value #%d is %d, expected %d at cycle %d",
+			diff.Index+1, diff.Actual, diff.Expected, diff.Cycle,
+		)
+	}
+}
+
+// writeMismatchReport prints every output of a failed test run that differs from its expected stream:
+// the values around the first difference side by side, the emission cycle of every wrong value
+// and the number of missing values. Images are drawn side by side instead (see `writeImageReport`).
+func writeMismatchReport(w io.Writer, run *runner.TestRun) {
+	if run.Result.Reason == engine.RuntimeError {
+		return
+	}
+
+	for _, c := range run.Engine.CompareOutputs() {
+		if c.Equal() {
+			continue
+		}
+
+		fmt.Fprintf(w, "  %s:\n", c.Stream)
+		if c.Image {
+			writeImageReport(w, c)
+			continue
+		}
+		from := max(c.First().Index-reportContext, 0)
+		to := min(c.First().Index+reportContext+1, max(len(c.Expected), len(c.Actual)))
+		fmt.Fprintf(w, "    index:    %s\n", formatColumns(from, to, func(i int) string { return fmt.Sprint(i + 1) }))
+		fmt.Fprintf(w, "    expected: %s\n", formatColumns(from, to, valueAt(c.Expected)))
+		fmt.Fprintf(w, "    actual:   %s\n", formatColumns(from, to, valueAt(c.Actual)))
+
+		if wrong := c.Wrong(); len(wrong) > 0 {
+			emitted := make([]string, 0, len(wrong))
+			for _, diff := range wrong {
+				emitted = append(emitted, fmt.Sprintf("#%d at cycle %d", diff.Index+1, diff.Cycle))
+			}
+			fmt.Fprintf(w, "    wrong values: %s\n", strings.Join(emitted, ", "))
+		}
+		if missing := len(c.Differences) - len(c.Wrong()); missing > 0 {
+			fmt.Fprintf(w, "    missing values: %d\n", missing)
+		}
+	}
+}
+
+// writeImageReport draws the expected and the actual image of a differing image stream side by side
+// and locates the first wrong pixel.
+func writeImageReport(w io.Writer, c *engine.Comparison) {
+	expected, actual := tui.RenderImage(c.Expected, tui.Options{}), tui.RenderImage(c.Actual, tui.Options{})
+	fmt.Fprintf(w, "    %-*s  %s\n", model.ImageWidth+2, "expected:", "actual:")
+	for i := range expected {
+		fmt.Fprintf(w, "    %s  %s\n", expected[i], actual[i])
+	}
+
+	first := c.First()
+	drawn := "never drawn"
+	if first.Cycle > 0 {
+		drawn = fmt.Sprintf("drawn at cycle %d", first.Cycle)
+	}
+	fmt.Fprintf(w, "    wrong pixels: %d, the first at %d,%d is %d, expected %d, %s\n",
+		len(c.Differences), first.Index%model.ImageWidth, first.Index/model.ImageWidth, first.Actual, first.Expected, drawn)
+}
+
+// formatColumns formats the cells of the given index range as right aligned columns.
+func formatColumns(from, to int, cell func(i int) string) string {
+	var builder strings.Builder
+	for i := from; i < to; i++ {
+		fmt.Fprintf(&builder, "%6s", cell(i))
+	}
+	return builder.String()
+}
+
+// valueAt returns a cell function printing the values, with `-` past their end.
+func valueAt(values []int16) func(i int) string {
+	return func(i int) string {
+		if i >= len(values) {
+			return "-"
+		}
+		return fmt.Sprint(values[i])
+	}
+}
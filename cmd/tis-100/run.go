@@ -0,0 +1,137 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/lekomish/tis-100/internal/engine"
+	"github.com/lekomish/tis-100/internal/loader"
+	"github.com/lekomish/tis-100/internal/model"
+	"github.com/lekomish/tis-100/internal/runner"
+	"github.com/lekomish/tis-100/internal/trace"
+)
+
+// runCommand implements `tis-100 run`: it loads a puzzle and a solution,
+// simulates the solution against every test set and prints a PASS/FAIL verdict.
+func runCommand(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("run", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	opts := addRunnerFlags(flags)
+	tracePath := flags.String("trace", "", "write the trace of the first failing test (or of the first test) to this file")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: tis-100 run [flags] <puzzle.lua> <solution.tis>")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return exitError
+	}
+	if flags.NArg() != 2 {
+		flags.Usage()
+		return exitError
+	}
+
+	code, runs, err := runSolution(flags.Arg(0), flags.Arg(1), *opts)
+	if err != nil {
+		reportError(stderr, flags.Arg(1), err)
+		return exitError
+	}
+
+	passed := 0
+	for i, run := range runs {
+		if run.Result.Passed() {
+			passed++
+			fmt.Fprintf(stdout, "test %d (seed %d): PASS (%d cycles)\n", i+1, run.Seed, run.Result.Cycles)
+			continue
+		}
+		fmt.Fprintf(
+			stdout, "test %d (seed %d): FAIL: %s (%d cycles)\n",
+			i+1, run.Seed, describeFailure(run), run.Result.Cycles,
+		)
+		writeMismatchReport(stdout, run)
+	}
+
+	if *tracePath != "" {
+		if err := writeTrace(*tracePath, code, runs, *opts); err != nil {
+			fmt.Fprintf(stderr, "tis-100: %v\n", err)
+			return exitError
+		}
+	}
+
+	title := runs[0].Puzzle.Title
+	if passed == len(runs) {
+		fmt.Fprintf(stdout, "PASS: %s (%d/%d tests)\n", title, passed, len(runs))
+		return exitPass
+	}
+	fmt.Fprintf(stdout, "FAIL: %s (%d/%d tests passed)\n", title, passed, len(runs))
+	return exitFail
+}
+
+// writeTrace records the first failing test, or the first test if all passed, into a trace file.
+// Execution is deterministic, so running the test again gives the same run.
+func writeTrace(path string, code *model.Code, runs []*runner.TestRun, opts runner.Options) error {
+	run := runs[0]
+	for _, r := range runs {
+		if !r.Result.Passed() {
+			run = r
+			break
+		}
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	_, err = trace.Record(f, run.Puzzle, code, opts.Mode, engine.RunOptions{
+		MaxCycles:        opts.MaxCycles,
+		StopOnFirstWrong: opts.StopOnFirstWrong,
+		DetectLivelock:   opts.DetectLivelock,
+		LivelockInterval: opts.LivelockInterval,
+	})
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// addRunnerFlags registers the flags selecting the test sets and the cycle limit of the runs.
+func addRunnerFlags(flags *flag.FlagSet) *runner.Options {
+	opts := &runner.Options{}
+	flags.Int64Var(&opts.Seed, "seed", 0, "seed of the first test set")
+	flags.IntVar(&opts.Tests, "tests", runner.DefaultTests, "number of test sets with consecutive seeds")
+	flags.IntVar(&opts.Random, "random", 0, "number of additional test sets with random seeds")
+	flags.IntVar(&opts.MaxCycles, "max-cycles", engine.DefaultMaxCycles, "maximum number of cycles to simulate")
+	addModeFlag(flags, &opts.Mode)
+	flags.BoolVar(&opts.StopOnFirstWrong, "stop-on-first-wrong", false, "stop a test as soon as an output receives a wrong value")
+	flags.BoolVar(&opts.DetectLivelock, "detect-livelock", false, "stop a test as soon as the machine state repeats")
+	flags.IntVar(&opts.LivelockInterval, "livelock-interval", 1, "number of cycles between two states recorded by -detect-livelock")
+	return opts
+}
+
+// addModeFlag registers the flag selecting the limits the solution is compiled with,
+// the ones of the original hardware by default.
+func addModeFlag(flags *flag.FlagSet, mode *engine.Mode) {
+	*mode = engine.ModeStrict
+	flags.Func("mode", "compile `mode`: strict (limits of the original hardware, the default) or sandbox", func(name string) error {
+		m, err := engine.ParseMode(name)
+		*mode = m
+		return err
+	})
+}
+
+// runSolution loads a solution and runs it against the test sets of a puzzle.
+func runSolution(puzzlePath, codePath string, opts runner.Options) (*model.Code, []*runner.TestRun, error) {
+	if opts.Tests+opts.Random <= 0 {
+		return nil, nil, fmt.Errorf("at least one test set is required")
+	}
+	code, err := loader.LoadCode(codePath)
+	if err != nil {
+		return nil, nil, err
+	}
+	runs, err := runner.Run(puzzlePath, code, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	return code, runs, nil
+}
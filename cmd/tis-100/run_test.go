@@ -0,0 +1,271 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lekomish/tis-100/internal/model"
+)
+
+const (
+	selfTestPuzzle   = "../../puzzles/self-test diagnostic.lua"
+	selfTestSolution = "../../puzzles/self-test diagnostic.tis"
+	templatePuzzle   = "../../puzzles/template.lua"
+	templateSolution = "../../puzzles/template.tis"
+	imagePuzzle      = "../../puzzles/image test pattern.lua"
+	imageSolution    = "../../puzzles/image test pattern.tis"
+	checkedPuzzle    = "../../puzzles/unordered echo.lua"
+	checkedSolution  = "../../puzzles/unordered echo.tis"
+)
+
+/* TESTS */
+
+// --- execute ---
+func TestExecuteWithoutArguments(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	code := execute(nil, &stdout, &stderr)
+	require.Equal(t, exitError, code)
+	require.Contains(t, stderr.String(), "Usage: tis-100")
+}
+
+func TestExecuteWithUnknownCommand(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	code := execute([]string{"unknown"}, &stdout, &stderr)
+	require.Equal(t, exitError, code)
+	require.Contains(t, stderr.String(), "unknown command \"unknown\"")
+}
+
+// --- runCommand ---
+func TestRunCommandPass(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	code := execute([]string{"run", selfTestPuzzle, selfTestSolution}, &stdout, &stderr)
+	require.Equal(t, exitPass, code, stderr.String())
+	require.Contains(t, stdout.String(), "test 1 (seed 0): PASS")
+	require.Contains(t, stdout.String(), "test 3 (seed 2): PASS")
+	require.Contains(t, stdout.String(), "PASS: SELF-TEST DIAGNOSTIC (3/3 tests)")
+}
+
+func TestRunCommandSeededTests(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	code := execute(
+		[]string{"run", "-seed", "40", "-tests", "2", "-random", "1", selfTestPuzzle, selfTestSolution},
+		&stdout,
+		&stderr,
+	)
+	require.Equal(t, exitPass, code, stderr.String())
+	require.Contains(t, stdout.String(), "test 1 (seed 40): PASS")
+	require.Contains(t, stdout.String(), "test 2 (seed 41): PASS")
+	require.Contains(t, stdout.String(), "test 3 (seed ")
+	require.Contains(t, stdout.String(), "PASS: SELF-TEST DIAGNOSTIC (3/3 tests)")
+}
+
+func TestRunCommandWithoutTests(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	code := execute([]string{"run", "-tests", "0", selfTestPuzzle, selfTestSolution}, &stdout, &stderr)
+	require.Equal(t, exitError, code)
+	require.Contains(t, stderr.String(), "at least one test set is required")
+}
+
+func TestRunCommandStall(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	code := execute([]string{"run", templatePuzzle, templateSolution}, &stdout, &stderr)
+	require.Equal(t, exitFail, code)
+	require.Contains(t, stdout.String(), "test 1 (seed 0): FAIL: deadlock: OUT waits to read UP from @9, which has no code")
+	require.Contains(t, stdout.String(), "FAIL: TEMPLATE (0/3 tests passed)")
+}
+
+func TestRunCommandMismatch(t *testing.T) {
+	// doubles nothing: the template expects every input value doubled
+	solution := setupSolution(t, map[int][]string{
+		0: {"MOV UP DOWN"},
+		4: {"MOV UP DOWN"},
+		8: {"MOV UP DOWN"},
+	})
+
+	var stdout, stderr bytes.Buffer
+	code := execute([]string{"run", templatePuzzle, solution}, &stdout, &stderr)
+	require.Equal(t, exitFail, code)
+	require.Contains(t, stdout.String(), "OUT: value #1 is")
+	require.Contains(t, stdout.String(), "    expected: ")
+	require.Contains(t, stdout.String(), "    wrong values: #1 at cycle ")
+	require.NotContains(t, stdout.String(), "missing values")
+}
+
+func TestRunCommandStopOnFirstWrong(t *testing.T) {
+	solution := setupSolution(t, map[int][]string{
+		0: {"MOV UP DOWN"},
+		4: {"MOV UP DOWN"},
+		8: {"MOV UP DOWN"},
+	})
+
+	var stdout, stderr bytes.Buffer
+	code := execute(
+		[]string{"run", "-tests", "1", "-stop-on-first-wrong", templatePuzzle, solution},
+		&stdout,
+		&stderr,
+	)
+	require.Equal(t, exitFail, code)
+	require.Contains(t, stdout.String(), "FAIL: OUT: value #1 is")
+	require.Contains(t, stdout.String(), "    actual:   ")
+	require.Contains(t, stdout.String(), "    wrong values: #1 at cycle 5\n")
+	require.Contains(t, stdout.String(), "    missing values: ")
+}
+
+func TestRunCommandImage(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	code := execute([]string{"run", imagePuzzle, imageSolution}, &stdout, &stderr)
+	require.Equal(t, exitPass, code, stderr.String())
+	require.Contains(t, stdout.String(), "test 1 (seed 0): PASS (123 cycles)")
+}
+
+func TestRunCommandImageMismatch(t *testing.T) {
+	// draws the line in light grey and one pixel short
+	solution := setupSolution(t, map[int][]string{
+		8: {"MOV 0 DOWN", "MOV 0 DOWN", "MOV 29 ACC", "L: MOV 2 DOWN", "SUB 1", "JNZ L", "JRO 0"},
+	})
+
+	var stdout, stderr bytes.Buffer
+	code := execute([]string{"run", "-tests", "1", "-max-cycles", "200", imagePuzzle, solution}, &stdout, &stderr)
+	require.Equal(t, exitFail, code)
+	require.Contains(t, stdout.String(), "test 1 (seed 0): FAIL: cycle limit reached (200 cycles)\n  IMAGE:\n")
+	require.Contains(t, stdout.String(), "    expected:                         actual:\n")
+	require.Contains(t, stdout.String(), "    │"+strings.Repeat("█", 30)+"│  │"+strings.Repeat("▒", 29)+" │\n")
+	require.Contains(t, stdout.String(), "    wrong pixels: 30, the first at 0,0 is 2, expected 3, drawn at cycle 7\n")
+}
+
+func TestRunCommandChecked(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	code := execute([]string{"run", checkedPuzzle, checkedSolution}, &stdout, &stderr)
+	require.Equal(t, exitPass, code, stderr.String())
+	require.Contains(t, stdout.String(), "PASS: UNORDERED ECHO (3/3 tests)")
+}
+
+func TestRunCommandCheckFailed(t *testing.T) {
+	// adds 1 to every value
+	solution := setupSolution(t, map[int][]string{
+		1: {"MOV UP DOWN"},
+		5: {"MOV UP DOWN"},
+		9: {"MOV UP ACC", "ADD 1", "MOV ACC DOWN"},
+	})
+
+	var stdout, stderr bytes.Buffer
+	code := execute([]string{"run", "-tests", "1", checkedPuzzle, solution}, &stdout, &stderr)
+	require.Equal(t, exitFail, code)
+	require.Contains(t, stdout.String(), "test 1 (seed 0): FAIL: OUT: check failed: OUT does not hold the values of IN (")
+	require.NotContains(t, stdout.String(), "  OUT:\n", "a checked stream should not be compared value by value")
+}
+
+func TestRunCommandCodeInDamagedNode(t *testing.T) {
+	// routes IN.X straight through the damaged node @2
+	solution := setupSolution(t, map[int][]string{
+		0: {"MOV UP RIGHT"},
+		1: {"MOV LEFT DOWN"},
+	})
+
+	var stdout, stderr bytes.Buffer
+	code := execute([]string{"run", selfTestPuzzle, solution}, &stdout, &stderr)
+	require.Equal(t, exitError, code)
+	require.Contains(t, stderr.String(), "node @2 is damaged and cannot hold code")
+}
+
+func TestRunCommandCompileErrors(t *testing.T) {
+	solution := setupSolution(t, map[int][]string{0: {"MOV UP DOWN", "JMP LOOP"}, 2: {"ADD"}})
+
+	var stdout, stderr bytes.Buffer
+	code := execute([]string{"run", selfTestPuzzle, solution}, &stdout, &stderr)
+	require.Equal(t, exitError, code)
+	require.Equal(t, solution+":@1:2: unknown label LOOP\n"+solution+":@3:1: ADD expects 1 operand\n", stderr.String())
+	require.Empty(t, stdout.String())
+}
+
+func TestRunCommandMode(t *testing.T) {
+	solution := setupSolution(t, map[int][]string{
+		0: {"MOV UP ACC # read the value", "MOV ACC DOWN"},
+		4: {"MOV UP DOWN"},
+		8: {"MOV UP DOWN"},
+	})
+
+	var stdout, stderr bytes.Buffer
+	code := execute([]string{"run", selfTestPuzzle, solution}, &stdout, &stderr)
+	require.Equal(t, exitError, code)
+	require.Equal(t, solution+":@1:1: line has 27 characters, at most 18 are allowed\n", stderr.String())
+
+	stdout.Reset()
+	stderr.Reset()
+	code = execute([]string{"run", "-mode", "sandbox", selfTestPuzzle, solution}, &stdout, &stderr)
+	require.NotEqual(t, exitError, code, stderr.String())
+	require.Empty(t, stderr.String())
+
+	code = execute([]string{"run", "-mode", "relaxed", selfTestPuzzle, solution}, &stdout, &stderr)
+	require.Equal(t, exitError, code)
+	require.Contains(t, stderr.String(), "unknown mode: \"relaxed\"")
+}
+
+func TestRunCommandCycleLimit(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	code := execute(
+		[]string{"run", "-max-cycles", "3", selfTestPuzzle, selfTestSolution},
+		&stdout,
+		&stderr,
+	)
+	require.Equal(t, exitFail, code)
+	require.Contains(t, stdout.String(), "cycle limit reached (3 cycles)")
+}
+
+func TestRunCommandLivelock(t *testing.T) {
+	solution := setupSolution(t, map[int][]string{0: {"L: JMP L"}})
+
+	var stdout, stderr bytes.Buffer
+	code := execute([]string{"run", "-detect-livelock", "-tests", "1", selfTestPuzzle, solution}, &stdout, &stderr)
+	require.Equal(t, exitFail, code)
+	require.Contains(t, stdout.String(), "test 1 (seed 0): FAIL: livelock: state at cycle 2 repeats cycle 1")
+}
+
+func TestRunCommandWrongArguments(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	code := execute([]string{"run", selfTestPuzzle}, &stdout, &stderr)
+	require.Equal(t, exitError, code)
+	require.Contains(t, stderr.String(), "Usage: tis-100 run")
+}
+
+func TestRunCommandMissingPuzzle(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	code := execute([]string{"run", "/notexisting.lua", selfTestSolution}, &stdout, &stderr)
+	require.Equal(t, exitError, code)
+	require.Contains(t, stderr.String(), "unable to load lua script")
+}
+
+// addRunnerFlags -> covered in previous tests
+// runSolution -> covered in previous tests
+// describeFailure -> covered in previous tests
+// describeDifference -> covered in previous tests
+// writeMismatchReport -> covered in previous tests
+// formatColumns -> covered in previous tests
+// valueAt -> covered in previous tests
+
+/* UTILS */
+
+// setupSolution writes a `.tis` file with the given code per node index
+// to a temporary directory and returns its path. Missing nodes are left empty.
+func setupSolution(tb testing.TB, nodes map[int][]string) string {
+	tb.Helper()
+
+	var builder strings.Builder
+	for i := range model.NodesNumber {
+		fmt.Fprintf(&builder, "@%d\n", i+1)
+		for _, line := range nodes[i] {
+			builder.WriteString(line + "\n")
+		}
+		builder.WriteString("\n")
+	}
+
+	filePath := filepath.Join(tb.TempDir(), "solution.tis")
+	require.NoError(tb, os.WriteFile(filePath, []byte(builder.String()), 0o600))
+	return filePath
+}
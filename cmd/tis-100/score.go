@@ -0,0 +1,83 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/lekomish/tis-100/internal/runner"
+	"github.com/lekomish/tis-100/internal/score"
+)
+
+// scoreReport is the JSON representation of the `score` command output.
+type scoreReport struct {
+	Puzzle string `json:"puzzle"`
+	*score.Score
+}
+
+// scoreCommand implements `tis-100 score`: it runs a solution against every test set
+// and prints its cycles, nodes and instructions as text or JSON.
+func scoreCommand(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("score", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	opts := addRunnerFlags(flags)
+	format := flags.String("format", "text", "output format: text or json")
+	aggregate := flags.String("aggregate", "average", "how to combine the cycles of several runs: average or max")
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: tis-100 score [flags] <puzzle.lua> <solution.tis>")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return exitError
+	}
+	if flags.NArg() != 2 || (*format != "text" && *format != "json") {
+		flags.Usage()
+		return exitError
+	}
+	agg, err := score.ParseAggregation(*aggregate)
+	if err != nil {
+		fmt.Fprintf(stderr, "tis-100: %v\n", err)
+		return exitError
+	}
+
+	code, runs, err := runSolution(flags.Arg(0), flags.Arg(1), *opts)
+	if err != nil {
+		reportError(stderr, flags.Arg(1), err)
+		return exitError
+	}
+
+	puzzle := runs[0].Puzzle
+	for i, run := range runs {
+		if !run.Result.Passed() {
+			fmt.Fprintf(
+				stdout, "FAIL: %s: test %d (seed %d): %s (%d cycles)\n",
+				puzzle.Title, i+1, run.Seed, describeFailure(run), run.Result.Cycles,
+			)
+			writeMismatchReport(stdout, run)
+			return exitFail
+		}
+	}
+
+	s, err := score.Compute(code, runner.Results(runs), agg)
+	if err != nil {
+		fmt.Fprintf(stderr, "tis-100: %v\n", err)
+		return exitError
+	}
+
+	if *format == "json" {
+		data, err := json.Marshal(scoreReport{Puzzle: puzzle.Title, Score: s})
+		if err != nil {
+			fmt.Fprintf(stderr, "tis-100: %v\n", err)
+			return exitError
+		}
+		fmt.Fprintln(stdout, string(data))
+		return exitPass
+	}
+
+	fmt.Fprintln(stdout, puzzle.Title)
+	fmt.Fprintf(stdout, "cycles:       %d\n", s.Cycles)
+	fmt.Fprintf(stdout, "nodes:        %d\n", s.Nodes)
+	fmt.Fprintf(stdout, "instructions: %d\n", s.Instructions)
+	return exitPass
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+/* TESTS */
+
+// --- scoreCommand ---
+func TestScoreCommandText(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	code := execute([]string{"score", selfTestPuzzle, selfTestSolution}, &stdout, &stderr)
+	require.Equal(t, exitPass, code, stderr.String())
+	require.Equal(
+		t,
+		"SELF-TEST DIAGNOSTIC\ncycles:       45\nnodes:        8\ninstructions: 8\n",
+		stdout.String(),
+	)
+}
+
+func TestScoreCommandJSON(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	code := execute(
+		[]string{"score", "-format", "json", selfTestPuzzle, selfTestSolution},
+		&stdout,
+		&stderr,
+	)
+	require.Equal(t, exitPass, code, stderr.String())
+	require.JSONEq(
+		t,
+		`{"puzzle":"SELF-TEST DIAGNOSTIC","cycles":45,"nodes":8,"instructions":8}`,
+		stdout.String(),
+	)
+}
+
+func TestScoreCommandFailingSolution(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	code := execute([]string{"score", templatePuzzle, templateSolution}, &stdout, &stderr)
+	require.Equal(t, exitFail, code)
+	require.Contains(t, stdout.String(), "FAIL: TEMPLATE: test 1 (seed 0): deadlock")
+}
+
+func TestScoreCommandWrongFormat(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	code := execute(
+		[]string{"score", "-format", "xml", selfTestPuzzle, selfTestSolution},
+		&stdout,
+		&stderr,
+	)
+	require.Equal(t, exitError, code)
+	require.Contains(t, stderr.String(), "Usage: tis-100 score")
+}
+
+func TestScoreCommandWrongAggregation(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	code := execute(
+		[]string{"score", "-aggregate", "min", selfTestPuzzle, selfTestSolution},
+		&stdout,
+		&stderr,
+	)
+	require.Equal(t, exitError, code)
+	require.Contains(t, stderr.String(), "unknown aggregation")
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/lekomish/tis-100/internal/engine"
+	"github.com/lekomish/tis-100/internal/loader"
+	"github.com/lekomish/tis-100/internal/tui"
+)
+
+// tuiCommand implements `tis-100 tui`: it loads a single test set of a puzzle and a solution
+// and lets the user step, run and fast-forward through the execution, pausing at breakpoints
+// (including those set with `!` in the solution), conditions and watchpoints.
+func tuiCommand(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("tui", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	seed := flags.Int64("seed", 0, "seed of the test set")
+	maxCycles := flags.Int("max-cycles", engine.DefaultMaxCycles, "maximum number of cycles to simulate")
+	var mode engine.Mode
+	addModeFlag(flags, &mode)
+	delay := flags.Duration("delay", tui.DefaultDelay, "pause between two frames while running")
+	plain := flags.Bool("plain", false, "do not use colours or clear the screen")
+	var breaks, watches, conditions []string
+	flags.Func("break", "breakpoint such as '@5:3' or '@5:3 if @5 ACC < 0' (repeatable)", appendTo(&breaks))
+	flags.Func("watch", "watchpoint on a register or port such as '@5 ACC' or '@5 LEFT' (repeatable)", appendTo(&watches))
+	flags.Func("when", "condition pausing the run when it becomes true such as '@5 ACC < 0' (repeatable)", appendTo(&conditions))
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: tis-100 tui [flags] <puzzle.lua> <solution.tis>")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return exitError
+	}
+	if flags.NArg() != 2 {
+		flags.Usage()
+		return exitError
+	}
+
+	puzzle, err := loader.LoadPuzzleWithSeed(flags.Arg(0), *seed)
+	if err != nil {
+		fmt.Fprintf(stderr, "tis-100: %v\n", err)
+		return exitError
+	}
+	code, err := loader.LoadCode(flags.Arg(1))
+	if err != nil {
+		fmt.Fprintf(stderr, "tis-100: %v\n", err)
+		return exitError
+	}
+	eng, err := engine.NewEngineWithMode(puzzle.Streams, puzzle.Layout, code, mode)
+	if err != nil {
+		reportError(stderr, flags.Arg(1), err)
+		return exitError
+	}
+
+	session := &tui.Session{
+		Engine:    eng,
+		Puzzle:    puzzle,
+		Code:      code,
+		MaxCycles: *maxCycles,
+		Delay:     *delay,
+		Options:   tui.Options{ANSI: !*plain},
+	}
+	for _, specs := range []struct {
+		values []string
+		add    func(string) error
+	}{{breaks, session.Break}, {watches, session.Watch}, {conditions, session.When}} {
+		for _, spec := range specs.values {
+			if err := specs.add(spec); err != nil {
+				fmt.Fprintf(stderr, "tis-100: %v\n", err)
+				return exitError
+			}
+		}
+	}
+	session.Loop(stdin, stdout)
+	return exitPass
+}
+
+// appendTo returns a flag function collecting every occurrence of a repeatable flag.
+func appendTo(values *[]string) func(string) error {
+	return func(value string) error {
+		*values = append(*values, value)
+		return nil
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+/* TESTS */
+
+// --- tuiCommand ---
+func TestTUICommandFastForward(t *testing.T) {
+	setupStdin(t, "f\nq\n")
+
+	var stdout, stderr bytes.Buffer
+	code := execute([]string{"tui", "-plain", selfTestPuzzle, selfTestSolution}, &stdout, &stderr)
+	require.Equal(t, exitPass, code, stderr.String())
+	require.Contains(t, stdout.String(), "SELF-TEST DIAGNOSTIC - cycle 0")
+	require.NotContains(t, stdout.String(), "\x1b")
+}
+
+func TestTUICommandBreakpoint(t *testing.T) {
+	setupStdin(t, "f\n")
+
+	var stdout, stderr bytes.Buffer
+	code := execute(
+		[]string{"tui", "-plain", "-when", "@1 ACC > 0", "-break", "@3:1", selfTestPuzzle, selfTestSolution},
+		&stdout,
+		&stderr,
+	)
+	require.Equal(t, exitPass, code, stderr.String())
+	require.Contains(t, stdout.String(), "paused: breakpoint @3:1")
+}
+
+func TestTUICommandWrongBreakpoint(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	code := execute([]string{"tui", "-watch", "@2 ACC", selfTestPuzzle, selfTestSolution}, &stdout, &stderr)
+	require.Equal(t, exitError, code)
+	require.Contains(t, stderr.String(), "node @2 is damaged")
+}
+
+func TestTUICommandWithCodeInDamagedNode(t *testing.T) {
+	solution := setupSolution(t, map[int][]string{1: {"NOP"}})
+
+	var stdout, stderr bytes.Buffer
+	code := execute([]string{"tui", selfTestPuzzle, solution}, &stdout, &stderr)
+	require.Equal(t, exitError, code)
+	require.Contains(t, stderr.String(), "node @2 is damaged")
+}
+
+func TestTUICommandWrongArguments(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	code := execute([]string{"tui", selfTestPuzzle}, &stdout, &stderr)
+	require.Equal(t, exitError, code)
+	require.Contains(t, stderr.String(), "Usage: tis-100 tui")
+}
+
+// appendTo -> covered in previous tests
+
+/* UTILS */
+
+// setupStdin replaces the input of interactive commands for the duration of the test.
+func setupStdin(tb testing.TB, input string) {
+	tb.Helper()
+
+	previous := stdin
+	stdin = strings.NewReader(input)
+	tb.Cleanup(func() { stdin = previous })
+}
@@ -0,0 +1,136 @@
+package engine
+
+import "github.com/lekomish/tis-100/internal/model"
+
+// DifferenceKind describes how an output value differs from the expected one.
+type DifferenceKind uint8
+
+// Supported DifferenceKind values.
+const (
+	WrongValue   DifferenceKind = iota // the output holds a different value than expected
+	ExtraValue                         // the output holds a value beyond the expected length
+	MissingValue                       // the output lacks an expected value
+)
+
+// Difference describes a single position at which an output differs from its expected stream.
+type Difference struct {
+	Kind     DifferenceKind // how the value differs
+	Index    int            // zero-based position of the value in the stream
+	Expected int16          // expected value (unset for ExtraValue)
+	Actual   int16          // emitted value (unset for MissingValue)
+	Cycle    int            // cycle the value was emitted at (unset for MissingValue)
+}
+
+// Comparison is the result of comparing an output with its expected stream.
+// For image streams the values are the pixels of the display, row by row.
+type Comparison struct {
+	Stream      string       // name of the expected stream
+	Image       bool         // the stream is an image, compared pixel by pixel
+	Expected    []int16      // expected values
+	Actual      []int16      // emitted values
+	Cycles      []int        // cycle each emitted value was emitted at
+	Differences []Difference // every differing position in stream order
+}
+
+// Equal reports whether the output matches the expected stream.
+func (c *Comparison) Equal() bool {
+	return len(c.Differences) == 0
+}
+
+// First returns the first difference, or nil if the output matches the expected stream.
+func (c *Comparison) First() *Difference {
+	if c.Equal() {
+		return nil
+	}
+	return &c.Differences[0]
+}
+
+// Wrong returns the differences caused by emitted values, i.e. wrong and extra values.
+func (c *Comparison) Wrong() []Difference {
+	wrong := make([]Difference, 0, len(c.Differences))
+	for _, diff := range c.Differences {
+		if diff.Kind != MissingValue {
+			wrong = append(wrong, diff)
+		}
+	}
+	return wrong
+}
+
+// Compare compares the output with the given expected stream value by value.
+// Values the output has not produced yet are reported as missing.
+// An image output is compared pixel by pixel, a wrong pixel being reported with
+// the cycle it was last drawn at. The values of a checked stream are left to the check
+// of the puzzle, so only extra and missing values are reported.
+func (o *Output) Compare(stream *model.Stream) *Comparison {
+	if o.Image != nil {
+		return o.compareImage(stream)
+	}
+
+	c := &Comparison{
+		Stream:      stream.Name,
+		Expected:    stream.Values,
+		Actual:      o.Values,
+		Cycles:      o.Cycles,
+		Differences: make([]Difference, 0),
+	}
+
+	for i := range max(o.Len(), stream.Len()) {
+		switch {
+		case i >= stream.Len():
+			c.Differences = append(c.Differences, Difference{
+				Kind: ExtraValue, Index: i, Actual: o.Values[i], Cycle: o.cycleAt(i),
+			})
+		case i >= o.Len():
+			c.Differences = append(c.Differences, Difference{
+				Kind: MissingValue, Index: i, Expected: stream.Values[i],
+			})
+		case !stream.Checked && o.Values[i] != stream.Values[i]:
+			c.Differences = append(c.Differences, Difference{
+				Kind: WrongValue, Index: i, Expected: stream.Values[i], Actual: o.Values[i], Cycle: o.cycleAt(i),
+			})
+		}
+	}
+	return c
+}
+
+// compareImage compares the display of an image output with the given expected stream pixel by pixel.
+func (o *Output) compareImage(stream *model.Stream) *Comparison {
+	c := &Comparison{
+		Stream:      stream.Name,
+		Image:       true,
+		Expected:    stream.Values,
+		Actual:      o.Image.Pixels,
+		Cycles:      o.Image.Cycles,
+		Differences: make([]Difference, 0),
+	}
+
+	for i, pixel := range o.Image.Pixels {
+		var expected int16
+		if i < stream.Len() {
+			expected = stream.Values[i]
+		}
+		if pixel != expected {
+			c.Differences = append(c.Differences, Difference{
+				Kind: WrongValue, Index: i, Expected: expected, Actual: pixel, Cycle: o.Image.Cycles[i],
+			})
+		}
+	}
+	return c
+}
+
+// cycleAt returns the cycle the value at the given index was emitted at, or 0 if unknown.
+func (o *Output) cycleAt(index int) int {
+	if index >= len(o.Cycles) {
+		return 0
+	}
+	return o.Cycles[index]
+}
+
+// CompareOutputs compares every output with its expected stream, in the order of `Outputs`.
+func (e *Engine) CompareOutputs() []*Comparison {
+	comparisons := make([]*Comparison, 0, len(e.Outputs))
+	for i, out := range e.Outputs {
+		comparisons = append(comparisons, out.Compare(e.expected[i]))
+	}
+	return comparisons
+}
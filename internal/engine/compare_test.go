@@ -0,0 +1,96 @@
+package engine_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lekomish/tis-100/internal/engine"
+	"github.com/lekomish/tis-100/internal/model"
+)
+
+/* TESTS */
+
+// --- Compare ---
+func TestCompareEqual(t *testing.T) {
+	out := newEmittedOutput([]int16{1, 2, 3})
+	c := out.Compare(&model.Stream{Name: "OUT", Values: []int16{1, 2, 3}})
+
+	require.True(t, c.Equal())
+	require.Nil(t, c.First())
+	require.Equal(t, "OUT", c.Stream)
+	require.Equal(t, []int{10, 20, 30}, c.Cycles)
+}
+
+func TestCompareWrongValues(t *testing.T) {
+	out := newEmittedOutput([]int16{1, 7, 3, 9})
+	c := out.Compare(&model.Stream{Name: "OUT", Values: []int16{1, 2, 3, 4}})
+
+	require.False(t, c.Equal())
+	require.Equal(t, []engine.Difference{
+		{Kind: engine.WrongValue, Index: 1, Expected: 2, Actual: 7, Cycle: 20},
+		{Kind: engine.WrongValue, Index: 3, Expected: 4, Actual: 9, Cycle: 40},
+	}, c.Differences)
+	require.Equal(t, &c.Differences[0], c.First())
+}
+
+func TestCompareExtraValues(t *testing.T) {
+	out := newEmittedOutput([]int16{1, 2})
+	c := out.Compare(&model.Stream{Name: "OUT", Values: []int16{1}})
+
+	require.Equal(t, []engine.Difference{
+		{Kind: engine.ExtraValue, Index: 1, Actual: 2, Cycle: 20},
+	}, c.Differences)
+}
+
+func TestCompareMissingValues(t *testing.T) {
+	out := newEmittedOutput([]int16{5})
+	c := out.Compare(&model.Stream{Name: "OUT", Values: []int16{1, 2}})
+
+	require.Equal(t, []engine.Difference{
+		{Kind: engine.WrongValue, Index: 0, Expected: 1, Actual: 5, Cycle: 10},
+		{Kind: engine.MissingValue, Index: 1, Expected: 2},
+	}, c.Differences)
+	require.Len(t, c.Wrong(), 1)
+}
+
+func TestCompareChecked(t *testing.T) {
+	out := newEmittedOutput([]int16{3, 1, 2, 4})
+	c := out.Compare(&model.Stream{Name: "OUT", Values: []int16{1, 2, 3}, Checked: true})
+
+	require.Equal(t, []engine.Difference{
+		{Kind: engine.ExtraValue, Index: 3, Actual: 4, Cycle: 40},
+	}, c.Differences, "the values of a checked stream should be left to the check")
+}
+
+// --- CompareOutputs ---
+func TestCompareOutputs(t *testing.T) {
+	eng := newPipeEngine(t, []int16{1, 2, 3}, []int16{1, 5, 3})
+	eng.Run(engine.RunOptions{})
+
+	comparisons := eng.CompareOutputs()
+	require.Len(t, comparisons, 1)
+	require.Len(t, comparisons[0].Differences, 1)
+
+	diff := comparisons[0].Differences[0]
+	require.Equal(t, engine.WrongValue, diff.Kind)
+	require.Equal(t, 1, diff.Index)
+	require.Equal(t, eng.Outputs[0].Cycles[1], diff.Cycle)
+	require.Positive(t, diff.Cycle)
+}
+
+// Equal -> covered in previous tests
+// First -> covered in previous tests
+// Wrong -> covered in previous tests
+// cycleAt -> covered in previous tests
+
+/* UTILS */
+
+// newEmittedOutput creates an output holding the given values, emitted every ten cycles.
+func newEmittedOutput(values []int16) *engine.Output {
+	out := engine.NewOutput(0)
+	for i, value := range values {
+		out.AddValueAt(value, (i+1)*10)
+	}
+	return out
+}
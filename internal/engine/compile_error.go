@@ -0,0 +1,86 @@
+package engine
+
+import (
+	"fmt"
+	"strings"
+)
+
+// CompileErrorCode classifies the problems found while compiling the code of a node.
+type CompileErrorCode uint8
+
+// Supported CompileErrorCode values.
+const (
+	CodeInvalidInstruction CompileErrorCode = iota // the mnemonic is not a known instruction
+	CodeOperandCount                               // the instruction has too few or too many operands
+	CodeInvalidOperand                             // an operand is neither a port, a register nor a number
+	CodeUnknownLabel                               // a jump refers to a label that is not defined in the node
+	CodeEmptyLabel                                 // a label definition has no name
+	CodeUnexpectedToken                            // punctuation out of place, such as a comma before the first operand
+	CodeValueOutOfRange                            // an immediate is beyond the range of the mode
+	CodeTooManyLines                               // the node has more lines or instructions than the mode allows
+	CodeLineTooLong                                // the line has more characters than the mode allows
+)
+
+// compileErrorCodeNames maps CompileErrorCode values to their human readable names.
+var compileErrorCodeNames = map[CompileErrorCode]string{
+	CodeInvalidInstruction: "invalid instruction",
+	CodeOperandCount:       "operand count",
+	CodeInvalidOperand:     "invalid operand",
+	CodeUnknownLabel:       "unknown label",
+	CodeEmptyLabel:         "empty label",
+	CodeUnexpectedToken:    "unexpected token",
+	CodeValueOutOfRange:    "value out of range",
+	CodeTooManyLines:       "too many lines",
+	CodeLineTooLong:        "line too long",
+}
+
+// String returns the human readable name of the error code.
+func (c CompileErrorCode) String() string {
+	if name, ok := compileErrorCodeNames[c]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// CompileError is a problem found in a single line of the code of a node.
+// The column span lets editors underline the offending text.
+type CompileError struct {
+	Node      int              // grid index of the node
+	Line      int              // zero-based number of the source line within the node's code
+	Column    int              // one-based column of the first offending character
+	EndColumn int              // one-based column following the last offending character
+	Code      CompileErrorCode // kind of problem
+	Message   string           // description of the problem
+}
+
+// Error implements `error`, locating the problem by node and line: `@3:2: unknown label LOOP`.
+func (e *CompileError) Error() string {
+	return fmt.Sprintf("@%d:%d: %s", e.Node+1, e.Line+1, e.Message)
+}
+
+// CompileErrors are all the problems found while compiling a solution, in node and line order.
+type CompileErrors []*CompileError
+
+// Error implements `error`, one problem per line.
+func (errs CompileErrors) Error() string {
+	messages := make([]string, 0, len(errs))
+	for _, err := range errs {
+		messages = append(messages, err.Error())
+	}
+	return strings.Join(messages, "\n")
+}
+
+// tokenError creates an error of the given code spanning the token.
+func tokenError(code CompileErrorCode, t token, format string, args ...any) *CompileError {
+	return spanError(code, t.column, t.column+len(t.text), format, args...)
+}
+
+// spanError creates an error of the given code spanning the zero-based columns from start to end, excluded.
+func spanError(code CompileErrorCode, start, end int, format string, args ...any) *CompileError {
+	return &CompileError{
+		Column:    start + 1,
+		EndColumn: end + 1,
+		Code:      code,
+		Message:   fmt.Sprintf(format, args...),
+	}
+}
@@ -0,0 +1,152 @@
+package engine_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lekomish/tis-100/internal/engine"
+	"github.com/lekomish/tis-100/internal/model"
+)
+
+/* TESTS */
+
+// --- Compile ---
+func TestCompileErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		line     string
+		code     engine.CompileErrorCode
+		message  string
+		column   int
+		end      int
+		expected string
+	}{
+		{
+			name:    "invalid instruction",
+			line:    "FOO 1",
+			code:    engine.CodeInvalidInstruction,
+			message: "invalid instruction FOO",
+			column:  1,
+			end:     4,
+		},
+		{
+			name:    "missing operand",
+			line:    "  MOV UP",
+			code:    engine.CodeOperandCount,
+			message: "MOV expects 2 operands",
+			column:  3,
+			end:     6,
+		},
+		{
+			name:    "extra operands",
+			line:    "ADD 1, 2 3",
+			code:    engine.CodeOperandCount,
+			message: "ADD expects 1 operand",
+			column:  8,
+			end:     11,
+		},
+		{
+			name:    "operand without operation",
+			line:    "NOP 5",
+			code:    engine.CodeOperandCount,
+			message: "NOP expects no operands",
+			column:  5,
+			end:     6,
+		},
+		{
+			name:    "invalid operand",
+			line:    "MOV UP SIDEWAYS",
+			code:    engine.CodeInvalidOperand,
+			message: "invalid operand SIDEWAYS",
+			column:  8,
+			end:     16,
+		},
+		{
+			name:    "number as destination",
+			line:    "MOV 1 2",
+			code:    engine.CodeInvalidOperand,
+			message: "invalid destination 2, expected a port or a register",
+			column:  7,
+			end:     8,
+		},
+		{
+			name:    "unknown label",
+			line:    "!START: JMP LOOP",
+			code:    engine.CodeUnknownLabel,
+			message: "unknown label LOOP",
+			column:  13,
+			end:     17,
+		},
+		{
+			name:    "empty label",
+			line:    " : NOP",
+			code:    engine.CodeEmptyLabel,
+			message: "empty label",
+			column:  2,
+			end:     3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code := &model.Code{Title: "TEST", Nodes: make([][]string, model.NodesNumber)}
+			code.Nodes[2] = []string{"NOP", "", tt.line}
+
+			compiled, err := engine.Compile(code)
+			require.Nil(t, compiled)
+
+			var errs engine.CompileErrors
+			require.True(t, errors.As(err, &errs))
+			require.Len(t, errs, 1)
+			require.Equal(t, &engine.CompileError{
+				Node:      2,
+				Line:      2,
+				Column:    tt.column,
+				EndColumn: tt.end,
+				Code:      tt.code,
+				Message:   tt.message,
+			}, errs[0])
+			require.EqualError(t, err, "@3:3: "+tt.message)
+		})
+	}
+}
+
+func TestCompileCollectsEveryError(t *testing.T) {
+	code := &model.Code{Title: "TEST", Nodes: make([][]string, model.NodesNumber)}
+	code.Nodes[0] = []string{"MOV UP", "ADD 1", "JMP NOWHERE"}
+	code.Nodes[5] = []string{"L: ADD ACC", "SWP BAK"}
+
+	_, err := engine.Compile(code)
+	var errs engine.CompileErrors
+	require.True(t, errors.As(err, &errs))
+	require.Len(t, errs, 3)
+	require.EqualError(t, err, "@1:1: MOV expects 2 operands\n@1:3: unknown label NOWHERE\n@6:2: SWP expects no operands")
+}
+
+func TestNewEngineCompileErrors(t *testing.T) {
+	code := &model.Code{Title: "TEST", Nodes: make([][]string, model.NodesNumber)}
+	code.Nodes[3] = []string{"MOV 1 DOWN", "HCF"}
+
+	eng, err := engine.NewEngine(nil, nil, code)
+	require.Nil(t, eng)
+	var errs engine.CompileErrors
+	require.True(t, errors.As(err, &errs))
+	require.Equal(t, engine.CodeInvalidInstruction, errs[0].Code)
+}
+
+// --- CompileErrorCode ---
+func TestCompileErrorCodeString(t *testing.T) {
+	require.Equal(t, "invalid instruction", engine.CodeInvalidInstruction.String())
+	require.Equal(t, "operand count", engine.CodeOperandCount.String())
+	require.Equal(t, "invalid operand", engine.CodeInvalidOperand.String())
+	require.Equal(t, "unknown label", engine.CodeUnknownLabel.String())
+	require.Equal(t, "empty label", engine.CodeEmptyLabel.String())
+	require.Equal(t, "unknown", engine.CompileErrorCode(42).String())
+}
+
+// Error -> covered in previous tests
+// tokenError -> covered in previous tests
+// spanError -> covered in previous tests
+// checkOperands -> covered in previous tests
+// parseJumpTarget -> covered in previous tests
@@ -0,0 +1,90 @@
+package engine_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lekomish/tis-100/internal/engine"
+	"github.com/lekomish/tis-100/internal/loader"
+	"github.com/lekomish/tis-100/internal/model"
+	"github.com/lekomish/tis-100/internal/score"
+)
+
+const (
+	// conformanceDir holds the reference solutions and their scores in the original game.
+	conformanceDir = "testdata/conformance"
+	// timingDir holds the solutions pinning down the timing rules and their expected scores.
+	timingDir = "testdata/timing"
+)
+
+// conformanceCase describes a single entry of the conformance or the timing corpus.
+type conformanceCase struct {
+	Name         string           // puzzle name
+	Reference    string           // where the expected scores come from (conformance corpus only)
+	Solution     string           // solution file name in `conformanceDir`
+	Layout       []model.NodeType // puzzle layout
+	Streams      []*model.Stream  // puzzle streams
+	Cycles       int              // expected number of cycles
+	Nodes        int              // expected number of nodes with instructions
+	Instructions int              // expected number of instructions
+}
+
+/* TESTS */
+
+// --- Conformance corpus ---
+func TestConformance(t *testing.T) {
+	for _, tc := range loadConformanceCases(t, conformanceDir) {
+		t.Run(tc.Name, func(t *testing.T) {
+			require.NotEmpty(t, tc.Reference, "conformance cases must name the source of their scores")
+			runConformanceCase(t, conformanceDir, tc)
+		})
+	}
+}
+
+// --- Timing corpus ---
+func TestTimingRegression(t *testing.T) {
+	for _, tc := range loadConformanceCases(t, timingDir) {
+		t.Run(tc.Name, func(t *testing.T) {
+			runConformanceCase(t, timingDir, tc)
+		})
+	}
+}
+
+/* UTILS */
+
+// runConformanceCase runs the solution of a case and checks its scores.
+func runConformanceCase(tb testing.TB, dir string, tc conformanceCase) {
+	tb.Helper()
+
+	code, err := loader.LoadCode(filepath.Join(dir, tc.Solution))
+	require.NoError(tb, err)
+
+	eng, err := engine.NewEngine(tc.Streams, tc.Layout, code)
+	require.NoError(tb, err)
+
+	res := eng.Run(engine.RunOptions{})
+	require.Equal(tb, engine.Completed, res.Reason)
+	require.Equal(tb, tc.Cycles, res.Cycles, "cycles")
+
+	s, err := score.Compute(code, []*engine.Result{res}, score.Average)
+	require.NoError(tb, err)
+	require.Equal(tb, tc.Nodes, s.Nodes, "nodes")
+	require.Equal(tb, tc.Instructions, s.Instructions, "instructions")
+}
+
+// loadConformanceCases reads the manifest of the corpus in the given directory.
+func loadConformanceCases(tb testing.TB, dir string) []conformanceCase {
+	tb.Helper()
+
+	data, err := os.ReadFile(filepath.Join(dir, "cases.json"))
+	require.NoError(tb, err)
+
+	var cases []conformanceCase
+	require.NoError(tb, json.Unmarshal(data, &cases))
+	require.NotEmpty(tb, cases)
+	return cases
+}
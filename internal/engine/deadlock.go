@@ -0,0 +1,318 @@
+package engine
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/lekomish/tis-100/internal/model"
+)
+
+// StallKind classifies why every node of the machine is blocked.
+type StallKind uint8
+
+// Supported StallKind values.
+const (
+	StallDeadlock StallKind = iota // nodes wait for each other in a cycle
+	StallStarved                   // a node waits for a node that will never communicate with it
+)
+
+// stallKindNames maps StallKind values to their human readable names.
+var stallKindNames = map[StallKind]string{
+	StallDeadlock: "circular wait",
+	StallStarved:  "starved",
+}
+
+// String returns the human readable name of the stall kind.
+func (k StallKind) String() string {
+	if name, ok := stallKindNames[k]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// Wait is an edge of the wait-for graph: a blocked node waiting for its neighbours.
+type Wait struct {
+	Node  *Node
+	Write bool    // the node waits for its value to be read, otherwise it waits to read one
+	Port  Port    // port of the pending read or write, as written in the instruction
+	On    []*Node // nodes that could complete the wait, none if the port is not connected
+}
+
+// Stall explains why every node of the machine is blocked. It is the error of the `Deadlock`
+// results of `Engine.Run`.
+type Stall struct {
+	Kind  StallKind
+	Waits []Wait // wait of every blocked node, in the order of `Engine.ActiveNodes`
+	// Path is the cycle of waits for `StallDeadlock`, each node waiting for the next one and the last
+	// one for the first. For `StallStarved` it is the chain of waits from an incomplete output to the node
+	// holding it back. The first node of `On` in each wait is the next node of the path.
+	Path []Wait
+
+	names map[*Node]string // names of the nodes used in messages
+}
+
+// Error implements `error`.
+func (s *Stall) Error() string {
+	return s.String()
+}
+
+// String describes the stall, following its path: for example
+// `circular wait: @1 waits to write RIGHT to @2, which waits to write LEFT to @1`.
+func (s *Stall) String() string {
+	var builder strings.Builder
+	if s.Kind == StallDeadlock {
+		builder.WriteString(s.Kind.String() + ": ")
+	}
+	for i, w := range s.Path {
+		if i > 0 {
+			builder.WriteString(", which ")
+		} else {
+			builder.WriteString(s.names[w.Node] + " ")
+		}
+		builder.WriteString(s.describe(w))
+	}
+	if s.Kind == StallStarved && len(s.Path) > 0 {
+		if last := s.Path[len(s.Path)-1]; len(last.On) > 0 {
+			builder.WriteString(", which " + s.terminal(last.On[0]))
+		}
+	}
+	return builder.String()
+}
+
+// describe describes a single wait without its node.
+func (s *Stall) describe(w Wait) string {
+	verb, preposition := "read", "from"
+	if w.Write {
+		verb, preposition = "write", "to"
+	}
+	if len(w.On) == 0 {
+		return fmt.Sprintf("waits to %s %s %s an unconnected port", verb, portName(w.Port), preposition)
+	}
+	names := make([]string, 0, len(w.On))
+	for _, n := range w.On {
+		names = append(names, s.names[n])
+	}
+	return fmt.Sprintf("waits to %s %s %s %s", verb, portName(w.Port), preposition, strings.Join(names, " or "))
+}
+
+// terminal describes why the node ending a starved path never communicates.
+func (s *Stall) terminal(n *Node) string {
+	switch {
+	case n.Type == model.MEMORY && len(n.Stack) == 0:
+		return "is empty"
+	case n.Type == model.MEMORY:
+		return "is full"
+	case n.Output == nil && n.Index < positionOffset:
+		return "is exhausted"
+	case len(n.Instructions) == 0:
+		return "has no code"
+	default:
+		return "is blocked"
+	}
+}
+
+// Stall builds the wait-for graph of the blocked nodes and classifies the stall.
+// It is meant to be called when no node can make progress anymore and the outputs are incomplete,
+// as `Run` does: a machine whose outputs are complete ends its run before it is considered stalled.
+func (e *Engine) Stall() *Stall {
+	s := &Stall{names: make(map[*Node]string)}
+	for _, n := range e.allNodes() {
+		s.names[n] = e.nodeName(n)
+	}
+
+	waits := make(map[*Node]*Wait)
+	for list := e.ActiveNodes; list != nil; list = list.Next {
+		if w := list.Node.wait(); w != nil && !e.exhausted(list.Node) {
+			s.Waits = append(s.Waits, *w)
+			waits[list.Node] = w
+		}
+	}
+
+	if cycle := findCycle(s.Waits, waits); cycle != nil {
+		s.Kind = StallDeadlock
+		s.Path = cycle
+		return s
+	}
+	s.Kind = StallStarved
+	s.Path = e.starvedPath(waits)
+	return s
+}
+
+// wait returns what the blocked node is waiting for, or nil if it is not blocked.
+// Stack memory nodes never wait: they only serve their neighbours.
+func (n *Node) wait() *Wait {
+	if !n.IsBlocked || n.Type == model.MEMORY {
+		return nil
+	}
+	if n.IsWriting {
+		w := &Wait{Node: n, Write: true, Port: n.OutboundPort}
+		switch {
+		case n.OutboundPort == PortAny:
+			w.On = n.neighbours()
+		case n.OutboundTarget != nil:
+			w.On = []*Node{n.OutboundTarget}
+		}
+		return w
+	}
+
+	ins := n.instruction()
+	if ins == nil || ins.SrcType != PortRef {
+		return nil
+	}
+	w := &Wait{Node: n, Port: ins.Src.Port}
+	if ins.Src.Port == PortAny {
+		w.On = n.neighbours()
+	} else if from := n.getInputPort(ins.Src.Port); from != nil {
+		w.On = []*Node{from}
+	}
+	return w
+}
+
+// neighbours returns the nodes connected to the node, in the order ANY probes them.
+func (n *Node) neighbours() []*Node {
+	var nodes []*Node
+	for _, p := range portProbeOrder {
+		if n.Ports[p] != nil {
+			nodes = append(nodes, n.Ports[p])
+		}
+	}
+	return nodes
+}
+
+// findCycle returns the first cycle of the wait-for graph, starting with its grid node
+// of the lowest index, or nil if the graph has none.
+func findCycle(order []Wait, waits map[*Node]*Wait) []Wait {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[*Node]int)
+	var stack []*Node
+
+	var visit func(n *Node) []*Node
+	visit = func(n *Node) []*Node {
+		state[n] = visiting
+		stack = append(stack, n)
+		for _, next := range waits[n].On {
+			if waits[next] == nil {
+				continue
+			}
+			switch state[next] {
+			case visiting:
+				for i, m := range stack {
+					if m == next {
+						return append([]*Node{}, stack[i:]...)
+					}
+				}
+			case unvisited:
+				if cycle := visit(next); cycle != nil {
+					return cycle
+				}
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[n] = visited
+		return nil
+	}
+
+	for _, w := range order {
+		if state[w.Node] != unvisited {
+			continue
+		}
+		cycle := visit(w.Node)
+		if cycle == nil {
+			continue
+		}
+
+		first := 0
+		for i, n := range cycle {
+			if n.Index < cycle[first].Index {
+				first = i
+			}
+		}
+		path := make([]Wait, 0, len(cycle))
+		for i := range cycle {
+			n, next := cycle[(first+i)%len(cycle)], cycle[(first+i+1)%len(cycle)]
+			path = append(path, waits[n].leading(next))
+		}
+		return path
+	}
+	return nil
+}
+
+// starvedPath follows the waits from the first incomplete output, or from the first wait
+// if every output is complete, until a node which does not wait for another one.
+func (e *Engine) starvedPath(waits map[*Node]*Wait) []Wait {
+	var start *Node
+	for list := e.NodeList; list != nil && start == nil; list = list.Next {
+		n := list.Node
+		if n.Output != nil && waits[n] != nil && !e.outputComplete(e.outputIndex(n.Output)) {
+			start = n
+		}
+	}
+	for list := e.ActiveNodes; list != nil && start == nil; list = list.Next {
+		if waits[list.Node] != nil {
+			start = list.Node
+		}
+	}
+
+	var path []Wait
+	seen := make(map[*Node]bool)
+	for n := start; n != nil && waits[n] != nil && !seen[n]; {
+		seen[n] = true
+		w := waits[n]
+		var next *Node
+		for _, m := range w.On {
+			if waits[m] != nil && !seen[m] {
+				next = m
+				break
+			}
+		}
+		if next == nil && len(w.On) > 0 {
+			next = w.On[0]
+		}
+		path = append(path, w.leading(next))
+		n = next
+	}
+	return path
+}
+
+// leading returns a copy of the wait with the given node first in `On`.
+func (w *Wait) leading(next *Node) Wait {
+	c := *w
+	c.On = make([]*Node, 0, len(w.On))
+	if next != nil {
+		c.On = append(c.On, next)
+	}
+	for _, n := range w.On {
+		if n != next {
+			c.On = append(c.On, n)
+		}
+	}
+	return c
+}
+
+// exhausted reports whether the node is an input node which has sent all its values.
+func (e *Engine) exhausted(n *Node) bool {
+	return n.Output == nil && n.Index < positionOffset && !n.IsWriting && int(n.InstructionPointer) == len(n.Instructions)-1
+}
+
+// outputIndex returns the position of the output in `Engine.Outputs`.
+func (e *Engine) outputIndex(out *Output) int {
+	for i, o := range e.Outputs {
+		if o == out {
+			return i
+		}
+	}
+	return -1
+}
+
+// nodeName returns the name of the node in messages: `@N` for grid nodes
+// and the stream name for input and output nodes.
+func (e *Engine) nodeName(n *Node) string {
+	if name, ok := e.streamNames[n]; ok {
+		return name
+	}
+	return fmt.Sprintf("@%d", int(n.Index)-positionOffset+1)
+}
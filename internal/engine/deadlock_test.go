@@ -0,0 +1,124 @@
+package engine_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lekomish/tis-100/internal/engine"
+	"github.com/lekomish/tis-100/internal/model"
+)
+
+/* TESTS */
+
+// --- Stall ---
+func TestStallCircularWait(t *testing.T) {
+	tests := []struct {
+		name     string
+		first    []string
+		second   []string
+		expected string
+	}{
+		{
+			name:     "writes",
+			first:    []string{"MOV 1 RIGHT"},
+			second:   []string{"MOV 2 LEFT"},
+			expected: "circular wait: @1 waits to write RIGHT to @2, which waits to write LEFT to @1",
+		},
+		{
+			name:     "reads",
+			first:    []string{"MOV RIGHT ACC"},
+			second:   []string{"ADD LEFT"},
+			expected: "circular wait: @1 waits to read RIGHT from @2, which waits to read LEFT from @1",
+		},
+		{
+			name:     "any",
+			first:    []string{"MOV ANY ACC"},
+			second:   []string{"MOV LEFT ACC"},
+			expected: "circular wait: @1 waits to read ANY from @2 or @5, which waits to read LEFT from @1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			eng := newTwoNodeEngine(t, tt.first, tt.second)
+			res := eng.Run(engine.RunOptions{})
+			require.Equal(t, engine.Deadlock, res.Reason)
+
+			var stall *engine.Stall
+			require.True(t, errors.As(res.Err, &stall))
+			require.Equal(t, engine.StallDeadlock, stall.Kind)
+			require.Equal(t, tt.expected, stall.Error())
+			require.Len(t, stall.Path, 2)
+			require.Same(t, eng.Nodes[0], stall.Path[0].Node)
+			require.Same(t, eng.Nodes[1], stall.Path[0].On[0])
+		})
+	}
+}
+
+func TestStallStarved(t *testing.T) {
+	tests := []struct {
+		name     string
+		nodes    map[int][]string
+		input    []int16
+		expected string
+	}{
+		{
+			name:     "exhausted input",
+			nodes:    map[int][]string{0: {"MOV UP DOWN"}, 4: {"MOV UP DOWN"}, 8: {"MOV UP DOWN"}},
+			input:    []int16{1},
+			expected: "OUT waits to read UP from @9, which waits to read UP from @5, which waits to read UP from @1, which waits to read UP from IN, which is exhausted",
+		},
+		{
+			name:     "node without code",
+			nodes:    map[int][]string{0: {"MOV UP DOWN"}, 8: {"MOV UP DOWN"}},
+			input:    []int16{1, 2},
+			expected: "OUT waits to read UP from @9, which waits to read UP from @5, which has no code",
+		},
+		{
+			name:     "unconnected port",
+			nodes:    map[int][]string{0: {"MOV UP ACC", "MOV ACC LEFT"}, 4: {"MOV UP DOWN"}, 8: {"MOV UP DOWN"}},
+			input:    []int16{1, 2},
+			expected: "OUT waits to read UP from @9, which waits to read UP from @5, which waits to read UP from @1, which waits to write LEFT to an unconnected port",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code := &model.Code{Title: "STARVED", Nodes: make([][]string, model.NodesNumber)}
+			for i, lines := range tt.nodes {
+				code.Nodes[i] = lines
+			}
+			streams := []*model.Stream{
+				{Type: model.INPUT, Name: "IN", Position: 0, Values: tt.input},
+				{Type: model.OUTPUT, Name: "OUT", Position: 0, Values: []int16{1, 2}},
+			}
+			eng, err := engine.NewEngine(streams, nil, code)
+			require.NoError(t, err)
+
+			res := eng.Run(engine.RunOptions{})
+			require.Equal(t, engine.Deadlock, res.Reason)
+			stall := eng.Stall()
+			require.Equal(t, engine.StallStarved, stall.Kind)
+			require.Equal(t, tt.expected, stall.String())
+			require.Equal(t, res.Err, stall)
+		})
+	}
+}
+
+func TestStallKindString(t *testing.T) {
+	require.Equal(t, "circular wait", engine.StallDeadlock.String())
+	require.Equal(t, "starved", engine.StallStarved.String())
+	require.Equal(t, "unknown", engine.StallKind(42).String())
+}
+
+// Error -> covered in previous tests
+// describe -> covered in previous tests
+// terminal -> covered in previous tests
+// wait -> covered in previous tests
+// neighbours -> covered in previous tests
+// findCycle -> covered in previous tests
+// starvedPath -> covered in previous tests
+// leading -> covered in previous tests
+// exhausted -> covered in previous tests
+// outputIndex -> covered in previous tests
+// nodeName -> covered in previous tests
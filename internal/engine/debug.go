@@ -0,0 +1,418 @@
+package engine
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/lekomish/tis-100/internal/model"
+)
+
+// Register identifies a register of a node inspected by conditions and watchpoints.
+type Register uint8
+
+// Supported Register values.
+const (
+	RegisterACC Register = iota // accumulator
+	RegisterBAK                 // backup register
+)
+
+// registerNames maps Register values to their names in the TIS-100 syntax.
+var registerNames = map[Register]string{
+	RegisterACC: "ACC",
+	RegisterBAK: "BAK",
+}
+
+// String returns the name of the register.
+func (r Register) String() string {
+	return registerNames[r]
+}
+
+// comparisons lists the operators supported by conditions, longest first so they parse greedily.
+var comparisons = []string{"<=", ">=", "==", "!=", "<", ">"}
+
+// Condition compares a register of a grid node with a constant, e.g. `@5 ACC < 0`.
+type Condition struct {
+	Node     int      // zero-based index of the grid node
+	Register Register // register to compare
+	Operator string   // one of <, <=, >, >=, ==, !=
+	Value    int16    // constant to compare with
+}
+
+// Holds reports whether the condition is true for the engine in its current state.
+func (c *Condition) Holds(e *Engine) bool {
+	value := e.Nodes[c.Node].register(c.Register)
+	switch c.Operator {
+	case "<":
+		return value < c.Value
+	case "<=":
+		return value <= c.Value
+	case ">":
+		return value > c.Value
+	case ">=":
+		return value >= c.Value
+	case "==":
+		return value == c.Value
+	default:
+		return value != c.Value
+	}
+}
+
+// String returns the condition in the syntax accepted by `ParseCondition`.
+func (c *Condition) String() string {
+	return fmt.Sprintf("@%d %s %s %d", c.Node+1, c.Register, c.Operator, c.Value)
+}
+
+// Breakpoint pauses a run before a node starts the instruction compiled from the given source line.
+type Breakpoint struct {
+	Node      int        // zero-based index of the grid node
+	Line      int        // zero-based source line within the node's code
+	Condition *Condition // optional condition that must hold for the breakpoint to trigger
+}
+
+// String returns the breakpoint in the syntax accepted by `ParseBreakpoint`.
+func (b *Breakpoint) String() string {
+	s := fmt.Sprintf("@%d:%d", b.Node+1, b.Line+1)
+	if b.Condition != nil {
+		s += " if " + b.Condition.String()
+	}
+	return s
+}
+
+// Watchpoint pauses a run when a register of a grid node changes, or when a value crosses
+// one of its ports in either direction.
+type Watchpoint struct {
+	Node     int      // zero-based index of the grid node
+	Port     Port     // watched port (UP, DOWN, LEFT or RIGHT), used when `OnPort` is set
+	OnPort   bool     // watch the port instead of the register
+	Register Register // watched register, used when `OnPort` is not set
+}
+
+// String returns the watchpoint in the syntax accepted by `ParseWatchpoint`.
+func (w *Watchpoint) String() string {
+	if w.OnPort {
+		return fmt.Sprintf("@%d %s", w.Node+1, portName(w.Port))
+	}
+	return fmt.Sprintf("@%d %s", w.Node+1, w.Register)
+}
+
+// Stop describes what paused a run. Exactly one of `Breakpoint`, `Condition` and `Watchpoint` is set.
+type Stop struct {
+	Breakpoint *Breakpoint // breakpoint reached by its node
+	Condition  *Condition  // condition that became true
+	Watchpoint *Watchpoint // watchpoint that triggered
+	Previous   int16       // previous value of a watched register
+	Value      int16       // new value of a watched register, or the value that crossed a watched port
+}
+
+// String describes the stop in a human readable form.
+func (s *Stop) String() string {
+	switch {
+	case s.Breakpoint != nil:
+		return "breakpoint " + s.Breakpoint.String()
+	case s.Condition != nil:
+		return "condition " + s.Condition.String()
+	case s.Watchpoint.OnPort:
+		return fmt.Sprintf("watchpoint %s: value %d", s.Watchpoint, s.Value)
+	default:
+		return fmt.Sprintf("watchpoint %s: %d -> %d", s.Watchpoint, s.Previous, s.Value)
+	}
+}
+
+// debugger holds the breakpoints, conditions and watchpoints of an engine and their state.
+type debugger struct {
+	breakpoints []*Breakpoint
+	conditions  []*Condition
+	held        []bool // whether each condition held after the previous cycle
+	watchpoints []*Watchpoint
+	registers   []int16 // value of each register watchpoint after the previous cycle
+	transfers   []transfer
+	resume      bool // the run paused before a cycle, which must be executed without checking breakpoints
+}
+
+// transfer is a value moved from one node to another during a cycle.
+type transfer struct {
+	from, to *Node
+	value    int16
+}
+
+// AddBreakpoint adds a breakpoint on the given node and source line.
+// The line must hold an instruction.
+func (e *Engine) AddBreakpoint(b Breakpoint) error {
+	if err := e.checkNode(b.Node); err != nil {
+		return err
+	}
+	if b.Condition != nil {
+		if err := e.checkNode(b.Condition.Node); err != nil {
+			return err
+		}
+	}
+	for _, ins := range e.Nodes[b.Node].Instructions {
+		if ins.Line == b.Line {
+			e.debug.breakpoints = append(e.debug.breakpoints, &b)
+			return nil
+		}
+	}
+	return fmt.Errorf("no instruction at line %d of node @%d", b.Line+1, b.Node+1)
+}
+
+// AddCondition adds a condition that pauses a run whenever it becomes true.
+func (e *Engine) AddCondition(c Condition) error {
+	if err := e.checkNode(c.Node); err != nil {
+		return err
+	}
+	e.debug.conditions = append(e.debug.conditions, &c)
+	e.debug.held = append(e.debug.held, c.Holds(e))
+	return nil
+}
+
+// AddWatchpoint adds a watchpoint on a register or a port of a grid node.
+func (e *Engine) AddWatchpoint(w Watchpoint) error {
+	if err := e.checkNode(w.Node); err != nil {
+		return err
+	}
+	if w.OnPort && w.Port > PortRight {
+		return fmt.Errorf("cannot watch port %s", portName(w.Port))
+	}
+	e.debug.watchpoints = append(e.debug.watchpoints, &w)
+	e.debug.registers = append(e.debug.registers, e.Nodes[w.Node].register(w.Register))
+	return nil
+}
+
+// Breakpoints returns the breakpoints of the engine, including those set with `!` in the source.
+func (e *Engine) Breakpoints() []*Breakpoint {
+	return e.debug.breakpoints
+}
+
+// ClearDebugger removes every breakpoint, condition and watchpoint.
+func (e *Engine) ClearDebugger() {
+	e.debug = debugger{}
+}
+
+// checkNode validates a zero-based grid node index.
+func (e *Engine) checkNode(node int) error {
+	if node < 0 || node >= len(e.Nodes) {
+		return fmt.Errorf("no node @%d", node+1)
+	}
+	if e.Nodes[node].Type == model.DAMAGED {
+		return fmt.Errorf("node @%d is damaged", node+1)
+	}
+	return nil
+}
+
+// debugging reports whether any breakpoint, condition or watchpoint is set.
+func (e *Engine) debugging() bool {
+	d := &e.debug
+	return len(d.breakpoints) > 0 || len(d.conditions) > 0 || len(d.watchpoints) > 0
+}
+
+// checkBreakpoints returns the first breakpoint whose node is about to start the marked instruction.
+// A node that is blocked has already started its current instruction.
+func (e *Engine) checkBreakpoints() *Stop {
+	for _, b := range e.debug.breakpoints {
+		n := e.Nodes[b.Node]
+		ins := n.instruction()
+		if ins == nil || n.IsBlocked || ins.Line != b.Line {
+			continue
+		}
+		if b.Condition == nil || b.Condition.Holds(e) {
+			return &Stop{Breakpoint: b}
+		}
+	}
+	return nil
+}
+
+// checkWatches updates the state of conditions and watchpoints after a cycle
+// and returns the first one that triggered.
+func (e *Engine) checkWatches() *Stop {
+	d := &e.debug
+	var stop *Stop
+
+	for i, c := range d.conditions {
+		holds := c.Holds(e)
+		if holds && !d.held[i] && stop == nil {
+			stop = &Stop{Condition: c}
+		}
+		d.held[i] = holds
+	}
+
+	for i, w := range d.watchpoints {
+		n := e.Nodes[w.Node]
+		if w.OnPort {
+			for _, t := range d.transfers {
+				crosses := (t.to == n && n.Ports[w.Port] == t.from) || (t.from == n && n.Ports[w.Port] == t.to)
+				if crosses && stop == nil {
+					stop = &Stop{Watchpoint: w, Value: t.value}
+				}
+			}
+			continue
+		}
+		value := n.register(w.Register)
+		if value != d.registers[i] && stop == nil {
+			stop = &Stop{Watchpoint: w, Previous: d.registers[i], Value: value}
+		}
+		d.registers[i] = value
+	}
+	return stop
+}
+
+// sync resets the state of conditions and watchpoints to the current machine state,
+// e.g. after it was restored from a snapshot.
+func (d *debugger) sync(e *Engine) {
+	for i, c := range d.conditions {
+		d.held[i] = c.Holds(e)
+	}
+	for i, w := range d.watchpoints {
+		d.registers[i] = e.Nodes[w.Node].register(w.Register)
+	}
+	d.transfers = d.transfers[:0]
+	d.resume = false
+}
+
+// recordTransfers remembers the values read this cycle, for port watchpoints.
+// Only the transfers of the last cycle are kept, so cycles run without debugging
+// do not trigger the watchpoints of a later run.
+func (e *Engine) recordTransfers() {
+	e.debug.transfers = e.debug.transfers[:0]
+	if len(e.debug.watchpoints) == 0 {
+		return
+	}
+	for list := e.ActiveNodes; list != nil; list = list.Next {
+		n := list.Node
+		if src := n.next.source; src != nil && src.claimant == n {
+			e.debug.transfers = append(e.debug.transfers, transfer{from: src, to: n, value: src.OutboundValue})
+		}
+	}
+}
+
+// register returns the value of the given register of the node.
+func (n *Node) register(r Register) int16 {
+	if r == RegisterBAK {
+		return n.BAK
+	}
+	return n.ACC
+}
+
+// ParseCondition parses a condition such as `@5 ACC < 0` or `node 5 ACC<0`.
+// Nodes are numbered from 1 as in `.tis` files.
+func ParseCondition(s string) (*Condition, error) {
+	invalid := fmt.Errorf("invalid condition %q: expected `@<node> <ACC|BAK> <operator> <value>`", s)
+
+	text := strings.TrimSpace(s)
+	if fields := strings.Fields(text); len(fields) > 0 && strings.EqualFold(fields[0], "node") {
+		text = strings.TrimSpace(text[len(fields[0]):])
+	}
+	ind := strings.IndexAny(text, "<>=!")
+	if ind == -1 {
+		return nil, invalid
+	}
+	operands := strings.Fields(text[:ind])
+	if len(operands) != 2 {
+		return nil, invalid
+	}
+	operator := ""
+	for _, op := range comparisons {
+		if strings.HasPrefix(text[ind:], op) {
+			operator = op
+			break
+		}
+	}
+	if operator == "" {
+		return nil, fmt.Errorf("invalid operator in condition %q", s)
+	}
+
+	node, err := parseNodeNumber(operands[0])
+	if err != nil {
+		return nil, err
+	}
+	register, err := parseRegister(operands[1])
+	if err != nil {
+		return nil, err
+	}
+	valueText := strings.TrimSpace(text[ind+len(operator):])
+	value, err := strconv.ParseInt(valueText, 10, 16)
+	if err != nil {
+		return nil, fmt.Errorf("invalid value %q", valueText)
+	}
+
+	return &Condition{Node: node, Register: register, Operator: operator, Value: int16(value)}, nil
+}
+
+// ParseBreakpoint parses a breakpoint such as `@5:3`, optionally followed by
+// `if <condition>`. Nodes and lines are numbered from 1.
+func ParseBreakpoint(s string) (*Breakpoint, error) {
+	location, condition, conditional := strings.Cut(strings.TrimSpace(s), " if ")
+	nodeText, lineText, ok := strings.Cut(strings.TrimSpace(location), ":")
+	if !ok {
+		return nil, fmt.Errorf("invalid breakpoint %q: expected `@<node>:<line>`", s)
+	}
+
+	node, err := parseNodeNumber(nodeText)
+	if err != nil {
+		return nil, err
+	}
+	line, err := strconv.Atoi(lineText)
+	if err != nil || line < 1 {
+		return nil, fmt.Errorf("invalid line %q", lineText)
+	}
+
+	b := &Breakpoint{Node: node, Line: line - 1}
+	if conditional {
+		if b.Condition, err = ParseCondition(condition); err != nil {
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
+// ParseWatchpoint parses a watchpoint such as `@5 ACC` or `@5 LEFT`.
+// Nodes are numbered from 1.
+func ParseWatchpoint(s string) (*Watchpoint, error) {
+	fields := strings.Fields(s)
+	if len(fields) != 2 {
+		return nil, fmt.Errorf("invalid watchpoint %q: expected `@<node> <ACC|BAK|UP|DOWN|LEFT|RIGHT>`", s)
+	}
+
+	node, err := parseNodeNumber(fields[0])
+	if err != nil {
+		return nil, err
+	}
+	if register, err := parseRegister(fields[1]); err == nil {
+		return &Watchpoint{Node: node, Register: register}, nil
+	}
+	port, ok := portNameMap[strings.ToUpper(fields[1])]
+	if !ok || port > PortRight {
+		return nil, fmt.Errorf("invalid register or port %q", fields[1])
+	}
+	return &Watchpoint{Node: node, Port: port, OnPort: true}, nil
+}
+
+// parseNodeNumber parses a 1-based node number, with or without the `@` prefix,
+// into a zero-based grid node index.
+func parseNodeNumber(s string) (int, error) {
+	number, err := strconv.Atoi(strings.TrimPrefix(s, "@"))
+	if err != nil || number < 1 || number > model.NodesNumber {
+		return 0, fmt.Errorf("invalid node %q", s)
+	}
+	return number - 1, nil
+}
+
+// parseRegister parses the name of a register.
+func parseRegister(s string) (Register, error) {
+	for r, name := range registerNames {
+		if strings.EqualFold(s, name) {
+			return r, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid register %q", s)
+}
+
+// portName returns the name of the port in the TIS-100 syntax.
+func portName(p Port) string {
+	for name, port := range portNameMap {
+		if port == p {
+			return name
+		}
+	}
+	return "?"
+}
@@ -0,0 +1,205 @@
+package engine_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lekomish/tis-100/internal/engine"
+	"github.com/lekomish/tis-100/internal/model"
+)
+
+/* TESTS */
+
+// --- AddBreakpoint ---
+func TestBreakpointPausesBeforeInstruction(t *testing.T) {
+	eng := newCounterEngine(t, []string{"ADD 1", "ADD 10"})
+	require.NoError(t, eng.AddBreakpoint(engine.Breakpoint{Node: 0, Line: 1}))
+
+	res := eng.Run(engine.RunOptions{MaxCycles: 100, Debug: true})
+	require.Equal(t, engine.Paused, res.Reason)
+	require.Equal(t, "breakpoint @1:2", res.Stop.String())
+	require.Equal(t, 1, res.Cycles)
+	require.Equal(t, int16(1), eng.Nodes[0].ACC, "the marked instruction should not be executed yet")
+
+	res = eng.Run(engine.RunOptions{MaxCycles: 100, Debug: true})
+	require.Equal(t, engine.Paused, res.Reason)
+	require.Equal(t, 3, res.Cycles, "the run should continue past the breakpoint and reach it again")
+	require.Equal(t, int16(12), eng.Nodes[0].ACC)
+}
+
+func TestBreakpointIgnoredWithoutDebug(t *testing.T) {
+	eng := newCounterEngine(t, []string{"ADD 1"})
+	require.NoError(t, eng.AddBreakpoint(engine.Breakpoint{Node: 0, Line: 0}))
+
+	res := eng.Run(engine.RunOptions{MaxCycles: 5})
+	require.Equal(t, engine.CycleLimit, res.Reason)
+}
+
+func TestBreakpointOnBlockedNodePausesOnce(t *testing.T) {
+	code := &model.Code{Title: "TEST", Nodes: make([][]string, model.NodesNumber)}
+	code.Nodes[0] = []string{"MOV RIGHT ACC"}
+	code.Nodes[1] = []string{"NOP", "NOP", "MOV 5 LEFT"}
+
+	eng, err := engine.NewEngine(pendingOutput(), nil, code)
+	require.NoError(t, err)
+	require.NoError(t, eng.AddBreakpoint(engine.Breakpoint{Node: 0, Line: 0}))
+
+	res := eng.Run(engine.RunOptions{MaxCycles: 100, Debug: true})
+	require.Equal(t, 0, res.Cycles)
+
+	res = eng.Run(engine.RunOptions{MaxCycles: 100, Debug: true})
+	require.Equal(t, engine.Paused, res.Reason)
+	require.Equal(t, 4, res.Cycles, "the waiting node should pause again only after the read completed")
+	require.Equal(t, int16(5), eng.Nodes[0].ACC)
+}
+
+func TestConditionalBreakpoint(t *testing.T) {
+	eng := newCounterEngine(t, []string{"ADD 1"})
+	b, err := engine.ParseBreakpoint("@1:1 if @1 ACC >= 3")
+	require.NoError(t, err)
+	require.NoError(t, eng.AddBreakpoint(*b))
+
+	res := eng.Run(engine.RunOptions{MaxCycles: 100, Debug: true})
+	require.Equal(t, engine.Paused, res.Reason)
+	require.Equal(t, int16(3), eng.Nodes[0].ACC)
+}
+
+func TestBreakpointFromSource(t *testing.T) {
+	eng := newCounterEngine(t, []string{"ADD 1", "LOOP: !ADD 10", "!END:", "JMP LOOP"})
+	require.Len(t, eng.Breakpoints(), 2)
+	require.Equal(t, "@1:2", eng.Breakpoints()[0].String())
+	require.Equal(t, "@1:4", eng.Breakpoints()[1].String(), "a label-only line should mark the next instruction")
+
+	res := eng.Run(engine.RunOptions{MaxCycles: 100, Debug: true})
+	require.Equal(t, "breakpoint @1:2", res.Stop.String())
+}
+
+func TestAddBreakpointErrors(t *testing.T) {
+	eng := newCounterEngine(t, []string{"START:", "ADD 1"})
+	require.ErrorContains(t, eng.AddBreakpoint(engine.Breakpoint{Node: 0, Line: 0}), "no instruction at line 1 of node @1")
+	require.ErrorContains(t, eng.AddBreakpoint(engine.Breakpoint{Node: 12}), "no node @13")
+}
+
+// --- AddCondition ---
+func TestConditionPausesWhenItBecomesTrue(t *testing.T) {
+	eng := newCounterEngine(t, []string{"SUB 1"})
+	c, err := engine.ParseCondition("node 1 ACC<-1")
+	require.NoError(t, err)
+	require.NoError(t, eng.AddCondition(*c))
+
+	res := eng.Run(engine.RunOptions{MaxCycles: 100, Debug: true})
+	require.Equal(t, engine.Paused, res.Reason)
+	require.Equal(t, "condition @1 ACC < -1", res.Stop.String())
+	require.Equal(t, 2, res.Cycles)
+
+	res = eng.Run(engine.RunOptions{MaxCycles: 100, Debug: true})
+	require.Equal(t, engine.CycleLimit, res.Reason, "a condition that stays true should not pause again")
+}
+
+// --- AddWatchpoint ---
+func TestRegisterWatchpoint(t *testing.T) {
+	eng := newCounterEngine(t, []string{"NOP", "ADD 1", "SAV"})
+	w, err := engine.ParseWatchpoint("@1 BAK")
+	require.NoError(t, err)
+	require.NoError(t, eng.AddWatchpoint(*w))
+
+	res := eng.Run(engine.RunOptions{MaxCycles: 100, Debug: true})
+	require.Equal(t, engine.Paused, res.Reason)
+	require.Equal(t, 3, res.Cycles)
+	require.Equal(t, "watchpoint @1 BAK: 0 -> 1", res.Stop.String())
+}
+
+func TestPortWatchpoint(t *testing.T) {
+	eng := newPipeEngine(t, []int16{7, 8}, []int16{7, 8})
+	w, err := engine.ParseWatchpoint("@5 down")
+	require.NoError(t, err)
+	require.NoError(t, eng.AddWatchpoint(*w))
+
+	res := eng.Run(engine.RunOptions{Debug: true})
+	require.Equal(t, engine.Paused, res.Reason)
+	require.Equal(t, "watchpoint @5 DOWN: value 7", res.Stop.String())
+	require.Empty(t, eng.Outputs[0].Values)
+
+	res = eng.Run(engine.RunOptions{Debug: true})
+	require.Equal(t, "watchpoint @5 DOWN: value 8", res.Stop.String())
+
+	eng.ClearDebugger()
+	res = eng.Run(engine.RunOptions{Debug: true})
+	require.Equal(t, engine.Completed, res.Reason)
+}
+
+func TestPortWatchpointIgnoresCyclesWithoutDebug(t *testing.T) {
+	eng := newPipeEngine(t, []int16{7, 8}, []int16{7, 8})
+	w, err := engine.ParseWatchpoint("@5 down")
+	require.NoError(t, err)
+	require.NoError(t, eng.AddWatchpoint(*w))
+
+	for len(eng.Outputs[0].Values) == 0 {
+		_, err := eng.Tick()
+		require.NoError(t, err)
+	}
+
+	res := eng.Run(engine.RunOptions{Debug: true})
+	require.Equal(t, engine.Paused, res.Reason)
+	require.Equal(t, "watchpoint @5 DOWN: value 8", res.Stop.String(), "the value 7 went through before debugging")
+}
+
+func TestAddWatchpointErrors(t *testing.T) {
+	eng := newCounterEngine(t, []string{"ADD 1"})
+	require.ErrorContains(t, eng.AddWatchpoint(engine.Watchpoint{Node: 0, OnPort: true, Port: engine.PortAny}), "cannot watch port ANY")
+
+	eng, err := engine.NewEngine(nil, newLayout(1), &model.Code{Nodes: make([][]string, model.NodesNumber)})
+	require.NoError(t, err)
+	require.ErrorContains(t, eng.AddWatchpoint(engine.Watchpoint{Node: 1}), "node @2 is damaged")
+}
+
+// --- Parse ---
+func TestParseErrors(t *testing.T) {
+	for _, spec := range []string{"@1 ACC", "@13 ACC < 0", "@1 PC < 0", "@1 ACC <> 0", "@1 ACC < x"} {
+		_, err := engine.ParseCondition(spec)
+		require.Error(t, err, spec)
+	}
+	for _, spec := range []string{"@1", "@1:0", "@1:x", "@1:1 if ACC"} {
+		_, err := engine.ParseBreakpoint(spec)
+		require.Error(t, err, spec)
+	}
+	for _, spec := range []string{"@1", "@1 ANY", "@1 PC"} {
+		_, err := engine.ParseWatchpoint(spec)
+		require.Error(t, err, spec)
+	}
+}
+
+// Holds -> covered in previous tests
+// String -> covered in previous tests
+// Breakpoints -> covered in previous tests
+// ClearDebugger -> covered in previous tests
+// checkNode -> covered in previous tests
+// debugging -> covered in previous tests
+// checkBreakpoints -> covered in previous tests
+// checkWatches -> covered in previous tests
+// recordTransfers -> covered in previous tests
+// register -> covered in previous tests
+// parseNodeNumber -> covered in previous tests
+// parseRegister -> covered in previous tests
+// portName -> covered in previous tests
+
+/* UTILS */
+
+// newCounterEngine creates an engine with the given code in the first node,
+// whose run only ends at the cycle limit (see `pendingOutput`).
+func newCounterEngine(tb testing.TB, lines []string) *engine.Engine {
+	tb.Helper()
+
+	code := &model.Code{Title: "COUNTER", Nodes: make([][]string, model.NodesNumber)}
+	code.Nodes[0] = lines
+	eng, err := engine.NewEngine(pendingOutput(), nil, code)
+	require.NoError(tb, err)
+	return eng
+}
+
+// pendingOutput returns an output stream below the last column that no node writes to,
+// so runs are not completed.
+func pendingOutput() []*model.Stream {
+	return []*model.Stream{{Type: model.OUTPUT, Name: "OUT", Position: 3, Values: []int16{1}}}
+}
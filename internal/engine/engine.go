@@ -8,12 +8,32 @@
 // Each node operates independently per tick, using message-passing through directional
 // ports (UP, DOWN, LEFT, RIGHT), shared accumulator (ACC), and auxiliary register (BAK).
 //
-// The engine tracks all active nodes, executes them in order each cycle, and reports
-// when all are blocked (indicating program stalling or completion).
+// The engine tracks all active nodes, executes them in lockstep each cycle, and reports
+// when all are blocked (indicating program stalling or completion). `Engine.Run` drives
+// the cycles on behalf of its callers and reports why and when the execution terminated.
+// Tools following the execution attach an `Observer` to receive its events cycle by cycle.
+//
+// The timing follows the original game, so cycle counts can be compared with its scores
+// (see `testdata/conformance` for the solutions scored by the original game the rules are checked
+// against, and `testdata/timing` for the regression tests of every rule):
+//   - every instruction, jumps included, takes one cycle; label-only lines are not instructions;
+//   - a value written to a port becomes readable in the next cycle; the writer stays blocked
+//     until the value is read and executes its next instruction in the cycle after that;
+//   - a value written to ANY is offered to every neighbour, the first reader takes it (the node
+//     with the lowest index wins ties) and becomes LAST for both nodes;
+//   - JRO reads its offset like any other source, blocking on ports, and a target before the first
+//     or after the last instruction is clamped to it;
+//   - LAST behaves like NIL until ANY has been used, and writing to NIL discards the value;
+//   - a value written to an unconnected port is never read, so the writer blocks forever;
+//   - input streams behave like a node writing its values down one by one, and output streams
+//     record a value in the cycle it is read, which ends the run when it is the last one;
+//   - image streams draw the values they read (see `Image`) and end the run once they show the expected image.
 package engine
 
 import (
 	"errors"
+	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/lekomish/tis-100/internal/model"
@@ -34,38 +54,65 @@ type Engine struct {
 	NodeList    *NodeList // linked list of ephemeral input/output nodes
 	ActiveNodes *NodeList // linked list of nodes that are acitve each tick
 	Outputs     []*Output // output values produced by output nodes
+	Cycle       int       // number of cycles executed so far
+
+	expected    []*model.Stream  // expected output streams, aligned with `Outputs`
+	streamNames map[*Node]string // names of the input and output nodes
+	debug       debugger         // breakpoints, conditions and watchpoints
+	observation observation      // attached observers and the events of the current cycle
+	livelock    livelockDetector // states recorded to detect livelocks
+	memories    []*Node          // stack memory nodes, settled at the end of every cycle
 }
 
-// NewEngine initializes an `Engine` with the provided input/output streams and code.
+// NewEngine initializes an `Engine` with the provided input/output streams, node layout and code.
 // It creates and connects nodes in a 4x3 grid and loads the program into them.
-func NewEngine(streams []*model.Stream, code *model.Code) (*Engine, error) {
+// A nil layout is treated as a grid of fully working compute nodes.
+// Damaged nodes are left unconnected, so neighbours reading or writing toward them block forever.
+// Stack memory nodes take the values written to them by any neighbour and offer them back last in, first out.
+// The code is compiled in `ModeSandbox`.
+func NewEngine(streams []*model.Stream, layout []model.NodeType, code *model.Code) (*Engine, error) {
+	return NewEngineWithMode(streams, layout, code, ModeSandbox)
+}
+
+// NewEngineWithMode initializes an `Engine` like `NewEngine`, compiling the code in the given mode.
+func NewEngineWithMode(streams []*model.Stream, layout []model.NodeType, code *model.Code, mode Mode) (*Engine, error) {
+	if layout != nil && len(layout) != model.NodesNumber {
+		return nil, errors.New("wrong layout size")
+	}
+
 	nodes := make([]*Node, 0, model.NodesNumber)
 	for i := range model.NodesNumber {
 		n := NewNode()
 		n.Index = uint8(i + positionOffset)
+		if layout != nil {
+			n.Type = layout[i]
+		}
 		nodes = append(nodes, n)
 	}
 
-	e := &Engine{Nodes: nodes, Outputs: make([]*Output, 0)}
-	// set up directional connections between adjacent nodes in the grid
+	e := &Engine{Nodes: nodes, Outputs: make([]*Output, 0), streamNames: make(map[*Node]string)}
+	// set up directional connections between adjacent working nodes in the grid
 	for i, n := range e.Nodes {
+		if n.Type == model.DAMAGED {
+			continue
+		}
 		row := i / cols
 		col := i % cols
 		if row < rows-1 {
-			n.Ports[PortDown] = e.Nodes[i+cols]
+			n.Ports[PortDown] = e.connectable(i + cols)
 		}
 		if row > 0 {
-			n.Ports[PortUp] = e.Nodes[i-cols]
+			n.Ports[PortUp] = e.connectable(i - cols)
 		}
 		if col < cols-1 {
-			n.Ports[PortRight] = e.Nodes[i+1]
+			n.Ports[PortRight] = e.connectable(i + 1)
 		}
 		if col > 0 {
-			n.Ports[PortLeft] = e.Nodes[i-1]
+			n.Ports[PortLeft] = e.connectable(i - 1)
 		}
 	}
 
-	if err := e.loadInstructions(code); err != nil {
+	if err := e.loadInstructions(code, mode); err != nil {
 		return nil, err
 	}
 	if err := e.initStreams(streams); err != nil {
@@ -75,27 +122,68 @@ func NewEngine(streams []*model.Stream, code *model.Code) (*Engine, error) {
 	return e, nil
 }
 
-// Tick executes one cycle of the engine by ticking all active nodes.
-// Returns true if all active nodes are blocked (i.e., no further progress is possible).
+// Tick executes one cycle of the engine in two phases.
+// In the compute phase every active node executes its instruction against the state
+// the machine had at the start of the cycle. In the commit phase the results are applied
+// all at once, so a value written in one cycle can only be read in the next one and
+// the outcome does not depend on the order of `ActiveNodes`. When several nodes read
+// the same value, the node with the lowest index gets it.
+// Returns true if all active nodes are blocked and no value was read or written during
+// the cycle (i.e., no further progress is possible).
 func (e *Engine) Tick() (bool, error) {
-	allBlocked := true
+	e.Cycle++
+	observed := e.observed()
+	if observed {
+		e.observeStart()
+	}
+
+	// compute phase
 	for list := e.ActiveNodes; list != nil; list = list.Next {
-		if err := list.Node.Tick(); err != nil {
+		if err := list.Node.compute(); err != nil {
 			return false, err
 		}
-		allBlocked = allBlocked && list.Node.IsBlocked
 	}
+
+	// commit phase
+	for list := e.ActiveNodes; list != nil; list = list.Next {
+		list.Node.claim()
+	}
+	allBlocked := true
+	for list := e.ActiveNodes; list != nil; list = list.Next {
+		progressed := list.Node.commit(e.Cycle)
+		allBlocked = allBlocked && !progressed
+	}
+	e.recordTransfers()
+	if observed {
+		e.observeCommit()
+	}
+	for list := e.ActiveNodes; list != nil; list = list.Next {
+		list.Node.release()
+	}
+	for _, n := range e.memories {
+		n.settle()
+	}
+	if observed {
+		e.observeEnd()
+	}
+
 	return allBlocked, nil
 }
 
 // initStreams initializes the stream nodes (input/output) and appends them to the active list.
 func (e *Engine) initStreams(streams []*model.Stream) error {
 	for _, stream := range streams {
+		if stream.Position >= model.IOPositionsNumber {
+			return fmt.Errorf("stream %q: position %d out of range (0-%d)", stream.Name, stream.Position, model.IOPositionsNumber-1)
+		}
 		switch stream.Type {
 		case model.INPUT:
 			n := e.createInputNode(stream)
 			e.ActiveNodes = e.ActiveNodes.Prepend(n)
-		case model.OUTPUT:
+		case model.OUTPUT, model.IMAGE:
+			if stream.Type == model.IMAGE && stream.Len() != model.ImageWidth*model.ImageHeight {
+				return fmt.Errorf("image stream %q must have %d pixels", stream.Name, model.ImageWidth*model.ImageHeight)
+			}
 			n := e.createOutputNode(stream)
 			e.ActiveNodes = e.ActiveNodes.Append(n)
 		default:
@@ -106,37 +194,103 @@ func (e *Engine) initStreams(streams []*model.Stream) error {
 }
 
 // loadInstructions parses and loads the given code into the corresponding physical nodes.
-func (e *Engine) loadInstructions(code *model.Code) error {
+func (e *Engine) loadInstructions(code *model.Code, mode Mode) error {
 	if len(code.Nodes) != model.NodesNumber {
 		return errors.New("wrong nodes number")
 	}
-
-	allInput := make([]*InputCode, model.NodesNumber)
-	for i := range model.NodesNumber {
-		allInput[i] = NewInputCode()
+	for i, n := range e.Nodes {
+		if n.Type == model.DAMAGED && hasCode(code.Nodes[i]) {
+			return fmt.Errorf("node @%d is damaged and cannot hold code", i+1)
+		}
+		if n.Type == model.MEMORY && hasCode(code.Nodes[i]) {
+			return fmt.Errorf("node @%d is a stack memory node and cannot hold code", i+1)
+		}
 	}
 
-	// format and parse each line of code into uppercased instrucionts
-	for i, n := range code.Nodes {
-		for _, line := range n {
-			formatted := strings.ToUpper(strings.TrimSpace(line))
-			allInput[i].AddLine(formatted)
-		}
+	compiled, err := CompileWithMode(code, mode)
+	if err != nil {
+		return err
 	}
 
-	// compile instructions for each node and mark them as active if needed
+	// mark nodes with instructions and stack memory nodes as active and register the breakpoints set in the source
 	for i, n := range e.Nodes {
-		if err := n.compileCode(allInput[i]); err != nil {
-			return err
+		n.Instructions = compiled[i]
+		if n.Type == model.MEMORY {
+			n.settle()
+			e.memories = append(e.memories, n)
+			e.ActiveNodes = e.ActiveNodes.Append(n)
 		}
 		if len(n.Instructions) > 0 {
 			e.ActiveNodes = e.ActiveNodes.Append(n)
 		}
+		for _, ins := range n.Instructions {
+			if ins.Breakpoint {
+				e.debug.breakpoints = append(e.debug.breakpoints, &Breakpoint{Node: i, Line: ins.Line})
+			}
+		}
 	}
 
 	return nil
 }
 
+// Compile parses the code of every node into executable instructions without building an engine.
+// It returns one instruction list per physical node, in grid order.
+// If the code has problems, the error is a `CompileErrors` listing all of them.
+// The code is compiled in `ModeSandbox`.
+func Compile(code *model.Code) ([][]*Instruction, error) {
+	return CompileWithMode(code, ModeSandbox)
+}
+
+// CompileWithMode parses the code of every node like `Compile`, within the limits of the given mode.
+func CompileWithMode(code *model.Code, mode Mode) ([][]*Instruction, error) {
+	if len(code.Nodes) != model.NodesNumber {
+		return nil, errors.New("wrong nodes number")
+	}
+
+	compiled := make([][]*Instruction, model.NodesNumber)
+	var errs CompileErrors
+	for i, lines := range code.Nodes {
+		// format and parse each line of code into uppercased instrucionts
+		input := NewInputCode()
+		for _, line := range lines {
+			input.AddLine(strings.ToUpper(line))
+		}
+
+		n := NewNode()
+		nodeErrs := append(mode.checkSize(lines), n.compileCode(input, mode)...)
+		sort.SliceStable(nodeErrs, func(a, b int) bool { return nodeErrs[a].Line < nodeErrs[b].Line })
+		for _, err := range nodeErrs {
+			err.Node = i
+			errs = append(errs, err)
+		}
+		compiled[i] = n.Instructions
+	}
+
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return compiled, nil
+}
+
+// hasCode reports whether the given node source contains any non-blank line.
+func hasCode(lines []string) bool {
+	for _, line := range lines {
+		if strings.TrimSpace(line) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// connectable returns the physical node at the given grid index,
+// or nil if it is damaged and therefore cannot be connected to.
+func (e *Engine) connectable(i int) *Node {
+	if e.Nodes[i].Type == model.DAMAGED {
+		return nil
+	}
+	return e.Nodes[i]
+}
+
 // createEphemeralNode creates a temporary node (used for I/O) and adds it to the engine's node list.
 func (e *Engine) createEphemeralNode() *Node {
 	n := NewNode()
@@ -148,11 +302,14 @@ func (e *Engine) createEphemeralNode() *Node {
 func (e *Engine) createInputNode(stream *model.Stream) *Node {
 	inputNode := e.createEphemeralNode()
 	inputNode.Index = stream.Position
+	e.nameStream(inputNode, stream, "input")
 	belowNode := e.Nodes[stream.Position]
 
-	// connect ports
-	inputNode.Ports[PortDown] = belowNode
-	belowNode.Ports[PortUp] = inputNode
+	// connect ports, unless the node below is damaged and the values have nowhere to go
+	if belowNode.Type != model.DAMAGED {
+		inputNode.Ports[PortDown] = belowNode
+		belowNode.Ports[PortUp] = inputNode
+	}
 
 	// load MOV instructions for each stream value
 	for _, value := range stream.Values {
@@ -163,35 +320,56 @@ func (e *Engine) createInputNode(stream *model.Stream) *Node {
 		ins.Dest.Port = PortDown
 	}
 
-	// add a JRO 0 to keep the node busy and cycling
-	ins := inputNode.appendInstruction(OpJro)
-	ins.SrcType = Immediate
-	ins.Src.Value = 0
+	// once the stream is exhausted, wait on the unconnected UP port so the node stays blocked
+	ins := inputNode.appendInstruction(OpMov)
+	ins.SrcType = PortRef
+	ins.Src.Port = PortUp
+	ins.DestType = PortRef
+	ins.Dest.Port = PortNil
 
 	return inputNode
 }
 
+// nameStream records the name of a stream node, derived from its kind and position if the stream has none.
+func (e *Engine) nameStream(n *Node, stream *model.Stream, kind string) {
+	name := stream.Name
+	if name == "" {
+		name = fmt.Sprintf("%s %d", kind, stream.Position)
+	}
+	e.streamNames[n] = name
+}
+
 // createOutputNode constructs a node that reads from the node above and stores values in an `Output`.
+// A value is recorded in the cycle it is read, which is the cycle counted by the original game.
+// The values of an image stream are also drawn on the display of the output.
 func (e *Engine) createOutputNode(stream *model.Stream) *Node {
 	outputNode := e.createEphemeralNode()
 	outputNode.Index = stream.Position + outputOffset
+	kind := "output"
+	if stream.Type == model.IMAGE {
+		kind = "image"
+	}
+	e.nameStream(outputNode, stream, kind)
 	aboveNode := e.Nodes[stream.Position+outputOffset-2*positionOffset]
 
-	// connect ports
-	outputNode.Ports[PortUp] = aboveNode
-	aboveNode.Ports[PortDown] = outputNode
+	// connect ports, unless the node above is damaged and can never produce values
+	if aboveNode.Type != model.DAMAGED {
+		outputNode.Ports[PortUp] = aboveNode
+		aboveNode.Ports[PortDown] = outputNode
+	}
 
-	// instruction to pull value from above and store it in ACC
-	ins := outputNode.appendInstruction(OpMov)
+	// instruction to pull value from above and output it in the same cycle
+	ins := outputNode.appendInstruction(OpOut)
 	ins.SrcType = PortRef
 	ins.Src.Port = PortUp
-	ins.DestType = PortRef
-	ins.Dest.Port = PortAcc
-	// instruction to output the ACC value
-	outputNode.appendInstruction(OpOut)
 
 	// bind output buffer to output node
-	e.Outputs = append(e.Outputs, NewOutput(stream.Position))
+	out := NewOutput(stream.Position)
+	if stream.Type == model.IMAGE {
+		out = NewImageOutput(stream.Position)
+	}
+	e.Outputs = append(e.Outputs, out)
+	e.expected = append(e.expected, stream)
 	outputNode.Output = e.Outputs[len(e.Outputs)-1]
 
 	return outputNode
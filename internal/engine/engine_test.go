@@ -25,7 +25,7 @@ func TestNewEngineValidSetup(t *testing.T) {
 		{Type: model.OUTPUT, Position: 0, Values: []int16{1}},
 	}
 
-	eng, err := engine.NewEngine(streams, code)
+	eng, err := engine.NewEngine(streams, nil, code)
 	require.NoError(t, err)
 	require.NotNil(t, eng)
 	require.Len(t, eng.Nodes, 12)
@@ -40,11 +40,60 @@ func TestNewEngineInvalidCodeLength(t *testing.T) {
 	}
 	strems := []*model.Stream{}
 
-	eng, err := engine.NewEngine(strems, code)
+	eng, err := engine.NewEngine(strems, nil, code)
 	require.Nil(t, eng)
 	require.ErrorContains(t, err, "wrong nodes number")
 }
 
+func TestNewEngineWrongLayoutSize(t *testing.T) {
+	code := &model.Code{Title: "TEST", Nodes: make([][]string, model.NodesNumber)}
+	layout := []model.NodeType{model.COMPUTE, model.DAMAGED}
+
+	eng, err := engine.NewEngine(nil, layout, code)
+	require.Nil(t, eng)
+	require.ErrorContains(t, err, "wrong layout size")
+}
+
+func TestNewEngineStreamPositionOutOfRange(t *testing.T) {
+	code := &model.Code{Title: "TEST", Nodes: make([][]string, model.NodesNumber)}
+	for _, streamType := range []model.StreamType{model.INPUT, model.OUTPUT} {
+		streams := []*model.Stream{{Type: streamType, Name: "S", Position: model.IOPositionsNumber}}
+
+		eng, err := engine.NewEngine(streams, nil, code)
+		require.Nil(t, eng)
+		require.EqualError(t, err, `stream "S": position 4 out of range (0-3)`)
+	}
+}
+
+func TestNewEngineCodeInDamagedNode(t *testing.T) {
+	code := &model.Code{Title: "TEST", Nodes: make([][]string, model.NodesNumber)}
+	code.Nodes[1] = []string{"MOV UP DOWN"}
+
+	eng, err := engine.NewEngine(nil, newLayout(1), code)
+	require.Nil(t, eng)
+	require.ErrorContains(t, err, "node @2 is damaged and cannot hold code")
+}
+
+func TestNewEngineDamagedNodeIsUnconnected(t *testing.T) {
+	code := &model.Code{Title: "TEST", Nodes: make([][]string, model.NodesNumber)}
+	streams := []*model.Stream{
+		{Type: model.INPUT, Position: 1, Values: []int16{1}},
+		{Type: model.OUTPUT, Position: 1, Values: []int16{1}},
+	}
+
+	eng, err := engine.NewEngine(streams, newLayout(5), code)
+	require.NoError(t, err)
+
+	damaged := eng.Nodes[5]
+	require.Equal(t, model.DAMAGED, damaged.Type)
+	require.Equal(t, [4]*engine.Node{nil, nil, nil, nil}, damaged.Ports)
+	require.Nil(t, eng.Nodes[1].Ports[engine.PortDown])
+	require.Nil(t, eng.Nodes[9].Ports[engine.PortUp])
+	require.Nil(t, eng.Nodes[4].Ports[engine.PortRight])
+	require.Nil(t, eng.Nodes[6].Ports[engine.PortLeft])
+	require.Equal(t, eng.Nodes[0], eng.Nodes[1].Ports[engine.PortLeft])
+}
+
 // --- Tick ---
 func TestEngineTickAllNodesBlocked(t *testing.T) {
 	code := &model.Code{
@@ -66,7 +115,7 @@ func TestEngineTickAllNodesBlocked(t *testing.T) {
 	}
 	streams := []*model.Stream{}
 
-	eng, err := engine.NewEngine(streams, code)
+	eng, err := engine.NewEngine(streams, nil, code)
 	require.NoError(t, err)
 
 	blocked, tickErr := eng.Tick()
@@ -97,31 +146,194 @@ func TestEngineTickWithInputOutput(t *testing.T) {
 		{Type: model.OUTPUT, Position: 0, Values: []int16{42}},
 	}
 
-	eng, err := engine.NewEngine(streams, code)
+	eng, err := engine.NewEngine(streams, nil, code)
 	require.NoError(t, err)
 
-	for range 3 {
+	// every hop takes a cycle, since written values become readable in the next cycle:
+	// input, three nodes and the output
+	for range 4 {
 		_, err := eng.Tick()
 		require.NoError(t, err)
 	}
+	require.Empty(t, eng.Outputs[0].Values)
 
+	_, err = eng.Tick()
+	require.NoError(t, err)
 	require.Len(t, eng.Outputs[0].Values, 1)
 	require.Equal(t, int16(42), eng.Outputs[0].Values[0])
 }
 
+func TestEngineTickWriteTowardDamagedNodeBlocks(t *testing.T) {
+	code := &model.Code{Title: "TEST", Nodes: make([][]string, model.NodesNumber)}
+	code.Nodes[0] = []string{"MOV 1 RIGHT"}
+	code.Nodes[2] = []string{"MOV LEFT ACC"}
+
+	eng, err := engine.NewEngine(nil, newLayout(1), code)
+	require.NoError(t, err)
+
+	// the first cycle issues the write, which is never read
+	blocked, err := eng.Tick()
+	require.NoError(t, err)
+	require.False(t, blocked)
+
+	for range 5 {
+		blocked, err := eng.Tick()
+		require.NoError(t, err)
+		require.True(t, blocked)
+	}
+	require.True(t, eng.Nodes[0].IsWriting)
+	require.Equal(t, uint8(0), eng.Nodes[0].InstructionPointer)
+	require.Equal(t, int16(0), eng.Nodes[2].ACC)
+}
+
+func TestEngineTickWriteVisibleInNextCycle(t *testing.T) {
+	code := &model.Code{Title: "TEST", Nodes: make([][]string, model.NodesNumber)}
+	code.Nodes[0] = []string{"MOV 5 RIGHT"}
+	code.Nodes[1] = []string{"MOV LEFT ACC"}
+
+	eng, err := engine.NewEngine(nil, nil, code)
+	require.NoError(t, err)
+
+	blocked, err := eng.Tick()
+	require.NoError(t, err)
+	require.False(t, blocked)
+	require.Equal(t, eng.Nodes[1], eng.Nodes[0].OutboundTarget)
+	require.Equal(t, int16(0), eng.Nodes[1].ACC)
+
+	_, err = eng.Tick()
+	require.NoError(t, err)
+	require.Equal(t, int16(5), eng.Nodes[1].ACC)
+	require.Nil(t, eng.Nodes[0].OutboundTarget)
+}
+
+func TestEngineTickIsOrderIndependent(t *testing.T) {
+	newEngine := func() *engine.Engine {
+		code := &model.Code{Title: "TEST", Nodes: make([][]string, model.NodesNumber)}
+		code.Nodes[0] = []string{"MOV UP ACC", "ADD ACC", "MOV ACC RIGHT"}
+		code.Nodes[1] = []string{"MOV LEFT DOWN", "MOV UP DOWN"}
+		code.Nodes[5] = []string{"MOV UP ANY"}
+		code.Nodes[9] = []string{"MOV ANY DOWN"}
+		streams := []*model.Stream{
+			{Type: model.INPUT, Position: 0, Values: []int16{1, 2, 3, 4}},
+			{Type: model.INPUT, Position: 1, Values: []int16{5, 6, 7, 8}},
+			{Type: model.OUTPUT, Position: 1, Values: []int16{2, 5, 4, 6, 6, 7, 8, 8}},
+		}
+		eng, err := engine.NewEngine(streams, nil, code)
+		require.NoError(t, err)
+		return eng
+	}
+
+	forward := newEngine()
+	backward := newEngine()
+	backward.ActiveNodes = reverseList(backward.ActiveNodes)
+
+	forwardRes := forward.Run(engine.RunOptions{})
+	backwardRes := backward.Run(engine.RunOptions{})
+	require.Equal(t, engine.Completed, forwardRes.Reason)
+	require.Equal(t, forwardRes, backwardRes)
+	require.Equal(t, forward.Outputs[0].Values, backward.Outputs[0].Values)
+}
+
+func TestEngineTickAnyWriteGoesToLowestIndexReader(t *testing.T) {
+	code := &model.Code{Title: "TEST", Nodes: make([][]string, model.NodesNumber)}
+	code.Nodes[0] = []string{"MOV RIGHT ACC", "WAIT: JMP WAIT"}
+	code.Nodes[1] = []string{"MOV 7 ANY"}
+	code.Nodes[2] = []string{"MOV LEFT ACC"}
+
+	eng, err := engine.NewEngine(nil, nil, code)
+	require.NoError(t, err)
+
+	for range 2 {
+		_, err := eng.Tick()
+		require.NoError(t, err)
+	}
+	require.Equal(t, int16(7), eng.Nodes[0].ACC)
+	require.Equal(t, int16(0), eng.Nodes[2].ACC)
+	require.True(t, eng.Nodes[2].IsBlocked)
+	require.Equal(t, eng.Nodes[0], eng.Nodes[1].Last)
+
+	// the next value offered to ANY goes to the reader still waiting
+	for range 2 {
+		_, err := eng.Tick()
+		require.NoError(t, err)
+	}
+	require.Equal(t, int16(7), eng.Nodes[2].ACC)
+}
+
+func TestEngineLabelOnlyLinesAreNotInstructions(t *testing.T) {
+	code := &model.Code{Title: "TEST", Nodes: make([][]string, model.NodesNumber)}
+	code.Nodes[0] = []string{"START:", "ADD 1", "JMP START", "END:"}
+
+	eng, err := engine.NewEngine(nil, nil, code)
+	require.NoError(t, err)
+	require.Len(t, eng.Nodes[0].Instructions, 2)
+
+	for range 4 {
+		_, err := eng.Tick()
+		require.NoError(t, err)
+	}
+	require.Equal(t, int16(2), eng.Nodes[0].ACC)
+}
+
+func TestEngineInstructionsKeepSourceLines(t *testing.T) {
+	code := &model.Code{Title: "TEST", Nodes: make([][]string, model.NodesNumber)}
+	code.Nodes[0] = []string{"START:", "", "ADD 1", "END: JMP START"}
+
+	eng, err := engine.NewEngine(nil, nil, code)
+	require.NoError(t, err)
+	require.Len(t, eng.Nodes[0].Instructions, 2)
+	require.Equal(t, 2, eng.Nodes[0].Instructions[0].Line)
+	require.Equal(t, 3, eng.Nodes[0].Instructions[1].Line)
+}
+
+func TestEngineJumpRelativeOperands(t *testing.T) {
+	code := &model.Code{Title: "TEST", Nodes: make([][]string, model.NodesNumber)}
+	code.Nodes[0] = []string{"JRO ACC", "JRO UP", "JRO -1", "JRO 2", "JRO ANY", "JRO LAST", "JRO NIL"}
+
+	compiled, err := engine.Compile(code)
+	require.NoError(t, err)
+	require.Len(t, compiled[0], 7)
+	require.Equal(t, engine.PortRef, compiled[0][1].SrcType)
+	require.Equal(t, engine.PortUp, compiled[0][1].Src.Port)
+	require.Equal(t, engine.Immediate, compiled[0][2].SrcType)
+	require.Equal(t, int16(-1), compiled[0][2].Src.Value)
+
+	code.Nodes[0] = []string{"L: JRO L"}
+	_, err = engine.Compile(code)
+	require.EqualError(t, err, "@1:1: invalid operand L")
+}
+
 // initStreams -> covered in previous tests
 // loadInstructions -> covered in previous tests
 // createEphemeralNode -> covered in previous tests
 // createInputNode -> covered in previous tests
 // createOutputNode -> covered in previous tests
 // appendInstruction -> covered in previous tests
-// compileCode -> covered in previous tests
-// parseInstruction -> covered in previous tests
-// parseMovInstruction -> covered in previous tests
-// parseUnaryInstruction -> covered in previous tests
-// parseJumpInstruction -> covered in previous tests
-// parseOperation -> covered in previous tests
+// compileCode -> covered in compile_error_test.go
+// parseInstruction -> covered in compile_error_test.go
+// parseOperation -> covered in compile_error_test.go
 // read -> covered in previous tests
 // write -> covered in previous tests
 // getInputPort -> covered in previous tests
 // getOutputPort -> covered in previous tests
+// connectable -> covered in previous tests
+
+/* UTILS */
+
+// reverseList returns a new list with the nodes of the given list in reverse order.
+func reverseList(list *engine.NodeList) *engine.NodeList {
+	var reversed *engine.NodeList
+	for ; list != nil; list = list.Next {
+		reversed = reversed.Prepend(list.Node)
+	}
+	return reversed
+}
+
+// newLayout returns a layout of compute nodes where the nodes at the given indexes are damaged.
+func newLayout(damaged ...int) []model.NodeType {
+	layout := make([]model.NodeType, model.NodesNumber)
+	for _, i := range damaged {
+		layout[i] = model.DAMAGED
+	}
+	return layout
+}
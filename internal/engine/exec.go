@@ -7,7 +7,8 @@ import (
 
 // read attempts to read a value from the specified operand.
 // It returns the value, a `blocked` flag (true if the read couldn't be completed),
-// and an error if the read is malformed.
+// and an error if the read is malformed. Reads see the state from the start of the cycle;
+// a value taken from another node is recorded as the source to be released on commit.
 //
 // Behavior:
 // - Immediate: returns the constant value.
@@ -15,13 +16,9 @@ import (
 //   - NIL: returns 0.
 //   - ACC: returns the node's ACC value.
 //   - ANY: probes ports in order (Left, Right, Up, Down) and reads if one is writing to this node.
-//   - LAST: returns 0 if a previous input node was used, but does not block.
-//   - Otherwise: reads from the designated direction if the peer is targeting this node.
+//   - LAST: reads from the node last used by ANY, or returns 0 like NIL if ANY was never used.
+//   - Otherwise: reads from the designated direction if the peer is writing to this node.
 func (n *Node) read(opType OperandType, op Operand) (int16, bool, error) {
-	if n.OutboundTarget != nil {
-		// if this node is trying to send a value, it can't receive
-		return 0, false, nil
-	}
 	if opType == Immediate {
 		// literal value
 		return op.Value, false, nil
@@ -35,30 +32,21 @@ func (n *Node) read(opType OperandType, op Operand) (int16, bool, error) {
 		// return from accumulator
 		return n.ACC, false, nil
 	case PortUp, PortRight, PortDown, PortLeft, PortAny, PortLast:
-		readFrom := n.getInputPort(op.Port)
-		if readFrom == nil {
-			// no connection or no node sending data
-			return 0, true, nil
-		} else if readFrom.OutboundTarget == n {
-			// successful read
-			val := readFrom.OutboundValue
-
-			// reset the sender
-			readFrom.OutboundValue = 0
-			readFrom.OutboundTarget = nil
-			readFrom.advancePC()
-
-			if op.Port == PortAny {
-				n.Last = readFrom
-			}
-			return val, false, nil
-		} else if op.Port == PortLast {
-			// no data available from previous connection
+		if op.Port == PortLast && n.Last == nil {
+			// LAST without a previous ANY behaves like NIL
 			return 0, false, nil
-		} else {
-			// data not ready
+		}
+		readFrom := n.getInputPort(op.Port)
+		if readFrom == nil || !readFrom.writesTo(n) {
+			// no connection or data not ready
 			return 0, true, nil
 		}
+		// successful read, the sender is reset on commit
+		n.next.source = readFrom
+		if op.Port == PortAny {
+			n.next.last = readFrom
+		}
+		return readFrom.OutboundValue, false, nil
 	default:
 		return 0, false, fmt.Errorf("invalid port: %v", op.Port)
 	}
@@ -66,30 +54,34 @@ func (n *Node) read(opType OperandType, op Operand) (int16, bool, error) {
 
 // write attempts to send a value to the specified port.
 // Returns a `blocked` flag (true if the send couldn't be completed) and an error if invalid.
+// A value sent to another node becomes readable in the next cycle, and the writer stays
+// blocked until it is read. A value sent to an unconnected port is never read.
+// Values are clamped to the range of ACC, as sandbox immediates may exceed it.
 //
 // Behavior:
 // - ACC: stores the value in the accumulator.
-// - ANY: sends to the first compatible output node.
-// - LAST: reuses the last successful destination.
-// - Specific direction: sends to the connected node, if available and not already sending.
+// - NIL: discards the value.
+// - ANY: offers the value to every neighbour, the first one to read it takes it.
+// - LAST: sends to the node last used by ANY, or discards the value like NIL if ANY was never used.
+// - Specific direction: sends to the connected node.
 func (n *Node) write(port Port, value int16) (bool, error) {
 	switch port {
 	case PortAcc:
-		n.ACC = value
+		n.setACC(int(value))
+		return false, nil
+	case PortNil:
 		return false, nil
 	case PortUp, PortRight, PortDown, PortLeft, PortAny, PortLast:
-		dest := n.getOutputPort(port)
-		if dest != nil && n.OutboundTarget == nil {
-			n.OutboundTarget = dest
-			n.OutboundValue = value
-			if port == PortAny {
-				n.Last = dest
-			}
+		if port == PortLast && n.Last == nil {
+			// LAST without a previous ANY behaves like NIL
+			return false, nil
 		}
-		// couldn't find destination or already outputting
+		n.next.isWriting = true
+		n.next.outboundPort = port
+		n.next.outboundTarget = n.getOutputPort(port)
+		n.next.outboundValue = clampValue(int(value))
+		// the write completes once a neighbour reads the value
 		return true, nil
-	case PortNil:
-		return false, errors.New("unable to write")
 	default:
 		return false, errors.New("nowhere to write")
 	}
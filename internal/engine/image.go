@@ -0,0 +1,65 @@
+package engine
+
+import "github.com/lekomish/tis-100/internal/model"
+
+// Stages of the image protocol, naming the meaning of the next value written to the display.
+const (
+	imageX      = iota // X coordinate of the first pixel to draw
+	imageY             // Y coordinate of the first pixel to draw
+	imageColour        // colour of the next pixel of the row
+)
+
+// Image is the display of an image output stream, `model.ImageWidth` by `model.ImageHeight` pixels.
+// Values are drawn with the protocol of the original game: the X and Y coordinates of a pixel
+// followed by the colours of consecutive pixels of its row, until a negative value starts over
+// with new coordinates. Pixels outside the display are not drawn, and colours outside the palette
+// are drawn black.
+type Image struct {
+	Pixels []int16 // colour of every pixel, row by row
+	Cycles []int   // cycle every pixel was last drawn at (0 if never drawn)
+
+	x, y  int // coordinates of the next pixel to draw
+	stage int // meaning of the next value, one of the image stages
+}
+
+// NewImage returns a black display.
+func NewImage() *Image {
+	return &Image{
+		Pixels: make([]int16, model.ImageWidth*model.ImageHeight),
+		Cycles: make([]int, model.ImageWidth*model.ImageHeight),
+	}
+}
+
+// Draw applies a value written to the display at the given cycle.
+func (img *Image) Draw(value int16, cycle int) {
+	if value < 0 {
+		img.stage = imageX
+		return
+	}
+
+	switch img.stage {
+	case imageX:
+		img.x = int(value)
+		img.stage = imageY
+	case imageY:
+		img.y = int(value)
+		img.stage = imageColour
+	default:
+		if img.x < model.ImageWidth && img.y < model.ImageHeight {
+			if value >= model.ImageColours {
+				value = 0
+			}
+			i := img.y*model.ImageWidth + img.x
+			img.Pixels[i] = value
+			img.Cycles[i] = cycle
+		}
+		img.x++
+	}
+}
+
+// Clear turns every pixel black and waits for new coordinates.
+func (img *Image) Clear() {
+	clear(img.Pixels)
+	clear(img.Cycles)
+	img.x, img.y, img.stage = 0, 0, imageX
+}
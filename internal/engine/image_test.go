@@ -0,0 +1,144 @@
+package engine_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lekomish/tis-100/internal/engine"
+	"github.com/lekomish/tis-100/internal/model"
+)
+
+/* TESTS */
+
+// --- Draw ---
+func TestImageDraw(t *testing.T) {
+	img := engine.NewImage()
+	values := []int16{2, 1, 3, 4, -1, 29, 16, 1, 2, -5, 40, 0, 3, -1, 1, 0, 9}
+	for i, value := range values {
+		img.Draw(value, i+1)
+	}
+
+	require.Equal(t, int16(3), pixelAt(img, 2, 1))
+	require.Equal(t, int16(4), pixelAt(img, 3, 1), "the colours should continue along the row")
+	require.Equal(t, 4, img.Cycles[model.ImageWidth+3])
+	require.Equal(t, int16(1), pixelAt(img, 29, 16))
+	require.Equal(t, int16(0), pixelAt(img, 0, 17), "the right edge should not wrap to the next row")
+	require.Equal(t, int16(0), pixelAt(img, 10, 1), "pixels outside the display should be ignored")
+	require.Equal(t, int16(0), pixelAt(img, 1, 0), "colours outside the palette should be drawn black")
+	require.Equal(t, len(values), img.Cycles[1])
+
+	img.Clear()
+	require.Equal(t, engine.NewImage(), img)
+}
+
+// --- Run ---
+func TestRunImage(t *testing.T) {
+	expected := make([]int16, model.ImageWidth*model.ImageHeight)
+	expected[model.ImageWidth+2], expected[model.ImageWidth+3] = 3, 4
+
+	tests := []struct {
+		name   string
+		code   []string
+		reason engine.Termination
+		wrong  int
+		cycles int
+	}{
+		{
+			name:   "drawn",
+			code:   []string{"MOV 2 DOWN", "MOV 1 DOWN", "MOV 3 DOWN", "MOV 4 DOWN", "MOV -1 DOWN", "JRO 0"},
+			reason: engine.Completed,
+			cycles: 8,
+		},
+		{
+			name:   "redrawn",
+			code:   []string{"MOV 2 DOWN", "MOV 1 DOWN", "MOV 1 DOWN", "MOV 4 DOWN", "MOV -1 DOWN", "MOV 2 DOWN", "MOV 1 DOWN", "MOV 3 DOWN"},
+			reason: engine.Completed,
+			cycles: 16,
+		},
+		{
+			name:   "wrong",
+			code:   []string{"MOV 2 DOWN", "MOV 1 DOWN", "MOV 3 DOWN", "MOV 1 DOWN", "MOV 0 DOWN", "JRO 0"},
+			reason: engine.CycleLimit,
+			wrong:  1,
+			cycles: 50,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			eng := newImageEngine(t, tt.code, expected)
+			res := eng.Run(engine.RunOptions{MaxCycles: 50})
+			require.Equal(t, tt.reason, res.Reason)
+			require.Equal(t, tt.cycles, res.Cycles)
+
+			comparisons := eng.CompareOutputs()
+			require.Len(t, comparisons, 1)
+			require.True(t, comparisons[0].Image)
+			require.Len(t, comparisons[0].Differences, tt.wrong)
+		})
+	}
+}
+
+func TestCompareImage(t *testing.T) {
+	expected := make([]int16, model.ImageWidth*model.ImageHeight)
+	expected[1] = 2
+	eng := newImageEngine(t, []string{"MOV 0 DOWN", "MOV 0 DOWN", "MOV 4 DOWN", "MOV 3 DOWN", "JRO 0"}, expected)
+	eng.Run(engine.RunOptions{MaxCycles: 20})
+
+	c := eng.CompareOutputs()[0]
+	require.Equal(t, "IMAGE", c.Stream)
+	require.Equal(t, []engine.Difference{
+		{Kind: engine.WrongValue, Index: 0, Expected: 0, Actual: 4, Cycle: 6},
+		{Kind: engine.WrongValue, Index: 1, Expected: 2, Actual: 3, Cycle: 8},
+	}, c.Differences)
+}
+
+func TestImageStreamSize(t *testing.T) {
+	code := &model.Code{Title: "IMAGE", Nodes: make([][]string, model.NodesNumber)}
+	streams := []*model.Stream{{Type: model.IMAGE, Name: "IMAGE", Position: 0, Values: []int16{1, 2}}}
+
+	_, err := engine.NewEngine(streams, nil, code)
+	require.EqualError(t, err, `image stream "IMAGE" must have 540 pixels`)
+}
+
+func TestRestoreImage(t *testing.T) {
+	expected := make([]int16, model.ImageWidth*model.ImageHeight)
+	expected[1] = 1
+	eng := newImageEngine(t, []string{"MOV 0 DOWN", "MOV 0 DOWN", "MOV 4 DOWN", "JRO 0"}, expected)
+	eng.Run(engine.RunOptions{MaxCycles: 7})
+	s := eng.Snapshot()
+	pixels := append([]int16(nil), eng.Outputs[0].Image.Pixels...)
+	require.Equal(t, int16(4), pixels[0])
+
+	restored := newImageEngine(t, []string{"MOV 0 DOWN", "MOV 0 DOWN", "MOV 4 DOWN", "JRO 0"}, expected)
+	require.NoError(t, restored.Restore(s))
+	require.Equal(t, pixels, restored.Outputs[0].Image.Pixels)
+	require.Equal(t, eng.Outputs[0].Image.Cycles, restored.Outputs[0].Image.Cycles)
+}
+
+// NewImage -> covered in previous tests
+// Clear -> covered in previous tests
+// NewImageOutput -> covered in previous tests
+// compareImage -> covered in previous tests
+// outputComplete -> covered in previous tests
+// restore -> covered in previous tests
+
+/* UTILS */
+
+// pixelAt returns the colour of the pixel of the image at the given coordinates.
+func pixelAt(img *engine.Image, x, y int) int16 {
+	return img.Pixels[y*model.ImageWidth+x]
+}
+
+// newImageEngine creates an engine with the given code in the first node of the bottom row,
+// which writes down to an image stream expecting the given pixels.
+func newImageEngine(tb testing.TB, lines []string, expected []int16) *engine.Engine {
+	tb.Helper()
+
+	code := &model.Code{Title: "IMAGE", Nodes: make([][]string, model.NodesNumber)}
+	code.Nodes[8] = lines
+	streams := []*model.Stream{{Type: model.IMAGE, Name: "IMAGE", Position: 0, Values: expected}}
+	eng, err := engine.NewEngine(streams, nil, code)
+	require.NoError(tb, err)
+	return eng
+}
@@ -5,27 +5,38 @@ import "strings"
 // InputCode represents raw assembly-like source code for a node,
 // including the lines of code and any labels mapped to line numbers.
 type InputCode struct {
-	Lines  []string         // list of code lines, in source order
-	Labels map[string]uint8 // mapping from label name to line index
+	Lines   []string         // list of code lines, in source order
+	Numbers []int            // zero-based source line number of each entry in `Lines`
+	Columns []int            // zero-based column of the first character of each entry in `Lines`
+	Labels  map[string]uint8 // mapping from label name to line index
+
+	added int // number of lines passed to `AddLine`, including ignored ones
 }
 
 // NewInputCode initializes and returns a pointer to an InputCode instance
 // with preallocated slices and maps.
 func NewInputCode() *InputCode {
 	return &InputCode{
-		Lines:  make([]string, 0),
-		Labels: make(map[string]uint8),
+		Lines:   make([]string, 0),
+		Numbers: make([]int, 0),
+		Columns: make([]int, 0),
+		Labels:  make(map[string]uint8),
 	}
 }
 
 // AddLine appends a new line of code to the input.
-// Empty or whitespace-only lines are ignored.
+// Empty or whitespace-only lines are ignored, but still count as source lines.
 func (ic *InputCode) AddLine(line string) {
-	line = strings.TrimSpace(line)
-	if line == "" {
+	number := ic.added
+	ic.added++
+
+	trimmed := strings.TrimSpace(line)
+	if trimmed == "" {
 		return
 	}
-	ic.Lines = append(ic.Lines, line)
+	ic.Lines = append(ic.Lines, trimmed)
+	ic.Numbers = append(ic.Numbers, number)
+	ic.Columns = append(ic.Columns, strings.Index(line, trimmed))
 }
 
 // AddLabel registers a label pointing to the given line index.
@@ -48,3 +59,21 @@ func (ic *InputCode) LineAt(index int) (string, bool) {
 	}
 	return ic.Lines[index], true
 }
+
+// numberAt returns the source line number of the line at the given index.
+// Lines added without `AddLine` are numbered by their index.
+func (ic *InputCode) numberAt(index int) int {
+	if index < len(ic.Numbers) {
+		return ic.Numbers[index]
+	}
+	return index
+}
+
+// columnAt returns the column of the line at the given index.
+// Lines added without `AddLine` start at the first column.
+func (ic *InputCode) columnAt(index int) int {
+	if index < len(ic.Columns) {
+		return ic.Columns[index]
+	}
+	return 0
+}
@@ -33,6 +33,8 @@ func TestAddLine(t *testing.T) {
 	ic.AddLine(" ADD ACC 1 ")
 	require.Len(t, ic.Lines, 2)
 	require.Equal(t, "ADD ACC 1", ic.Lines[1])
+	require.Equal(t, []int{0, 3}, ic.Numbers, "ignored lines should still be counted")
+	require.Equal(t, []int{0, 1}, ic.Columns)
 }
 
 // --- AddLabel ---
@@ -72,3 +74,5 @@ func TestLineAt(t *testing.T) {
 	_, ok = ic.LineAt(-1)
 	require.False(t, ok)
 }
+
+// numberAt -> covered in previous tests
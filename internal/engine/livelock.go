@@ -0,0 +1,79 @@
+package engine
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// Repetition is the error of the `Livelock` results of `Engine.Run`: the machine came back
+// to a state it already had, so it would go through the same cycles forever.
+type Repetition struct {
+	Cycle   int // cycle at the end of which the state repeated
+	Repeats int // earlier cycle with the same state
+}
+
+// Error implements `error`.
+func (r *Repetition) Error() string {
+	return fmt.Sprintf("state at cycle %d repeats cycle %d", r.Cycle, r.Repeats)
+}
+
+// livelockDetector remembers the states the machine went through. The states are never
+// forgotten during a run, so it grows with the number of recorded states.
+type livelockDetector struct {
+	seen map[string]int // cycle of every recorded state
+	key  []byte         // buffer of the current state
+}
+
+// checkLivelock records the current state of the machine every `interval` cycles and returns
+// the repetition if the state was already recorded. The outputs only grow, so their lengths
+// stand for their content and a repeated state means nothing was emitted in between.
+func (e *Engine) checkLivelock(interval int) *Repetition {
+	if interval <= 0 {
+		interval = 1
+	}
+	if e.Cycle%interval != 0 {
+		return nil
+	}
+
+	d := &e.livelock
+	if d.seen == nil {
+		d.seen = make(map[string]int)
+	}
+	d.key = e.appendState(d.key[:0])
+	if cycle, ok := d.seen[string(d.key)]; ok {
+		return &Repetition{Cycle: e.Cycle, Repeats: cycle}
+	}
+	d.seen[string(d.key)] = e.Cycle
+	return nil
+}
+
+// appendState appends a binary encoding of the machine state, without the cycle, to buf.
+func (e *Engine) appendState(buf []byte) []byte {
+	for _, n := range e.allNodes() {
+		flags := byte(0)
+		if n.IsBlocked {
+			flags |= 1
+		}
+		if n.IsWriting {
+			flags |= 2
+		}
+		buf = append(buf, flags, n.InstructionPointer, byte(n.OutboundPort),
+			byte(nodeIndex(n.OutboundTarget)), byte(nodeIndex(n.Last)))
+		buf = binary.LittleEndian.AppendUint16(buf, uint16(n.ACC))
+		buf = binary.LittleEndian.AppendUint16(buf, uint16(n.BAK))
+		buf = binary.LittleEndian.AppendUint16(buf, uint16(n.OutboundValue))
+		buf = append(buf, byte(len(n.Stack)))
+		for _, v := range n.Stack {
+			buf = binary.LittleEndian.AppendUint16(buf, uint16(v))
+		}
+	}
+	for _, out := range e.Outputs {
+		buf = binary.LittleEndian.AppendUint16(buf, uint16(out.Len()))
+	}
+	return buf
+}
+
+// reset forgets the recorded states, which no longer lead to the current one.
+func (d *livelockDetector) reset() {
+	d.seen = nil
+}
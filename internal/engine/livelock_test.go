@@ -0,0 +1,93 @@
+package engine_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lekomish/tis-100/internal/engine"
+)
+
+/* TESTS */
+
+// --- Run ---
+func TestRunLivelock(t *testing.T) {
+	tests := []struct {
+		name     string
+		lines    []string
+		interval int
+		expected string
+		cycles   int
+	}{
+		{
+			name:     "spin",
+			lines:    []string{"JRO 0"},
+			expected: "state at cycle 2 repeats cycle 1",
+			cycles:   2,
+		},
+		{
+			name:     "loop",
+			lines:    []string{"L: NOP", "JMP L"},
+			expected: "state at cycle 3 repeats cycle 1",
+			cycles:   3,
+		},
+		{
+			name:     "saturated counter",
+			lines:    []string{"ADD 1"},
+			expected: "state at cycle 1000 repeats cycle 999",
+			cycles:   1000,
+		},
+		{
+			name:     "interval",
+			lines:    []string{"L: JMP L"},
+			interval: 10,
+			expected: "state at cycle 20 repeats cycle 10",
+			cycles:   20,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			eng := newCounterEngine(t, tt.lines)
+			res := eng.Run(engine.RunOptions{DetectLivelock: true, LivelockInterval: tt.interval})
+			require.Equal(t, engine.Livelock, res.Reason)
+			require.Equal(t, tt.cycles, res.Cycles)
+			require.EqualError(t, res.Err, tt.expected)
+
+			var repetition *engine.Repetition
+			require.True(t, errors.As(res.Err, &repetition))
+			require.Equal(t, tt.cycles, repetition.Cycle)
+		})
+	}
+}
+
+func TestRunLivelockDisabled(t *testing.T) {
+	eng := newCounterEngine(t, []string{"L: JMP L"})
+	res := eng.Run(engine.RunOptions{MaxCycles: 100})
+	require.Equal(t, engine.CycleLimit, res.Reason)
+	require.Equal(t, 100, res.Cycles)
+}
+
+func TestRunLivelockProgress(t *testing.T) {
+	eng := newPipeEngine(t, []int16{1, 2, 3}, []int16{1, 2, 3})
+	res := eng.Run(engine.RunOptions{DetectLivelock: true})
+	require.Equal(t, engine.Completed, res.Reason)
+}
+
+func TestRunLivelockAfterRestore(t *testing.T) {
+	eng := newCounterEngine(t, []string{"ADD 1"})
+	initial := eng.Snapshot()
+	res := eng.Run(engine.RunOptions{MaxCycles: 50, DetectLivelock: true})
+	require.Equal(t, engine.CycleLimit, res.Reason)
+
+	// the states recorded before the restore come again, but they are not repetitions
+	require.NoError(t, eng.Restore(initial))
+	res = eng.Run(engine.RunOptions{MaxCycles: 50, DetectLivelock: true})
+	require.Equal(t, engine.CycleLimit, res.Reason)
+	require.Equal(t, 50, res.Cycles)
+}
+
+// Error -> covered in previous tests
+// checkLivelock -> covered in previous tests
+// appendState -> covered in previous tests
+// reset -> covered in previous tests
@@ -0,0 +1,48 @@
+package engine
+
+import "github.com/lekomish/tis-100/internal/model"
+
+// computeMemory takes the value a neighbour writes to the stack memory node, unless the stack is full.
+// The node never counts as progress on its own: it only takes part in the transfers of its neighbours.
+func (n *Node) computeMemory() {
+	n.next = nodeState{
+		blocked:        true,
+		isWriting:      n.IsWriting,
+		outboundPort:   n.OutboundPort,
+		outboundTarget: n.OutboundTarget,
+		outboundValue:  n.OutboundValue,
+	}
+	if len(n.Stack) >= model.MemoryCapacity {
+		return
+	}
+	readFrom := n.getInputPort(PortAny)
+	if readFrom == nil {
+		return
+	}
+	n.next.source = readFrom
+	n.next.push = true
+	n.next.pushValue = readFrom.OutboundValue
+}
+
+// pop removes the value on top of the stack of a memory node once a neighbour has read it.
+func (n *Node) pop() {
+	n.claimant = nil
+	n.Stack = n.Stack[:len(n.Stack)-1]
+}
+
+// settle applies the value pushed to the stack memory node during the cycle and offers the value
+// on top of the stack to every neighbour. A pushed value becomes readable in the next cycle.
+func (n *Node) settle() {
+	if n.next.push {
+		n.Stack = append(n.Stack, n.next.pushValue)
+		n.next.push = false
+	}
+	n.IsBlocked = true
+	n.IsWriting = len(n.Stack) > 0
+	n.OutboundPort = PortAny
+	n.OutboundTarget = nil
+	n.OutboundValue = 0
+	if n.IsWriting {
+		n.OutboundValue = n.Stack[len(n.Stack)-1]
+	}
+}
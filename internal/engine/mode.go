@@ -0,0 +1,79 @@
+package engine
+
+import (
+	"fmt"
+	"math"
+	"unicode/utf8"
+
+	"github.com/lekomish/tis-100/internal/model"
+)
+
+// Mode selects the limits the code of the nodes is compiled with.
+type Mode uint8
+
+// Supported Mode values.
+const (
+	ModeSandbox Mode = iota // no limit on the size of the code, immediates only have to fit in 16 bits
+	ModeStrict              // the limits of the original hardware (see `model.MaxCodeLines` and `model.MaxLineLength`)
+)
+
+// maxInstructions is the number of instructions a node can address in any mode,
+// instruction pointers and labels being bytes.
+const maxInstructions = math.MaxUint8 + 1
+
+// modeNames maps the names accepted by `ParseMode` to Mode values.
+var modeNames = map[string]Mode{
+	"sandbox": ModeSandbox,
+	"strict":  ModeStrict,
+}
+
+// ParseMode converts a name ("sandbox" or "strict") into a Mode.
+func ParseMode(name string) (Mode, error) {
+	mode, ok := modeNames[name]
+	if !ok {
+		return 0, fmt.Errorf("unknown mode: %q", name)
+	}
+	return mode, nil
+}
+
+// String returns the name of the mode.
+func (m Mode) String() string {
+	for name, mode := range modeNames {
+		if mode == m {
+			return name
+		}
+	}
+	return "unknown"
+}
+
+// valueRange returns the lowest and the highest immediate accepted in the mode.
+func (m Mode) valueRange() (int, int) {
+	if m == ModeStrict {
+		return model.MinACC, model.MaxACC
+	}
+	return math.MinInt16, math.MaxInt16
+}
+
+// checkSize reports the lines of a node exceeding the size limits of the mode: the lines beyond
+// `model.MaxCodeLines` and the characters beyond `model.MaxLineLength` in strict mode.
+func (m Mode) checkSize(lines []string) []*CompileError {
+	if m != ModeStrict {
+		return nil
+	}
+
+	var errs []*CompileError
+	for i, line := range lines {
+		if i == model.MaxCodeLines {
+			err := spanError(CodeTooManyLines, 0, len(line), "node has %d lines, at most %d are allowed", len(lines), model.MaxCodeLines)
+			err.Line = i
+			errs = append(errs, err)
+		}
+		if length := utf8.RuneCountInString(line); length > model.MaxLineLength {
+			start := len(string([]rune(line)[:model.MaxLineLength]))
+			err := spanError(CodeLineTooLong, start, len(line), "line has %d characters, at most %d are allowed", length, model.MaxLineLength)
+			err.Line = i
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
@@ -0,0 +1,160 @@
+package engine_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lekomish/tis-100/internal/engine"
+	"github.com/lekomish/tis-100/internal/model"
+)
+
+/* TESTS */
+
+// --- ParseMode ---
+func TestParseMode(t *testing.T) {
+	mode, err := engine.ParseMode("strict")
+	require.NoError(t, err)
+	require.Equal(t, engine.ModeStrict, mode)
+
+	mode, err = engine.ParseMode("sandbox")
+	require.NoError(t, err)
+	require.Equal(t, engine.ModeSandbox, mode)
+
+	_, err = engine.ParseMode("relaxed")
+	require.ErrorContains(t, err, "unknown mode: \"relaxed\"")
+}
+
+// --- String ---
+func TestModeString(t *testing.T) {
+	require.Equal(t, "strict", engine.ModeStrict.String())
+	require.Equal(t, "sandbox", engine.ModeSandbox.String())
+	require.Equal(t, "unknown", engine.Mode(42).String())
+}
+
+// --- CompileWithMode ---
+func TestCompileWithModeLimits(t *testing.T) {
+	tests := []struct {
+		name    string
+		lines   []string
+		strict  string
+		sandbox string
+	}{
+		{
+			name:  "within the limits",
+			lines: append(strings.Split(strings.Repeat("NOP\n", 14), "\n")[:14], "MOV -999 ACC #max"),
+		},
+		{
+			name:   "too many lines",
+			lines:  strings.Split(strings.Repeat("NOP\n", 16), "\n")[:16],
+			strict: "@1:16: node has 16 lines, at most 15 are allowed",
+		},
+		{
+			name:   "line too long",
+			lines:  []string{"NOP", "MOV UP DOWN # relay"},
+			strict: "@1:2: line has 19 characters, at most 18 are allowed",
+		},
+		{
+			name:   "immediate beyond the accumulator",
+			lines:  []string{"ADD 1000"},
+			strict: "@1:1: value 1000 is out of range -999..999",
+		},
+		{
+			name:    "immediate beyond 16 bits",
+			lines:   []string{"MOV 50000 ACC"},
+			strict:  "@1:1: value 50000 is out of range -999..999",
+			sandbox: "@1:1: value 50000 is out of range -32768..32767",
+		},
+		{
+			name:    "immediate beyond integers",
+			lines:   []string{"ADD -9999999999999"},
+			strict:  "@1:1: value -9999999999999 is out of range -999..999",
+			sandbox: "@1:1: value -9999999999999 is out of range -32768..32767",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code := &model.Code{Title: "TEST", Nodes: make([][]string, model.NodesNumber)}
+			code.Nodes[0] = tt.lines
+
+			for mode, expected := range map[engine.Mode]string{engine.ModeStrict: tt.strict, engine.ModeSandbox: tt.sandbox} {
+				_, err := engine.CompileWithMode(code, mode)
+				if expected == "" {
+					require.NoError(t, err, mode.String())
+				} else {
+					require.EqualError(t, err, expected, mode.String())
+				}
+			}
+		})
+	}
+}
+
+func TestCompileWithModeErrorSpans(t *testing.T) {
+	code := &model.Code{Title: "TEST", Nodes: make([][]string, model.NodesNumber)}
+	code.Nodes[2] = []string{"JMP L # back to the start", "MOV 1000 DOWN"}
+
+	_, err := engine.CompileWithMode(code, engine.ModeStrict)
+	var errs engine.CompileErrors
+	require.True(t, errors.As(err, &errs))
+	require.Len(t, errs, 3, "problems of a line and of its size are reported together")
+
+	require.Equal(t, engine.CodeLineTooLong, errs[0].Code)
+	require.Equal(t, 0, errs[0].Line)
+	require.Equal(t, 19, errs[0].Column)
+	require.Equal(t, 26, errs[0].EndColumn)
+	require.Equal(t, engine.CodeUnknownLabel, errs[1].Code)
+	require.Equal(t, 0, errs[1].Line)
+	require.Equal(t, engine.CodeValueOutOfRange, errs[2].Code)
+	require.Equal(t, 5, errs[2].Column)
+	require.Equal(t, 9, errs[2].EndColumn)
+}
+
+func TestCompileWithModeTooManyInstructions(t *testing.T) {
+	code := &model.Code{Title: "TEST", Nodes: make([][]string, model.NodesNumber)}
+	code.Nodes[0] = strings.Split(strings.Repeat("NOP\n", 257), "\n")[:257]
+
+	_, err := engine.CompileWithMode(code, engine.ModeSandbox)
+	require.EqualError(t, err, "@1:257: node has 257 instructions, at most 256 are allowed")
+
+	code.Nodes[0] = code.Nodes[0][:256]
+	_, err = engine.CompileWithMode(code, engine.ModeSandbox)
+	require.NoError(t, err)
+}
+
+// --- NewEngineWithMode ---
+func TestNewEngineWithMode(t *testing.T) {
+	code := &model.Code{Title: "TEST", Nodes: make([][]string, model.NodesNumber)}
+	code.Nodes[0] = []string{"MOV 1000 ACC"}
+
+	eng, err := engine.NewEngineWithMode(nil, nil, code, engine.ModeStrict)
+	require.Nil(t, eng)
+	require.EqualError(t, err, "@1:1: value 1000 is out of range -999..999")
+
+	eng, err = engine.NewEngine(nil, nil, code)
+	require.NoError(t, err, "engines are built in sandbox mode by default")
+	require.Equal(t, int16(1000), eng.Nodes[0].Instructions[0].Src.Value)
+}
+
+func TestSandboxValuesAreClamped(t *testing.T) {
+	code := &model.Code{Title: "TEST", Nodes: make([][]string, model.NodesNumber)}
+	code.Nodes[0] = []string{"MOV 5000 RIGHT"}
+	code.Nodes[1] = []string{"MOV LEFT ACC", "ADD 32767", "MOV ACC DOWN", "MOV -5000 DOWN"}
+	code.Nodes[5] = []string{"MOV UP DOWN"}
+	code.Nodes[9] = []string{"MOV UP DOWN"}
+
+	streams := []*model.Stream{{Type: model.OUTPUT, Name: "OUT", Position: 1, Values: []int16{999, -999}}}
+
+	eng, err := engine.NewEngine(streams, nil, code)
+	require.NoError(t, err)
+	for range 12 {
+		_, err = eng.Tick()
+		require.NoError(t, err)
+	}
+	require.Equal(t, int16(999), eng.Nodes[1].ACC, "values sent through ports and sums stay within ACC")
+	require.Equal(t, []int16{999, -999}, eng.Outputs[0].Values)
+}
+
+// valueRange -> covered in previous tests
+// checkSize -> covered in previous tests
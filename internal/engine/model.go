@@ -22,11 +22,13 @@ type Operand struct {
 // Instruction describes a complete TIS-100-sytle instruction,
 // including the operation and its source and destination operands.
 type Instruction struct {
-	Op       OpCode      // the operation to execute (e.g., MOV, ADD)
-	SrcType  OperandType // type of source operand (Immediate or PortRef)
-	Src      Operand     // source operand value or port
-	DestType OperandType // type of destination operand
-	Dest     Operand     // destination operand value or port
+	Op         OpCode      // the operation to execute (e.g., MOV, ADD)
+	SrcType    OperandType // type of source operand (Immediate or PortRef)
+	Src        Operand     // source operand value or port
+	DestType   OperandType // type of destination operand
+	Dest       Operand     // destination operand value or port
+	Line       int         // zero-based number of the source line within the node's code
+	Breakpoint bool        // the source line is marked with the `!` breakpoint prefix
 }
 
 // Supported OpCode values.
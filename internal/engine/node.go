@@ -1,22 +1,55 @@
 package engine
 
-import "errors"
+import (
+	"errors"
+
+	"github.com/lekomish/tis-100/internal/model"
+)
 
 // Node represents a single compute node in the TIS-100 virtual machine.
 // Each node has its own accumulator (ACC), backup register (BAK),
 // instruction memory, and connections to neighboring nodes.
 type Node struct {
 	Index              uint8          // index of the node in the grid
+	Type               model.NodeType // type of the node in the puzzle layout (e.g., COMPUTE, DAMAGED)
 	IsBlocked          bool           // whether the node is currently blocked (e.g., waiting for input/output)
 	InstructionPointer uint8          // points to the current instruction being executed
 	Instructions       []*Instruction // instruction memory for the node
 	ACC                int16          // accumulator register
 	BAK                int16          // backup register
-	OutboundTarget     *Node          // node this node is currently writing to (if any)
-	Last               *Node          // last node successfully communicated with
+	IsWriting          bool           // whether the node holds a written value that has not been read yet
+	OutboundPort       Port           // port the pending value is written to
+	OutboundTarget     *Node          // node the pending value is written to (nil for ANY or an unconnected port)
+	Last               *Node          // last node communicated with through ANY
 	OutboundValue      int16          // value being sent to `OutboundTarget`
 	Ports              [4]*Node       // connections to neighboring nodes (UP, RIGHT, DOWN, LEFT)
 	Output             *Output        // optional output collector for OUT instruction
+	Stack              []int16        // values held by a stack memory node, the top one last
+
+	next     nodeState // state computed for the next cycle during the compute phase
+	claimant *Node     // reader consuming `OutboundValue` in the current cycle
+}
+
+// nodeState holds the part of a node that an instruction may change.
+// It is filled during the compute phase of a cycle and applied during the commit phase.
+type nodeState struct {
+	blocked        bool
+	ip             uint8
+	acc            int16
+	bak            int16
+	isWriting      bool
+	outboundPort   Port
+	outboundTarget *Node
+	outboundValue  int16
+	last           *Node
+	source         *Node // node whose outbound value is consumed by the instruction
+	emit           bool  // whether `emitValue` is sent to the output collector
+	emitValue      int16
+	ins            *Instruction // instruction attempted, nil while a previous write is pending
+	clamped        bool         // whether `acc` was clamped to the range of ACC
+	unclamped      int          // value written to `acc` before it was clamped
+	push           bool         // whether a stack memory node pushes `pushValue`
+	pushValue      int16
 }
 
 // NewNode creates and returns a new Node initialized instruction memory and ports.
@@ -27,21 +60,55 @@ func NewNode() *Node {
 	}
 }
 
-// Tick executes a single instruction cycle on the node.
-// It fetches, decodes, and executes the current instruction.
-// If the instruction is MOV, ADD, SUB, etc., it performs reads/writes as needed.
-// If the instruction is a jump, it may update the instruction pointer.
+// Tick executes a single instruction cycle on the node in isolation.
+// It runs both phases of an engine cycle (see `Engine.Tick`) for this node alone.
 // Returns an error if execution fails (e.g., invalid instruction or read/write error).
 func (n *Node) Tick() error {
+	if err := n.compute(); err != nil {
+		return err
+	}
+	n.claim()
+	_ = n.commit(0)
+	n.release()
+	return nil
+}
+
+// compute fetches, decodes, and executes the current instruction against the state
+// the machine had at the start of the cycle. The outcome is stored in `next` and
+// becomes visible to the other nodes only after `commit`.
+// If the instruction is MOV, ADD, SUB, etc., it performs reads/writes as needed.
+// If the instruction is a jump, it may update the instruction pointer.
+func (n *Node) compute() error {
+	if n.Type == model.MEMORY {
+		n.computeMemory()
+		return nil
+	}
 	// prevent execution if no instructions are loaded
 	if len(n.Instructions) == 0 {
 		return errors.New("no instructions to execute")
 	}
 
-	n.IsBlocked = true
 	n.resetPCIfOutOfBounds()
+	n.next = nodeState{
+		blocked:        true,
+		ip:             n.InstructionPointer,
+		acc:            n.ACC,
+		bak:            n.BAK,
+		isWriting:      n.IsWriting,
+		outboundPort:   n.OutboundPort,
+		outboundTarget: n.OutboundTarget,
+		outboundValue:  n.OutboundValue,
+		last:           n.Last,
+	}
+	if n.IsWriting {
+		// the value written in a previous cycle has not been read yet
+		return nil
+	}
+
 	// fetch current instruction
 	ins := n.instruction()
+	n.next.ins = ins
+	jumped := false
 
 	switch ins.Op {
 	case OpMov:
@@ -60,70 +127,145 @@ func (n *Node) Tick() error {
 		if err != nil || blocked {
 			return err
 		}
-		n.ACC += val
-		n.clampACC()
+		n.setACC(int(n.ACC) + int(val))
 	case OpSub:
 		// SUB SRC - subtract value from ACC
 		val, blocked, err := n.read(ins.SrcType, ins.Src)
 		if err != nil || blocked {
 			return err
 		}
-		n.ACC -= val
-		n.clampACC()
+		n.setACC(int(n.ACC) - int(val))
 	case OpJmp:
 		// JMP LABEL - unconditional jump
 		n.jumpTo(ins.Src.Value)
-		return nil
+		jumped = true
 	case OpJro:
-		// JRO OFFSET - relative jump
-		n.jumpTo(int16(n.InstructionPointer) + ins.Src.Value)
-		return nil
+		// JRO SRC - relative jump by the value read, clamped to the program
+		val, blocked, err := n.read(ins.SrcType, ins.Src)
+		if err != nil || blocked {
+			return err
+		}
+		n.jumpRelative(val)
+		jumped = true
 	case OpJez:
 		// JEZ LABEL - jump if ACC == 0
 		if n.ACC == 0 {
 			n.jumpTo(ins.Src.Value)
-			return nil
+			jumped = true
 		}
 	case OpJgz:
 		// JGZ LABEL - jump if ACC > 0
 		if n.ACC > 0 {
 			n.jumpTo(ins.Src.Value)
-			return nil
+			jumped = true
 		}
 	case OpJlz:
 		// JLZ LABEL - jump if ACC < 0
 		if n.ACC < 0 {
 			n.jumpTo(ins.Src.Value)
-			return nil
+			jumped = true
 		}
 	case OpJnz:
 		// JNZ LABEL - jump if ACC != 0
 		if n.ACC != 0 {
 			n.jumpTo(ins.Src.Value)
-			return nil
+			jumped = true
 		}
 	case OpSwp:
 		// SWP - swap ACC and BAK
-		n.ACC, n.BAK = n.BAK, n.ACC
+		n.next.acc, n.next.bak = n.next.bak, n.next.acc
 	case OpSav:
 		// SAV - copy ACC to BAK
-		n.BAK = n.ACC
+		n.next.bak = n.next.acc
 	case OpNeg:
 		// NEG - negate ACC
-		n.ACC *= -1
+		n.next.acc *= -1
 	case OpNop:
 		// NOP - do nothing
 	case OpOut:
-		// OUT - write ACC value to output collector
-		if n.Output != nil {
-			n.Output.AddValue(n.ACC)
+		// OUT [SRC] - write value to output collector (ACC when no port is given)
+		val := n.ACC
+		if ins.SrcType == PortRef {
+			v, blocked, err := n.read(ins.SrcType, ins.Src)
+			if err != nil || blocked {
+				return err
+			}
+			val = v
 		}
+		n.next.emit = true
+		n.next.emitValue = val
 	default:
 		return errors.New("unknown operation")
 	}
 
-	// mark node as not blocked and move to the next instruction
-	n.IsBlocked = false
-	n.advancePC()
+	// mark node as not blocked and move to the next instruction unless it jumped
+	n.next.blocked = false
+	if !jumped {
+		n.advancePC()
+	}
 	return nil
 }
+
+// claim registers the node as the reader of the outbound value it consumes this cycle.
+// When several nodes read the same value, the one with the lowest index wins.
+func (n *Node) claim() {
+	src := n.next.source
+	if src == nil {
+		return
+	}
+	if src.claimant == nil || n.Index < src.claimant.Index {
+		src.claimant = n
+	}
+}
+
+// commit applies the state computed by `compute`.
+// A node that lost the claim on the value it read stays blocked and unchanged.
+// Returns true if the node made progress: it completed an instruction,
+// consumed a value or started writing one. Emitted output values are recorded with the given cycle.
+func (n *Node) commit(cycle int) bool {
+	src := n.next.source
+	if src != nil && src.claimant != n {
+		n.IsBlocked = true
+		n.next.push = false
+		return false
+	}
+
+	progressed := !n.next.blocked || src != nil || (!n.IsWriting && n.next.isWriting)
+
+	n.IsBlocked = n.next.blocked
+	n.InstructionPointer = n.next.ip
+	n.ACC = n.next.acc
+	n.BAK = n.next.bak
+	n.IsWriting = n.next.isWriting
+	n.OutboundPort = n.next.outboundPort
+	n.OutboundTarget = n.next.outboundTarget
+	n.OutboundValue = n.next.outboundValue
+	n.Last = n.next.last
+	if n.next.emit && n.Output != nil {
+		n.Output.AddValueAt(n.next.emitValue, cycle)
+	}
+	return progressed
+}
+
+// release completes the write of the node whose outbound value was consumed by this one,
+// resetting the sender and moving it past its MOV instruction. The sender executes its
+// next instruction in the following cycle.
+func (n *Node) release() {
+	src := n.next.source
+	if src == nil || src.claimant != n {
+		return
+	}
+	if src.Type == model.MEMORY {
+		src.pop()
+		return
+	}
+	src.claimant = nil
+	if src.OutboundPort == PortAny {
+		src.Last = n
+	}
+	src.IsBlocked = false
+	src.IsWriting = false
+	src.OutboundTarget = nil
+	src.OutboundValue = 0
+	src.InstructionPointer = src.followingPC(src.InstructionPointer)
+}
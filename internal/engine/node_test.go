@@ -63,6 +63,31 @@ func TestNodeTickSub(t *testing.T) {
 	require.Equal(t, int16(13), node.ACC)
 }
 
+func TestNodeTickClampsSandboxValues(t *testing.T) {
+	tests := []struct {
+		name     string
+		acc      int16
+		ins      *engine.Instruction
+		expected int16
+	}{
+		{name: "ADD beyond 16 bits", acc: 999, ins: &engine.Instruction{Op: engine.OpAdd, SrcType: engine.Immediate, Src: engine.Operand{Value: 32767}}, expected: 999},
+		{name: "SUB beyond 16 bits", acc: -999, ins: &engine.Instruction{Op: engine.OpSub, SrcType: engine.Immediate, Src: engine.Operand{Value: 32767}}, expected: -999},
+		{name: "MOV to ACC", ins: movImmediateToAcc(5000), expected: 999},
+		{name: "negative MOV to ACC", ins: movImmediateToAcc(-32768), expected: -999},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node := engine.NewNode()
+			node.ACC = tt.acc
+			node.Instructions = append(node.Instructions, tt.ins)
+
+			err := node.Tick()
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, node.ACC)
+		})
+	}
+}
+
 func TestNodeTickSavSwp(t *testing.T) {
 	node := engine.NewNode()
 	node.ACC = 9
@@ -110,6 +135,120 @@ func TestNodeTickJumpInstructions(t *testing.T) {
 	require.Equal(t, int16(5), node.ACC)
 }
 
+func TestNodeTickJumpIsNotBlocked(t *testing.T) {
+	node := engine.NewNode()
+	node.Instructions = append(node.Instructions, &engine.Instruction{
+		Op:      engine.OpJro,
+		SrcType: engine.Immediate,
+		Src:     engine.Operand{Value: 0},
+	})
+
+	err := node.Tick()
+	require.NoError(t, err)
+	require.False(t, node.IsBlocked)
+	require.Equal(t, uint8(0), node.InstructionPointer)
+}
+
+func TestNodeTickJumpRelative(t *testing.T) {
+	tests := []struct {
+		name     string
+		src      engine.OperandType
+		value    int16
+		acc      int16
+		expected uint8
+	}{
+		{name: "forward", src: engine.Immediate, value: 2, expected: 3},
+		{name: "backward", src: engine.Immediate, value: -1, expected: 0},
+		{name: "in place", src: engine.Immediate, value: 0, expected: 1},
+		{name: "clamped to the first instruction", src: engine.Immediate, value: -999, expected: 0},
+		{name: "clamped to the last instruction", src: engine.Immediate, value: 999, expected: 3},
+		{name: "register", src: engine.PortRef, acc: 2, expected: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 0: NOP
+			// 1: JRO SRC
+			// 2: NOP
+			// 3: NOP
+			node := engine.NewNode()
+			node.ACC = tt.acc
+			node.InstructionPointer = 1
+			node.Instructions = []*engine.Instruction{
+				{Op: engine.OpNop},
+				{Op: engine.OpJro, SrcType: tt.src, Src: engine.Operand{Value: tt.value, Port: engine.PortAcc}},
+				{Op: engine.OpNop},
+				{Op: engine.OpNop},
+			}
+
+			require.NoError(t, node.Tick())
+			require.Equal(t, tt.expected, node.InstructionPointer)
+		})
+	}
+}
+
+func TestNodeTickMovToNil(t *testing.T) {
+	node := engine.NewNode()
+	node.Instructions = append(node.Instructions, &engine.Instruction{
+		Op:       engine.OpMov,
+		SrcType:  engine.Immediate,
+		Src:      engine.Operand{Value: 1},
+		DestType: engine.PortRef,
+		Dest:     engine.Operand{Port: engine.PortNil},
+	})
+
+	err := node.Tick()
+	require.NoError(t, err)
+	require.False(t, node.IsBlocked)
+	require.False(t, node.IsWriting)
+}
+
+func TestNodeTickLastWithoutAnyActsAsNil(t *testing.T) {
+	node := engine.NewNode()
+	node.ACC = 5
+	node.Instructions = []*engine.Instruction{
+		{
+			Op:       engine.OpMov,
+			SrcType:  engine.PortRef,
+			Src:      engine.Operand{Port: engine.PortLast},
+			DestType: engine.PortRef,
+			Dest:     engine.Operand{Port: engine.PortAcc},
+		},
+		{
+			Op:       engine.OpMov,
+			SrcType:  engine.Immediate,
+			Src:      engine.Operand{Value: 1},
+			DestType: engine.PortRef,
+			Dest:     engine.Operand{Port: engine.PortLast},
+		},
+	}
+
+	err := node.Tick()
+	require.NoError(t, err)
+	require.False(t, node.IsBlocked)
+	require.Equal(t, int16(0), node.ACC)
+
+	err = node.Tick()
+	require.NoError(t, err)
+	require.False(t, node.IsBlocked)
+	require.False(t, node.IsWriting)
+}
+
+func TestNodeTickOutFromPort(t *testing.T) {
+	node := engine.NewNode()
+	node.Output = engine.NewOutput(0)
+	node.Instructions = append(node.Instructions, &engine.Instruction{
+		Op:      engine.OpOut,
+		SrcType: engine.PortRef,
+		Src:     engine.Operand{Port: engine.PortUp},
+	})
+
+	// nothing is connected above, so the node waits
+	err := node.Tick()
+	require.NoError(t, err)
+	require.True(t, node.IsBlocked)
+	require.Empty(t, node.Output.Values)
+}
+
 func TestNodeTickOut(t *testing.T) {
 	node := engine.NewNode()
 	node.ACC = 123
@@ -133,8 +272,15 @@ func TestNodeTick_InvalidOp(t *testing.T) {
 
 // advancePC -> covered in previous tests
 // jumpTo -> covered in previous tests
-// clampACC -> covered in previous tests
+// setACC -> covered in previous tests
+// clampValue -> covered in previous tests
 // resetPCIfOutOfBounds -> covered in previous tests
+// followingPC -> covered in previous tests
+// compute -> covered in previous tests
+// claim -> covered in previous tests
+// commit -> covered in previous tests
+// release -> covered in previous tests
+// writesTo -> covered in previous tests
 // instruction -> covered in previous tests
 
 /* UTILS */
@@ -0,0 +1,165 @@
+package engine
+
+// Observer receives the events of the cycles executed by an engine it is attached to
+// with `Engine.AddObserver`. Events are delivered at the end of every cycle, once its
+// outcome is visible in the nodes, in the order of `Engine.ActiveNodes` and followed
+// by `CycleFinished`. Stream nodes take part in events like grid nodes: input nodes
+// have indexes 0-3 and output nodes indexes 16-19.
+// Embed `NopObserver` to implement only some of the callbacks.
+type Observer interface {
+	// InstructionExecuted is called when a node completes an instruction.
+	// A write to another node completes when the value is read.
+	InstructionExecuted(n *Node, ins *Instruction)
+	// NodeBlocked is called when a node starts waiting for a read or a write to complete.
+	NodeBlocked(n *Node)
+	// NodeUnblocked is called when a blocked node can execute its next instruction.
+	NodeUnblocked(n *Node)
+	// ValueTransferred is called when a node reads a value written by a neighbour,
+	// port being the direction from the writer to the reader.
+	ValueTransferred(from, to *Node, port Port, value int16)
+	// ACCClamped is called when the result of ADD or SUB, or a value moved to ACC,
+	// is clamped to the range of ACC.
+	ACCClamped(n *Node, value int, clamped int16)
+	// ValueEmitted is called when a value is added to an output.
+	ValueEmitted(out *Output, value int16)
+	// CycleFinished is called after the events of a cycle.
+	CycleFinished(cycle int)
+}
+
+// NopObserver is an `Observer` ignoring every event.
+type NopObserver struct{}
+
+// InstructionExecuted implements `Observer`.
+func (NopObserver) InstructionExecuted(*Node, *Instruction) {}
+
+// NodeBlocked implements `Observer`.
+func (NopObserver) NodeBlocked(*Node) {}
+
+// NodeUnblocked implements `Observer`.
+func (NopObserver) NodeUnblocked(*Node) {}
+
+// ValueTransferred implements `Observer`.
+func (NopObserver) ValueTransferred(*Node, *Node, Port, int16) {}
+
+// ACCClamped implements `Observer`.
+func (NopObserver) ACCClamped(*Node, int, int16) {}
+
+// ValueEmitted implements `Observer`.
+func (NopObserver) ValueEmitted(*Output, int16) {}
+
+// CycleFinished implements `Observer`.
+func (NopObserver) CycleFinished(int) {}
+
+// observation collects the events of a cycle for the observers. It is only used
+// while observers are attached, so an engine without observers does no extra work.
+type observation struct {
+	observers []Observer
+	blocked   []bool      // blocked state of the active nodes at the start of the cycle
+	events    []nodeEvent // outcome of the commit phase of the active nodes
+}
+
+// nodeEvent is the outcome of the commit phase of an active node.
+type nodeEvent struct {
+	node    *Node
+	applied bool         // the computed state was committed
+	from    *Node        // writer of the value read by the node, if any
+	fromIns *Instruction // instruction completed by the writer
+	port    Port         // direction from the writer to the node
+	value   int16        // value read by the node
+}
+
+// AddObserver attaches an observer to the engine.
+func (e *Engine) AddObserver(o Observer) {
+	e.observation.observers = append(e.observation.observers, o)
+}
+
+// RemoveObserver detaches an observer from the engine.
+func (e *Engine) RemoveObserver(o Observer) {
+	observers := e.observation.observers[:0]
+	for _, attached := range e.observation.observers {
+		if attached != o {
+			observers = append(observers, attached)
+		}
+	}
+	e.observation.observers = observers
+}
+
+// observed reports whether any observer is attached.
+func (e *Engine) observed() bool {
+	return len(e.observation.observers) > 0
+}
+
+// observeStart records the state of the active nodes before a cycle.
+func (e *Engine) observeStart() {
+	obs := &e.observation
+	obs.blocked = obs.blocked[:0]
+	for list := e.ActiveNodes; list != nil; list = list.Next {
+		obs.blocked = append(obs.blocked, list.Node.IsBlocked)
+	}
+}
+
+// observeCommit records the outcome of the commit phase, before the writers are released.
+func (e *Engine) observeCommit() {
+	obs := &e.observation
+	obs.events = obs.events[:0]
+	for list := e.ActiveNodes; list != nil; list = list.Next {
+		n := list.Node
+		src := n.next.source
+		ev := nodeEvent{node: n, applied: src == nil || src.claimant == n}
+		if src != nil && ev.applied {
+			ev.from = src
+			ev.fromIns = src.instruction()
+			ev.port = src.portTo(n)
+			ev.value = src.OutboundValue
+		}
+		obs.events = append(obs.events, ev)
+	}
+}
+
+// observeEnd delivers the events of the cycle to the observers.
+func (e *Engine) observeEnd() {
+	obs := &e.observation
+	for _, o := range obs.observers {
+		for _, ev := range obs.events {
+			n := ev.node
+			if ev.from != nil {
+				o.ValueTransferred(ev.from, n, ev.port, ev.value)
+				if ev.fromIns != nil {
+					// stack memory nodes serve values without executing instructions
+					o.InstructionExecuted(ev.from, ev.fromIns)
+				}
+			}
+			if !ev.applied {
+				continue
+			}
+			if !n.next.blocked {
+				o.InstructionExecuted(n, n.next.ins)
+			}
+			if n.next.clamped {
+				o.ACCClamped(n, n.next.unclamped, n.next.acc)
+			}
+			if n.next.emit && n.Output != nil {
+				o.ValueEmitted(n.Output, n.next.emitValue)
+			}
+		}
+		for i, ev := range obs.events {
+			switch n := ev.node; {
+			case !obs.blocked[i] && n.IsBlocked:
+				o.NodeBlocked(n)
+			case obs.blocked[i] && !n.IsBlocked:
+				o.NodeUnblocked(n)
+			}
+		}
+		o.CycleFinished(e.Cycle)
+	}
+}
+
+// portTo returns the port connecting the node to the given neighbour, or `PortNil` if none does.
+func (n *Node) portTo(neighbour *Node) Port {
+	for p, node := range n.Ports {
+		if node == neighbour {
+			return Port(p)
+		}
+	}
+	return PortNil
+}
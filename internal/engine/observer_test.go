@@ -0,0 +1,145 @@
+package engine_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lekomish/tis-100/internal/engine"
+	"github.com/lekomish/tis-100/internal/model"
+)
+
+/* TESTS */
+
+// --- AddObserver ---
+func TestObserverPipe(t *testing.T) {
+	eng := newPipeEngine(t, []int16{7}, []int16{7})
+	obs := &eventLog{}
+	eng.AddObserver(obs)
+
+	res := eng.Run(engine.RunOptions{})
+	require.Equal(t, engine.Completed, res.Reason)
+	require.Equal(t, []string{
+		"blocked 0", "blocked 4", "blocked 8", "blocked 12", "blocked 16", "cycle 1",
+		"moved 7 from 0 to 4 through 1", "executed 0:0", "unblocked 0", "cycle 2",
+		"moved 7 from 4 to 8 through 1", "executed 4:0", "blocked 0", "unblocked 4", "cycle 3",
+		"moved 7 from 8 to 12 through 1", "executed 8:0", "blocked 4", "unblocked 8", "cycle 4",
+		"moved 7 from 12 to 16 through 1", "executed 12:0", "executed 16:0", "emitted 7 to 0",
+		"blocked 8", "unblocked 12", "unblocked 16", "cycle 5",
+	}, obs.events)
+}
+
+func TestObserverClamp(t *testing.T) {
+	eng := newCounterEngine(t, []string{"ADD 999", "ADD 999", "MOV ACC ANY", "NOP"})
+	obs := &eventLog{}
+	eng.AddObserver(obs)
+	eng.Run(engine.RunOptions{MaxCycles: 3})
+	require.Equal(t, []string{
+		"executed 4:0", "blocked 19", "cycle 1",
+		"executed 4:1", "clamped 1998 to 999 in 4", "cycle 2",
+		"blocked 4", "cycle 3",
+	}, obs.events)
+}
+
+func TestObserverTransferThroughAny(t *testing.T) {
+	eng := newTwoNodeEngine(t, []string{"MOV 5 ANY"}, []string{"MOV ANY ACC"})
+	obs := &eventLog{}
+	eng.AddObserver(obs)
+	eng.Run(engine.RunOptions{MaxCycles: 2})
+	require.Contains(t, obs.events, "moved 5 from 4 to 5 through 3")
+}
+
+func TestRemoveObserver(t *testing.T) {
+	eng := newCounterEngine(t, []string{"ADD 1"})
+	first, second := &eventLog{}, &eventLog{}
+	eng.AddObserver(first)
+	eng.AddObserver(second)
+
+	eng.Run(engine.RunOptions{MaxCycles: 1})
+	eng.RemoveObserver(first)
+	eng.Run(engine.RunOptions{MaxCycles: 2})
+	require.Equal(t, []string{"executed 4:0", "blocked 19", "cycle 1"}, first.events)
+	require.Equal(t, []string{"executed 4:0", "blocked 19", "cycle 1", "executed 4:0", "cycle 2"}, second.events)
+}
+
+func TestObserverNopEmbedding(t *testing.T) {
+	eng := newCounterEngine(t, []string{"ADD 1"})
+	obs := &cycleCounter{}
+	eng.AddObserver(obs)
+	eng.Run(engine.RunOptions{MaxCycles: 4})
+	require.Equal(t, 4, obs.cycles)
+}
+
+func TestTickWithoutObserversDoesNotAllocate(t *testing.T) {
+	eng := newPipeEngine(t, []int16{1, 2, 3}, []int16{1, 2, 3})
+	allocs := testing.AllocsPerRun(100, func() {
+		_, _ = eng.Tick()
+	})
+	require.Zero(t, allocs)
+}
+
+// RemoveObserver -> covered in previous tests
+// observed -> covered in previous tests
+// observeStart -> covered in previous tests
+// observeCommit -> covered in previous tests
+// observeEnd -> covered in previous tests
+// portTo -> covered in previous tests
+
+/* UTILS */
+
+// newTwoNodeEngine creates an engine with code in the first two nodes of the grid,
+// whose run only ends at the cycle limit (see `pendingOutput`).
+func newTwoNodeEngine(tb testing.TB, first, second []string) *engine.Engine {
+	tb.Helper()
+
+	code := &model.Code{Title: "TWO", Nodes: make([][]string, model.NodesNumber)}
+	code.Nodes[0] = first
+	code.Nodes[1] = second
+	eng, err := engine.NewEngine(pendingOutput(), nil, code)
+	require.NoError(tb, err)
+	return eng
+}
+
+// cycleCounter is an observer counting cycles, ignoring the other events.
+type cycleCounter struct {
+	engine.NopObserver
+	cycles int
+}
+
+func (c *cycleCounter) CycleFinished(int) {
+	c.cycles++
+}
+
+// eventLog is an observer recording every event as text.
+type eventLog struct {
+	events []string
+}
+
+func (l *eventLog) InstructionExecuted(n *engine.Node, ins *engine.Instruction) {
+	l.events = append(l.events, fmt.Sprintf("executed %d:%d", n.Index, ins.Line))
+}
+
+func (l *eventLog) NodeBlocked(n *engine.Node) {
+	l.events = append(l.events, fmt.Sprintf("blocked %d", n.Index))
+}
+
+func (l *eventLog) NodeUnblocked(n *engine.Node) {
+	l.events = append(l.events, fmt.Sprintf("unblocked %d", n.Index))
+}
+
+func (l *eventLog) ValueTransferred(from, to *engine.Node, port engine.Port, value int16) {
+	l.events = append(l.events, fmt.Sprintf("moved %d from %d to %d through %d", value, from.Index, to.Index, port))
+}
+
+func (l *eventLog) ACCClamped(n *engine.Node, value int, clamped int16) {
+	l.events = append(l.events, fmt.Sprintf("clamped %d to %d in %d", value, clamped, n.Index))
+}
+
+func (l *eventLog) ValueEmitted(out *engine.Output, value int16) {
+	l.events = append(l.events, fmt.Sprintf("emitted %d to %d", value, out.Index))
+}
+
+func (l *eventLog) CycleFinished(cycle int) {
+	l.events = append(l.events, fmt.Sprintf("cycle %d", cycle))
+}
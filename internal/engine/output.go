@@ -1,12 +1,18 @@
 package engine
 
-import "github.com/lekomish/tis-100/internal/model"
+import (
+	"slices"
+
+	"github.com/lekomish/tis-100/internal/model"
+)
 
 // Output represents an output stream from a TIS-100 node.
 // It stores all values sent to a specific output port during execution.
 type Output struct {
 	Index  uint8   // the index or ID of the output stream (e.g., 1)
 	Values []int16 // the values written to this stream during simulation
+	Cycles []int   // the cycle each value was emitted at (0 if unknown)
+	Image  *Image  // display drawn with the values of an image stream (nil for number streams)
 }
 
 // NewOutput initializes and returns a new Output instance
@@ -15,12 +21,30 @@ func NewOutput(index uint8) *Output {
 	return &Output{
 		Index:  index,
 		Values: make([]int16, 0),
+		Cycles: make([]int, 0),
 	}
 }
 
+// NewImageOutput initializes and returns a new Output of an image stream
+// with a given stream index, an empty value buffer and a black display.
+func NewImageOutput(index uint8) *Output {
+	o := NewOutput(index)
+	o.Image = NewImage()
+	return o
+}
+
 // AddValue appends a single value to the output stream.
 func (o *Output) AddValue(value int16) {
+	o.AddValueAt(value, 0)
+}
+
+// AddValueAt appends a single value emitted at the given cycle to the output stream.
+func (o *Output) AddValueAt(value int16, cycle int) {
 	o.Values = append(o.Values, value)
+	o.Cycles = append(o.Cycles, cycle)
+	if o.Image != nil {
+		o.Image.Draw(value, cycle)
+	}
 }
 
 // Len returns the number of values currently stored in the output stream.
@@ -41,10 +65,23 @@ func (o *Output) At(index int) (int16, bool) {
 // but retains the stream index.
 func (o *Output) Clear() {
 	o.Values = o.Values[:0]
+	o.Cycles = o.Cycles[:0]
+	if o.Image != nil {
+		o.Image.Clear()
+	}
+}
+
+// restore replaces the content of the output stream, drawing the display again.
+func (o *Output) restore(values []int16, cycles []int) {
+	o.Clear()
+	for i, value := range values {
+		o.AddValueAt(value, cycles[i])
+	}
 }
 
 // EqualToStream checks whether the Output matches the given Stream
-// in position and values.
+// in position and values. An image output matches an image stream when
+// its display has the pixels of the stream.
 func (o *Output) EqualToStream(stream *model.Stream) bool {
 	if stream == nil {
 		return false
@@ -52,6 +89,12 @@ func (o *Output) EqualToStream(stream *model.Stream) bool {
 	if o.Index != stream.Position {
 		return false
 	}
+	if (o.Image != nil) != (stream.Type == model.IMAGE) {
+		return false
+	}
+	if o.Image != nil {
+		return slices.Equal(o.Image.Pixels, stream.Values)
+	}
 	if o.Len() != stream.Len() {
 		return false
 	}
@@ -29,6 +29,19 @@ func TestAddValueAndLen(t *testing.T) {
 	require.Equal(t, int16(-3), out.Values[1])
 }
 
+// --- AddValueAt ---
+func TestAddValueAt(t *testing.T) {
+	out := engine.NewOutput(0)
+	out.AddValueAt(5, 7)
+	out.AddValue(6)
+
+	require.Equal(t, []int16{5, 6}, out.Values)
+	require.Equal(t, []int{7, 0}, out.Cycles)
+
+	out.Clear()
+	require.Empty(t, out.Cycles)
+}
+
 // --- At ---
 func TestAt(t *testing.T) {
 	out := engine.NewOutput(0)
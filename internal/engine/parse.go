@@ -9,6 +9,7 @@ import (
 
 // opCodeMap maps strings mnemonics to their corresponding OpCode values.
 var opCodeMap = map[string]OpCode{
+	"MOV": OpMov,
 	"SUB": OpSub,
 	"ADD": OpAdd,
 	"JEZ": OpJez,
@@ -45,131 +46,182 @@ func (n *Node) appendInstruction(op OpCode) *Instruction {
 }
 
 // compileCode compiles raw lines of TIS-100 code form InputCode into executable Instructions for the node.
-// It also extracts and stores labels in the InputCode for jump resolution.
-func (n *Node) compileCode(ic *InputCode) error {
+// Lines follow the grammar of the original game (see `sourceLine`).
+// It also extracts and stores labels in the InputCode for jump resolution. A label points to
+// the instruction that follows it, so label-only and comment-only lines do not produce instructions.
+// Every instruction keeps the number of the source line it was compiled from.
+// A line prefixed with `!` sets a breakpoint on its instruction (or on the next one for lines without one).
+// Immediates are checked against the range of the mode.
+// Compilation goes on after a faulty line, so every problem of the node is returned.
+func (n *Node) compileCode(ic *InputCode, mode Mode) []*CompileError {
+	var errs []*CompileError
+	instructions := make([][]token, 0, len(ic.Lines))
+	lines := make([]string, 0, len(ic.Lines))
+	numbers := make([]int, 0, len(ic.Numbers))
+	columns := make([]int, 0, len(ic.Columns))
+	breakpoints := make([]bool, 0, len(ic.Lines))
+	breakpoint := false
 	for i, line := range ic.Lines {
-		if ind := strings.Index(line, ":"); ind != -1 {
-			label := strings.TrimSpace(line[:ind])
-			if label == "" {
-				return fmt.Errorf("empty label in line %d", i)
-			}
-			ic.AddLabel(label, uint8(i))
-
-			args := strings.TrimSpace(line[ind+1:])
-			if len(args) == 0 {
-				args = "NOP"
-			}
-			ic.Lines[i] = args
+		column := ic.columnAt(i)
+		sl, err := parseLine(line, column)
+		if err != nil {
+			err.Line = ic.numberAt(i)
+			errs = append(errs, err)
+			continue
+		}
+		if sl.label != nil {
+			ic.AddLabel(sl.label.text, uint8(len(lines)))
+		}
+		breakpoint = breakpoint || sl.breakpoint
+		if len(sl.instruction) == 0 {
+			continue
 		}
-	}
 
-	for _, line := range ic.Lines {
-		if err := n.parseInstruction(ic, line); err != nil {
-			return err
+		first, last := sl.instruction[0], sl.instruction[len(sl.instruction)-1]
+		instructions = append(instructions, sl.instruction)
+		lines = append(lines, line[first.column-column:last.column-column+len(last.text)])
+		numbers = append(numbers, ic.numberAt(i))
+		columns = append(columns, first.column)
+		breakpoints = append(breakpoints, breakpoint)
+		breakpoint = false
+	}
+	ic.Lines = lines
+	ic.Numbers = numbers
+	ic.Columns = columns
+
+	for i, tokens := range instructions {
+		if i == maxInstructions {
+			err := tokenError(CodeTooManyLines, tokens[0], "node has %d instructions, at most %d are allowed", len(instructions), maxInstructions)
+			err.Line = ic.Numbers[i]
+			errs = append(errs, err)
+			break
 		}
+		ins, err := parseInstruction(ic, tokens, mode)
+		if err != nil {
+			err.Line = ic.Numbers[i]
+			errs = append(errs, err)
+			continue
+		}
+		ins.Line = ic.Numbers[i]
+		ins.Breakpoint = breakpoints[i]
+		n.Instructions = append(n.Instructions, ins)
 	}
 
-	return nil
+	return errs
 }
 
-// parseInstruction determines the type of instruction and delegates to the appropriate parser.
-func (n *Node) parseInstruction(ic *InputCode, line string) error {
-	if len(line) < 3 {
-		return errors.New("invalid line length")
-	}
-
-	mnemonic := strings.ToUpper(line[:3])
-	if mnemonic == "MOV" {
-		return n.parseMovInstruction(line)
-	}
-
+// parseInstruction parses the tokens of an instruction, its mnemonic followed by its operands.
+// The returned error has no node and line, which are known to the caller only.
+func parseInstruction(ic *InputCode, tokens []token, mode Mode) (*Instruction, *CompileError) {
+	mnemonic := strings.ToUpper(tokens[0].text)
 	op, ok := opCodeMap[mnemonic]
 	if !ok {
-		return fmt.Errorf("invalid instruction: %s", mnemonic)
+		return nil, tokenError(CodeInvalidInstruction, tokens[0], "invalid instruction %s", mnemonic)
 	}
 
+	ins := &Instruction{Op: op}
 	switch op {
-	case OpSub, OpAdd:
-		return n.parseUnaryInstruction(line, op)
-	case OpJez, OpJmp, OpJnz, OpJgz, OpJlz, OpJro:
-		return n.parseJumpInstruction(ic, line, op)
-	case OpSav, OpSwp, OpNop, OpNeg, OpOut:
-		n.appendInstruction(op)
-		return nil
+	case OpMov:
+		if err := checkOperands(tokens, 2); err != nil {
+			return nil, err
+		}
+		if err := parseOperation(tokens[1], mode, &ins.SrcType, &ins.Src); err != nil {
+			return nil, err
+		}
+		if err := parseDestination(tokens[2], &ins.DestType, &ins.Dest); err != nil {
+			return nil, err
+		}
+	case OpSub, OpAdd, OpJro:
+		if err := checkOperands(tokens, 1); err != nil {
+			return nil, err
+		}
+		if err := parseOperation(tokens[1], mode, &ins.SrcType, &ins.Src); err != nil {
+			return nil, err
+		}
+	case OpJez, OpJmp, OpJnz, OpJgz, OpJlz:
+		if err := checkOperands(tokens, 1); err != nil {
+			return nil, err
+		}
+		if err := parseJumpTarget(ic, tokens[1], ins); err != nil {
+			return nil, err
+		}
 	default:
-		return fmt.Errorf("invalid instruction: %q", op)
+		if err := checkOperands(tokens, 0); err != nil {
+			return nil, err
+		}
 	}
+	return ins, nil
 }
 
-// parseMovInstruction parses a MOV instruction,
-// which expects two operands (source and destination).
-func (n *Node) parseMovInstruction(line string) error {
-	if len(line) <= 3 {
-		return errors.New("wrong mov instruction format")
-	}
-
-	args := strings.TrimSpace(line[4:])
-	tokens := strings.FieldsFunc(args, func(r rune) bool {
-		return r == ' ' || r == ','
-	})
-	if len(tokens) != 2 {
-		return fmt.Errorf("MOV expects 2 arguments: %q", line)
+// checkOperands checks that the instruction, the first token, is followed by the expected number of operands.
+// Missing operands are reported on the instruction and extra ones on the operands in excess.
+func checkOperands(tokens []token, expected int) *CompileError {
+	operands := len(tokens) - 1
+	if operands == expected {
+		return nil
 	}
 
-	ins := n.appendInstruction(OpMov)
-	if err := parseOperation(tokens[0], &ins.SrcType, &ins.Src); err != nil {
-		return err
+	mnemonic := strings.ToUpper(tokens[0].text)
+	message := fmt.Sprintf("%s expects %d operands", mnemonic, expected)
+	switch expected {
+	case 0:
+		message = mnemonic + " expects no operands"
+	case 1:
+		message = mnemonic + " expects 1 operand"
 	}
-	if err := parseOperation(tokens[1], &ins.DestType, &ins.Dest); err != nil {
-		return err
-	}
-	return nil
-}
 
-// parseUnaryInstruction parses single-operand instructions like ADD, SUB, etc.
-func (n *Node) parseUnaryInstruction(line string, op OpCode) error {
-	if len(line) < 4 {
-		return fmt.Errorf("wrong instruction: %q", line)
+	if operands < expected {
+		return tokenError(CodeOperandCount, tokens[0], "%s", message)
 	}
-	args := strings.TrimSpace(line[4:])
-	ins := n.appendInstruction(op)
-	return parseOperation(args, &ins.SrcType, &ins.Src)
+	first, last := tokens[expected+1], tokens[len(tokens)-1]
+	return spanError(CodeOperandCount, first.column, last.column+len(last.text), "%s", message)
 }
 
-// parseJumpInstruction parses jump instructions using label references.
-// It resolves labels from the InputCode's label map and sets the operand accordingly.
-func (n *Node) parseJumpInstruction(ic *InputCode, line string, op OpCode) error {
-	if len(line) < 4 {
-		return fmt.Errorf("wrong instruction: %q", line)
-	}
-	label := strings.TrimSpace(line[4:])
-	pos, ok := ic.Labels[label]
+// parseJumpTarget resolves the label a jump refers to from the InputCode's label map
+// and sets the operand of the instruction accordingly.
+func parseJumpTarget(ic *InputCode, label token, ins *Instruction) *CompileError {
+	pos, ok := ic.Labels[label.text]
 	if !ok {
-		return fmt.Errorf("label not found: %s", label)
+		return tokenError(CodeUnknownLabel, label, "unknown label %s", label.text)
 	}
-	ins := n.appendInstruction(op)
 	ins.SrcType = Immediate
 	ins.Src.Value = int16(pos)
 	return nil
 }
 
-// parseOperation parses an operand string and fills in the OperandType and Operand fields.
-// Supports both port names and immediate numeric values.
-func parseOperation(strOp string, opType *OperandType, op *Operand) error {
-	if strOp == "" {
-		return errors.New("no source was found")
+// parseDestination parses the destination of a MOV, which must be a port or a register:
+// unlike a source, it cannot be a number.
+func parseDestination(t token, opType *OperandType, op *Operand) *CompileError {
+	port, ok := portNameMap[strings.ToUpper(t.text)]
+	if !ok {
+		if _, err := strconv.Atoi(t.text); err == nil || errors.Is(err, strconv.ErrRange) {
+			return tokenError(CodeInvalidOperand, t, "invalid destination %s, expected a port or a register", t.text)
+		}
+		return tokenError(CodeInvalidOperand, t, "invalid operand %s", t.text)
 	}
+	*opType = PortRef
+	op.Port = port
+	return nil
+}
 
+// parseOperation parses an operand token and fills in the OperandType and Operand fields.
+// Supports both port names and immediate numeric values within the range of the mode.
+func parseOperation(t token, mode Mode, opType *OperandType, op *Operand) *CompileError {
 	*opType = PortRef
-	if port, ok := portNameMap[strings.ToUpper(strOp)]; ok {
+	if port, ok := portNameMap[strings.ToUpper(t.text)]; ok {
 		op.Port = port
-	} else {
-		num, err := strconv.Atoi(strOp)
-		if err != nil {
-			return fmt.Errorf("invalid operand: %q", strOp)
-		}
-		*opType = Immediate
-		op.Value = int16(num)
+		return nil
+	}
+
+	num, err := strconv.Atoi(t.text)
+	low, high := mode.valueRange()
+	switch {
+	case errors.Is(err, strconv.ErrRange) || err == nil && (num < low || num > high):
+		return tokenError(CodeValueOutOfRange, t, "value %s is out of range %d..%d", t.text, low, high)
+	case err != nil:
+		return tokenError(CodeInvalidOperand, t, "invalid operand %s", t.text)
 	}
+	*opType = Immediate
+	op.Value = int16(num)
 	return nil
 }
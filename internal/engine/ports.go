@@ -1,5 +1,7 @@
 package engine
 
+import "github.com/lekomish/tis-100/internal/model"
+
 // portProbeOrder defines the order in which ports are probed
 // when using the PortAny directive. This affects how input and output
 // behaviour resolves in ambiguous or multi-port connections.
@@ -8,14 +10,14 @@ var portProbeOrder = []Port{PortLeft, PortRight, PortUp, PortDown}
 // getInputPort returns the appropriate input Node for the given port.
 // Special handling:
 // - `PortAny`: iterates over ports in `portProbeOrder` to find the first connected node sending to this node.
-// - `PortLast`: returns the last node that successfully sent data to this one.
+// - `PortLast`: returns the last node communicated with through ANY.
 // - `Default`: return the node connected to the specified direction.
 func (n *Node) getInputPort(port Port) *Node {
 	switch port {
 	case PortAny:
 		for _, p := range portProbeOrder {
 			node := n.Ports[p]
-			if node != nil && node.OutboundTarget == n {
+			if node != nil && node.writesTo(n) {
 				return node
 			}
 		}
@@ -29,29 +31,26 @@ func (n *Node) getInputPort(port Port) *Node {
 
 // getOutputPort returns the destination Node for the given output port.
 // Special handling:
-// - `PortAny`: searches for a node whose current instruction is MOV and whose source refers to this node.
-// - `PortLast`: returns the last output node used.
+// - `PortAny`: returns nil, the value is offered to every neighbour instead.
+// - `PortLast`: returns the last node communicated with through ANY.
 // - `Default`: returns the node connected to the specified direction.
 func (n *Node) getOutputPort(port Port) *Node {
-	if port == PortAny {
-		for _, p := range portProbeOrder {
-			node := n.Ports[p]
-			if node == nil {
-				continue
-			}
-
-			ins := node.instruction()
-			if ins == nil || ins.Op != OpMov || ins.SrcType != PortRef {
-				continue
-			}
-			if ins.Src.Port == PortAny || node.Ports[ins.Src.Port] == n {
-				return node
-			}
-		}
+	switch port {
+	case PortAny:
 		return nil
-	} else if port == PortLast {
+	case PortLast:
 		return n.Last
-	} else {
+	default:
 		return n.Ports[port]
 	}
 }
+
+// writesTo reports whether the node holds a value that the given neighbour may read,
+// either because it is written to that neighbour or because it is offered to ANY.
+// Stack memory nodes do not exchange values with each other.
+func (n *Node) writesTo(reader *Node) bool {
+	if !n.IsWriting || (n.Type == model.MEMORY && reader.Type == model.MEMORY) {
+		return false
+	}
+	return n.OutboundTarget == reader || n.OutboundPort == PortAny
+}
@@ -0,0 +1,186 @@
+package engine
+
+// DefaultMaxCycles is the cycle limit used by `Run` when none is configured.
+const DefaultMaxCycles = 100000
+
+// Termination describes why `Engine.Run` stopped.
+type Termination uint8
+
+// Supported Termination values.
+const (
+	Completed    Termination = iota // every output stream received all expected values
+	Mismatch                        // an output stream received a wrong or an extra value
+	Deadlock                        // every active node is blocked and no progress is possible (see `Stall`)
+	CycleLimit                      // the configured maximum number of cycles was reached
+	RuntimeError                    // a node failed to execute its instruction
+	Paused                          // a breakpoint, condition or watchpoint triggered (see `Result.Stop`)
+	Livelock                        // the machine came back to an earlier state with incomplete outputs (see `Repetition`)
+)
+
+// terminationNames maps Termination values to their human readable names.
+var terminationNames = map[Termination]string{
+	Completed:    "completed",
+	Mismatch:     "output mismatch",
+	Deadlock:     "deadlock",
+	CycleLimit:   "cycle limit reached",
+	RuntimeError: "runtime error",
+	Paused:       "paused",
+	Livelock:     "livelock",
+}
+
+// String returns the human readable name of the termination reason.
+func (t Termination) String() string {
+	if name, ok := terminationNames[t]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// RunOptions configures a call to `Engine.Run`.
+type RunOptions struct {
+	MaxCycles        int  // total number of cycles after which the run stops (0 means `DefaultMaxCycles`)
+	StopOnFirstWrong bool // stop as soon as an output receives a wrong value
+	Debug            bool // pause at breakpoints, conditions and watchpoints
+	DetectLivelock   bool // stop as soon as the machine state repeats, keeping every recorded state (see `Run`)
+	LivelockInterval int  // number of cycles between two recorded states (0 means every cycle)
+}
+
+// Result describes the outcome of `Engine.Run`.
+type Result struct {
+	Reason Termination // why the run stopped
+	Cycles int         // number of cycles executed when the run stopped
+	Err    error       // execution error for RuntimeError, or the `*Stall` of a Deadlock or the `*Repetition` of a Livelock
+	Stop   *Stop       // what paused the run, set only when Reason is Paused
+}
+
+// Passed reports whether the run produced all expected outputs.
+func (r *Result) Passed() bool {
+	return r.Reason == Completed
+}
+
+// Run ticks the engine until all expected outputs are produced, an output mismatches,
+// the machine stalls, the cycle limit is reached or a node fails.
+// Outputs are compared against the expected values once they are all complete,
+// while a value beyond the expected length ends the run immediately.
+// With `StopOnFirstWrong` a wrong value ends the run immediately as well.
+// A machine without outputs never completes.
+//
+// With `DetectLivelock` the state of the machine is recorded after every cycle (or every
+// `LivelockInterval` cycles) and the run ends as soon as a state repeats, since the machine would
+// go through the same cycles forever without completing its outputs.
+// Every recorded state is kept until the run ends, a few hundred bytes each (more with filled
+// stack memory nodes), so the memory used grows with `MaxCycles / LivelockInterval`:
+// long runs should record states less often.
+//
+// With `Debug` the run pauses before a node starts an instruction with a breakpoint and after
+// a cycle in which a condition became true or a watchpoint triggered. The engine can be
+// inspected and the run continued by calling `Run` again.
+func (e *Engine) Run(opts RunOptions) *Result {
+	maxCycles := opts.MaxCycles
+	if maxCycles <= 0 {
+		maxCycles = DefaultMaxCycles
+	}
+	debug := opts.Debug && e.debugging()
+
+	for e.Cycle < maxCycles {
+		if debug {
+			if stop := e.checkBreakpoints(); stop != nil && !e.debug.resume {
+				e.debug.resume = true
+				return &Result{Reason: Paused, Cycles: e.Cycle, Stop: stop}
+			}
+			e.debug.resume = false
+		}
+
+		allBlocked, err := e.Tick()
+		if err != nil {
+			return &Result{Reason: RuntimeError, Cycles: e.Cycle, Err: err}
+		}
+
+		if e.outputsOverflow() || (opts.StopOnFirstWrong && e.outputsDiverge()) {
+			return &Result{Reason: Mismatch, Cycles: e.Cycle}
+		}
+		if e.outputsComplete() {
+			if e.outputsMatch() {
+				return &Result{Reason: Completed, Cycles: e.Cycle}
+			}
+			return &Result{Reason: Mismatch, Cycles: e.Cycle}
+		}
+		if allBlocked {
+			return &Result{Reason: Deadlock, Cycles: e.Cycle, Err: e.Stall()}
+		}
+		if opts.DetectLivelock {
+			if r := e.checkLivelock(opts.LivelockInterval); r != nil {
+				return &Result{Reason: Livelock, Cycles: e.Cycle, Err: r}
+			}
+		}
+		if debug {
+			if stop := e.checkWatches(); stop != nil {
+				return &Result{Reason: Paused, Cycles: e.Cycle, Stop: stop}
+			}
+		}
+	}
+
+	return &Result{Reason: CycleLimit, Cycles: e.Cycle}
+}
+
+// outputsComplete reports whether every output is complete (see `outputComplete`).
+// A machine without outputs is never complete: it runs until it stalls or reaches its cycle limit.
+func (e *Engine) outputsComplete() bool {
+	if len(e.Outputs) == 0 {
+		return false
+	}
+	for i := range e.Outputs {
+		if !e.outputComplete(i) {
+			return false
+		}
+	}
+	return true
+}
+
+// outputComplete reports whether the output at the given position of `Outputs` holds as many
+// values as its expected stream. Pixels can be drawn over again, so an image output is only
+// complete once its display shows the expected image.
+func (e *Engine) outputComplete(i int) bool {
+	out := e.Outputs[i]
+	if out.Image != nil {
+		return out.EqualToStream(e.expected[i])
+	}
+	return out.Len() >= e.expected[i].Len()
+}
+
+// outputsOverflow reports whether any number output holds more values than its expected stream.
+func (e *Engine) outputsOverflow() bool {
+	for i, out := range e.Outputs {
+		if out.Image == nil && out.Len() > e.expected[i].Len() {
+			return true
+		}
+	}
+	return false
+}
+
+// outputsDiverge reports whether any number output holds a value different from the expected one
+// at the same position. Checked outputs never diverge.
+func (e *Engine) outputsDiverge() bool {
+	for i, out := range e.Outputs {
+		if out.Image != nil || e.expected[i].Checked {
+			continue
+		}
+		for j, value := range out.Values {
+			if j < e.expected[i].Len() && value != e.expected[i].Values[j] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// outputsMatch reports whether every output is equal to its expected stream.
+// Checked outputs are left to the check of the puzzle.
+func (e *Engine) outputsMatch() bool {
+	for i, out := range e.Outputs {
+		if !e.expected[i].Checked && !out.EqualToStream(e.expected[i]) {
+			return false
+		}
+	}
+	return true
+}
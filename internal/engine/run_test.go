@@ -0,0 +1,160 @@
+package engine_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lekomish/tis-100/internal/engine"
+	"github.com/lekomish/tis-100/internal/model"
+)
+
+/* TESTS */
+
+// --- Run ---
+func TestRunCompleted(t *testing.T) {
+	eng := newPipeEngine(t, []int16{1, 2, 3}, []int16{1, 2, 3})
+
+	res := eng.Run(engine.RunOptions{})
+	require.Equal(t, engine.Completed, res.Reason)
+	require.True(t, res.Passed())
+	require.NoError(t, res.Err)
+	require.Equal(t, eng.Cycle, res.Cycles)
+	require.Positive(t, res.Cycles)
+}
+
+func TestRunMismatch(t *testing.T) {
+	eng := newPipeEngine(t, []int16{1, 2, 3}, []int16{1, 5, 3})
+
+	res := eng.Run(engine.RunOptions{})
+	require.Equal(t, engine.Mismatch, res.Reason)
+	require.False(t, res.Passed())
+	require.Equal(t, []int16{1, 2, 3}, eng.Outputs[0].Values, "the run should continue to the end")
+}
+
+func TestRunStopOnFirstWrong(t *testing.T) {
+	eng := newPipeEngine(t, []int16{1, 2, 3}, []int16{1, 5, 3})
+
+	res := eng.Run(engine.RunOptions{StopOnFirstWrong: true})
+	require.Equal(t, engine.Mismatch, res.Reason)
+	require.Equal(t, []int16{1, 2}, eng.Outputs[0].Values, "the run should stop at the wrong value")
+	require.Equal(t, eng.Outputs[0].Cycles[1], res.Cycles)
+}
+
+func TestRunMismatchOnExtraValue(t *testing.T) {
+	code := &model.Code{Title: "TEST", Nodes: make([][]string, model.NodesNumber)}
+	code.Nodes[0] = []string{"MOV UP DOWN"}
+	code.Nodes[4] = []string{"MOV UP DOWN"}
+	code.Nodes[8] = []string{"MOV UP DOWN"}
+	streams := []*model.Stream{
+		{Type: model.INPUT, Position: 0, Values: []int16{1, 2}},
+		{Type: model.OUTPUT, Position: 0, Values: []int16{1}},
+		{Type: model.OUTPUT, Position: 1, Values: []int16{1}},
+	}
+
+	eng, err := engine.NewEngine(streams, nil, code)
+	require.NoError(t, err)
+
+	res := eng.Run(engine.RunOptions{})
+	require.Equal(t, engine.Mismatch, res.Reason)
+	require.Equal(t, 2, eng.Outputs[0].Len())
+}
+
+func TestRunChecked(t *testing.T) {
+	code := &model.Code{Title: "PIPE", Nodes: make([][]string, model.NodesNumber)}
+	code.Nodes[0] = []string{"MOV UP DOWN"}
+	code.Nodes[4] = []string{"MOV UP DOWN"}
+	code.Nodes[8] = []string{"MOV UP DOWN"}
+	streams := []*model.Stream{
+		{Type: model.INPUT, Position: 0, Values: []int16{1, 2, 3}},
+		{Type: model.OUTPUT, Position: 0, Values: []int16{3, 2, 1}, Checked: true},
+	}
+	eng, err := engine.NewEngine(streams, nil, code)
+	require.NoError(t, err)
+
+	res := eng.Run(engine.RunOptions{StopOnFirstWrong: true})
+	require.Equal(t, engine.Completed, res.Reason, "the values of a checked stream should be left to the check")
+	require.True(t, eng.CompareOutputs()[0].Equal())
+}
+
+func TestRunDeadlock(t *testing.T) {
+	eng := newPipeEngine(t, []int16{1, 2}, []int16{1, 2, 3})
+
+	res := eng.Run(engine.RunOptions{})
+	require.Equal(t, engine.Deadlock, res.Reason)
+	require.Equal(t, "deadlock", res.Reason.String())
+}
+
+func TestRunCycleLimit(t *testing.T) {
+	eng := newPipeEngine(t, []int16{1, 2, 3}, []int16{1, 2, 3})
+
+	res := eng.Run(engine.RunOptions{MaxCycles: 2})
+	require.Equal(t, engine.CycleLimit, res.Reason)
+	require.Equal(t, 2, res.Cycles)
+
+	// the limit is absolute, so the run can be resumed with a higher one
+	res = eng.Run(engine.RunOptions{})
+	require.Equal(t, engine.Completed, res.Reason)
+}
+
+func TestRunWithoutOutputs(t *testing.T) {
+	code := &model.Code{Title: "TEST", Nodes: make([][]string, model.NodesNumber)}
+	code.Nodes[0] = []string{"ADD 1"}
+	eng, err := engine.NewEngine(nil, nil, code)
+	require.NoError(t, err)
+
+	res := eng.Run(engine.RunOptions{MaxCycles: 10})
+	require.Equal(t, engine.CycleLimit, res.Reason, "a machine without outputs is never complete")
+	require.Equal(t, 10, res.Cycles)
+
+	code.Nodes[0] = []string{"MOV UP ACC"}
+	eng, err = engine.NewEngine(nil, nil, code)
+	require.NoError(t, err)
+
+	res = eng.Run(engine.RunOptions{MaxCycles: 10})
+	require.Equal(t, engine.Deadlock, res.Reason)
+}
+
+func TestRunRuntimeError(t *testing.T) {
+	eng := newPipeEngine(t, []int16{1}, []int16{1})
+	eng.Nodes[0].Instructions[0].Op = engine.OpCode(255)
+
+	res := eng.Run(engine.RunOptions{})
+	require.Equal(t, engine.RuntimeError, res.Reason)
+	require.ErrorContains(t, res.Err, "unknown operation")
+}
+
+// --- String ---
+func TestTerminationString(t *testing.T) {
+	require.Equal(t, "completed", engine.Completed.String())
+	require.Equal(t, "output mismatch", engine.Mismatch.String())
+	require.Equal(t, "cycle limit reached", engine.CycleLimit.String())
+	require.Equal(t, "runtime error", engine.RuntimeError.String())
+	require.Equal(t, "unknown", engine.Termination(255).String())
+}
+
+// outputsComplete -> covered in previous tests
+// outputsOverflow -> covered in previous tests
+// outputsDiverge -> covered in previous tests
+// outputsMatch -> covered in previous tests
+
+/* UTILS */
+
+// newPipeEngine creates an engine that passes the input values straight down
+// the first column of the grid into an output expecting the given values.
+func newPipeEngine(tb testing.TB, input, expected []int16) *engine.Engine {
+	tb.Helper()
+
+	code := &model.Code{Title: "PIPE", Nodes: make([][]string, model.NodesNumber)}
+	code.Nodes[0] = []string{"MOV UP DOWN"}
+	code.Nodes[4] = []string{"MOV UP DOWN"}
+	code.Nodes[8] = []string{"MOV UP DOWN"}
+	streams := []*model.Stream{
+		{Type: model.INPUT, Position: 0, Values: input},
+		{Type: model.OUTPUT, Position: 0, Values: expected},
+	}
+
+	eng, err := engine.NewEngine(streams, nil, code)
+	require.NoError(tb, err)
+	return eng
+}
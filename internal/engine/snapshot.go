@@ -0,0 +1,222 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/lekomish/tis-100/internal/model"
+)
+
+// SnapshotVersion is the version of the snapshot format written by `Engine.Snapshot`.
+const SnapshotVersion = 1
+
+// noNode marks a missing node reference in snapshots.
+const noNode = -1
+
+// Snapshot is a serialisable copy of the whole machine state between two cycles.
+// Node references are stored as node indexes, so a snapshot can be restored
+// into any engine built from the same puzzle and code.
+type Snapshot struct {
+	Version int              `json:"version"`
+	Cycle   int              `json:"cycle"`
+	Nodes   []NodeSnapshot   `json:"nodes"`   // grid nodes followed by the input and output nodes
+	Outputs []OutputSnapshot `json:"outputs"` // in the order of `Engine.Outputs`
+}
+
+// NodeSnapshot is the state of a single node. Input nodes keep their read position
+// in the stream as their instruction pointer.
+type NodeSnapshot struct {
+	Index          uint8   `json:"index"`
+	Blocked        bool    `json:"blocked,omitempty"`
+	IP             uint8   `json:"ip"`
+	ACC            int16   `json:"acc"`
+	BAK            int16   `json:"bak"`
+	Writing        bool    `json:"writing,omitempty"`
+	OutboundPort   Port    `json:"outboundPort,omitempty"`
+	OutboundTarget int     `json:"outboundTarget"` // index of the target node, or -1
+	OutboundValue  int16   `json:"outboundValue,omitempty"`
+	Last           int     `json:"last"`            // index of the LAST node, or -1
+	Stack          []int16 `json:"stack,omitempty"` // values of a stack memory node, the top one last
+}
+
+// Equal reports whether both snapshots describe the same node state.
+func (ns NodeSnapshot) Equal(other NodeSnapshot) bool {
+	return ns.Index == other.Index && ns.Blocked == other.Blocked && ns.IP == other.IP &&
+		ns.ACC == other.ACC && ns.BAK == other.BAK && ns.Writing == other.Writing &&
+		ns.OutboundPort == other.OutboundPort && ns.OutboundTarget == other.OutboundTarget &&
+		ns.OutboundValue == other.OutboundValue && ns.Last == other.Last && slices.Equal(ns.Stack, other.Stack)
+}
+
+// OutputSnapshot is the content of an output stream.
+type OutputSnapshot struct {
+	Index  uint8   `json:"index"`
+	Values []int16 `json:"values"`
+	Cycles []int   `json:"cycles"`
+}
+
+// Snapshot captures the current state of the engine.
+func (e *Engine) Snapshot() *Snapshot {
+	s := &Snapshot{Version: SnapshotVersion, Cycle: e.Cycle}
+	for _, n := range e.allNodes() {
+		s.Nodes = append(s.Nodes, NodeSnapshot{
+			Index:          n.Index,
+			Blocked:        n.IsBlocked,
+			IP:             n.InstructionPointer,
+			ACC:            n.ACC,
+			BAK:            n.BAK,
+			Writing:        n.IsWriting,
+			OutboundPort:   n.OutboundPort,
+			OutboundTarget: nodeIndex(n.OutboundTarget),
+			OutboundValue:  n.OutboundValue,
+			Last:           nodeIndex(n.Last),
+			Stack:          append([]int16(nil), n.Stack...),
+		})
+	}
+	for _, out := range e.Outputs {
+		s.Outputs = append(s.Outputs, OutputSnapshot{
+			Index:  out.Index,
+			Values: append([]int16{}, out.Values...),
+			Cycles: append([]int{}, out.Cycles...),
+		})
+	}
+	return s
+}
+
+// Restore brings the engine back to the state captured in the snapshot.
+// The engine must have been built from the same puzzle and code as the captured one.
+func (e *Engine) Restore(s *Snapshot) error {
+	if s.Version != SnapshotVersion {
+		return fmt.Errorf("unsupported snapshot version %d", s.Version)
+	}
+	nodes := e.allNodes()
+	if len(s.Nodes) != len(nodes) || len(s.Outputs) != len(e.Outputs) {
+		return errors.New("snapshot does not match the engine")
+	}
+
+	byIndex := make(map[int]*Node, len(nodes))
+	for _, n := range nodes {
+		byIndex[int(n.Index)] = n
+	}
+	for i, ns := range s.Nodes {
+		if ns.Index != nodes[i].Index || int(ns.IP) > len(nodes[i].Instructions) {
+			return errors.New("snapshot does not match the engine")
+		}
+		if len(ns.Stack) > 0 && (nodes[i].Type != model.MEMORY || len(ns.Stack) > model.MemoryCapacity) {
+			return errors.New("snapshot does not match the engine")
+		}
+		if (ns.OutboundTarget != noNode && byIndex[ns.OutboundTarget] == nil) || (ns.Last != noNode && byIndex[ns.Last] == nil) {
+			return errors.New("snapshot refers to unknown nodes")
+		}
+	}
+	for i, out := range s.Outputs {
+		if out.Index != e.Outputs[i].Index || len(out.Values) != len(out.Cycles) {
+			return errors.New("snapshot does not match the engine")
+		}
+	}
+
+	for i, ns := range s.Nodes {
+		n := nodes[i]
+		n.IsBlocked = ns.Blocked
+		n.InstructionPointer = ns.IP
+		n.ACC = ns.ACC
+		n.BAK = ns.BAK
+		n.IsWriting = ns.Writing
+		n.OutboundPort = ns.OutboundPort
+		n.OutboundTarget = byIndex[ns.OutboundTarget]
+		n.OutboundValue = ns.OutboundValue
+		n.Last = byIndex[ns.Last]
+		n.Stack = append(n.Stack[:0], ns.Stack...)
+	}
+	for i, out := range s.Outputs {
+		e.Outputs[i].restore(out.Values, out.Cycles)
+	}
+	e.Cycle = s.Cycle
+	e.debug.sync(e)
+	e.livelock.reset()
+	return nil
+}
+
+// allNodes returns the grid nodes followed by the input and output nodes.
+func (e *Engine) allNodes() []*Node {
+	nodes := append([]*Node{}, e.Nodes...)
+	for list := e.NodeList; list != nil; list = list.Next {
+		nodes = append(nodes, list.Node)
+	}
+	return nodes
+}
+
+// nodeIndex returns the index of the node, or `noNode` for nil.
+func nodeIndex(n *Node) int {
+	if n == nil {
+		return noNode
+	}
+	return int(n.Index)
+}
+
+// History keeps periodic snapshots of a run, so it can be rewound to any earlier cycle.
+// Rewinding restores the closest snapshot and replays the cycles up to the requested one,
+// which gives the same state because execution is deterministic.
+type History struct {
+	Interval  int         // number of cycles between two snapshots
+	snapshots []*Snapshot // in increasing cycle order
+}
+
+// DefaultHistoryInterval is the number of cycles between two snapshots used by `NewHistory`.
+const DefaultHistoryInterval = 100
+
+// NewHistory creates a history taking a snapshot every `interval` cycles
+// (`DefaultHistoryInterval` if not positive).
+func NewHistory(interval int) *History {
+	if interval <= 0 {
+		interval = DefaultHistoryInterval
+	}
+	return &History{Interval: interval}
+}
+
+// Record takes a snapshot of the engine if its cycle is due one and none exists yet.
+// It should be called before the first cycle and after every following one.
+func (h *History) Record(e *Engine) {
+	if e.Cycle%h.Interval != 0 {
+		return
+	}
+	for i := len(h.snapshots) - 1; i >= 0 && h.snapshots[i].Cycle >= e.Cycle; i-- {
+		if h.snapshots[i].Cycle == e.Cycle {
+			return
+		}
+	}
+	h.snapshots = append(h.snapshots, e.Snapshot())
+}
+
+// Rewind brings the engine back to the given earlier cycle.
+// The observers of the engine are detached while the cycles are replayed,
+// as they have already received their events.
+func (h *History) Rewind(e *Engine, cycle int) error {
+	if cycle < 0 || cycle > e.Cycle {
+		return fmt.Errorf("cannot rewind to cycle %d", cycle)
+	}
+
+	var closest *Snapshot
+	for _, s := range h.snapshots {
+		if s.Cycle <= cycle {
+			closest = s
+		}
+	}
+	if closest == nil {
+		return fmt.Errorf("no snapshot before cycle %d", cycle)
+	}
+
+	if err := e.Restore(closest); err != nil {
+		return err
+	}
+	observers := e.observation.observers
+	e.observation.observers = nil
+	defer func() { e.observation.observers = observers }()
+	for e.Cycle < cycle {
+		if _, err := e.Tick(); err != nil {
+			return err
+		}
+	}
+	e.debug.sync(e)
+	return nil
+}
@@ -0,0 +1,138 @@
+package engine_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lekomish/tis-100/internal/engine"
+	"github.com/lekomish/tis-100/internal/model"
+)
+
+/* TESTS */
+
+// --- Snapshot --- Restore ---
+func TestSnapshotRestoreIntoNewEngine(t *testing.T) {
+	eng := newPipeEngine(t, []int16{1, 2, 3}, []int16{1, 2, 3})
+	eng.Run(engine.RunOptions{MaxCycles: 7})
+
+	data, err := json.Marshal(eng.Snapshot())
+	require.NoError(t, err)
+	var s engine.Snapshot
+	require.NoError(t, json.Unmarshal(data, &s))
+
+	restored := newPipeEngine(t, []int16{1, 2, 3}, []int16{1, 2, 3})
+	require.NoError(t, restored.Restore(&s))
+	require.Equal(t, 7, restored.Cycle)
+	require.Equal(t, eng.Outputs[0].Values, restored.Outputs[0].Values)
+	require.Equal(t, eng.Snapshot(), restored.Snapshot())
+
+	res := restored.Run(engine.RunOptions{})
+	expected := eng.Run(engine.RunOptions{})
+	require.Equal(t, expected, res, "the restored engine should continue exactly like the original")
+}
+
+func TestSnapshotKeepsNodeReferences(t *testing.T) {
+	code := &model.Code{Title: "ANY", Nodes: make([][]string, model.NodesNumber)}
+	code.Nodes[0] = []string{"MOV 1 ANY", "MOV 2 LAST"}
+	code.Nodes[1] = []string{"MOV ANY ACC", "MOV LAST ACC"}
+
+	eng, err := engine.NewEngine(pendingOutput(), nil, code)
+	require.NoError(t, err)
+	for range 3 {
+		_, err := eng.Tick()
+		require.NoError(t, err)
+	}
+	require.True(t, eng.Nodes[0].IsWriting)
+	s := eng.Snapshot()
+
+	for range 3 {
+		_, err := eng.Tick()
+		require.NoError(t, err)
+	}
+	require.NoError(t, eng.Restore(s))
+	require.Same(t, eng.Nodes[1], eng.Nodes[0].Last)
+	require.Same(t, eng.Nodes[1], eng.Nodes[0].OutboundTarget)
+	require.Same(t, eng.Nodes[0], eng.Nodes[1].Last)
+	require.Equal(t, s, eng.Snapshot())
+}
+
+func TestRestoreMismatchingSnapshot(t *testing.T) {
+	eng := newPipeEngine(t, []int16{1}, []int16{1})
+
+	s := eng.Snapshot()
+	s.Version = 99
+	require.ErrorContains(t, eng.Restore(s), "unsupported snapshot version 99")
+
+	s = eng.Snapshot()
+	s.Nodes = s.Nodes[1:]
+	require.ErrorContains(t, eng.Restore(s), "snapshot does not match the engine")
+
+	s = eng.Snapshot()
+	s.Nodes[0].Last = 42
+	require.ErrorContains(t, eng.Restore(s), "snapshot refers to unknown nodes")
+}
+
+// --- History ---
+func TestHistoryRewind(t *testing.T) {
+	eng := newPipeEngine(t, []int16{1, 2, 3, 4, 5}, []int16{1, 2, 3, 4, 5})
+	history := engine.NewHistory(4)
+
+	states := map[int]*engine.Snapshot{}
+	history.Record(eng)
+	for range 12 {
+		states[eng.Cycle] = eng.Snapshot()
+		_, err := eng.Tick()
+		require.NoError(t, err)
+		history.Record(eng)
+	}
+
+	for _, cycle := range []int{11, 6, 4, 0} {
+		require.NoError(t, history.Rewind(eng, cycle))
+		require.Equal(t, states[cycle], eng.Snapshot(), "cycle %d", cycle)
+	}
+
+	require.ErrorContains(t, history.Rewind(eng, 1), "cannot rewind to cycle 1")
+}
+
+func TestHistoryRewindMutesObservers(t *testing.T) {
+	eng := newPipeEngine(t, []int16{1, 2, 3, 4, 5}, []int16{1, 2, 3, 4, 5})
+	history := engine.NewHistory(4)
+	counter := &cycleCounter{}
+	log := &eventLog{}
+	eng.AddObserver(counter)
+	eng.AddObserver(log)
+
+	history.Record(eng)
+	for range 10 {
+		_, err := eng.Tick()
+		require.NoError(t, err)
+		history.Record(eng)
+	}
+	events := len(log.events)
+
+	require.NoError(t, history.Rewind(eng, 7))
+	require.Equal(t, 10, counter.cycles, "replayed cycles should not be observed again")
+	require.Len(t, log.events, events)
+
+	_, err := eng.Tick()
+	require.NoError(t, err)
+	require.Equal(t, 11, counter.cycles, "observers should be attached again after the rewind")
+	require.Greater(t, len(log.events), events)
+}
+
+func TestHistoryRewindWithoutSnapshot(t *testing.T) {
+	eng := newPipeEngine(t, []int16{1}, []int16{1})
+	history := engine.NewHistory(0)
+	require.Equal(t, engine.DefaultHistoryInterval, history.Interval)
+
+	_, err := eng.Tick()
+	require.NoError(t, err)
+	require.ErrorContains(t, history.Rewind(eng, 0), "no snapshot before cycle 0")
+}
+
+// Record -> covered in previous tests
+// allNodes -> covered in previous tests
+// nodeIndex -> covered in previous tests
+// sync -> covered in previous tests
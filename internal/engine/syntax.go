@@ -0,0 +1,128 @@
+package engine
+
+import (
+	"strings"
+	"unicode"
+)
+
+// tokenKind classifies the tokens of a source line.
+type tokenKind uint8
+
+// Supported tokenKind values.
+const (
+	tokenWord  tokenKind = iota // mnemonic, operand or label name
+	tokenComma                  // separator between operands
+	tokenColon                  // end of a label definition
+	tokenBang                   // breakpoint marker
+)
+
+// token is a word or a punctuation mark of a source line with its zero-based column in the line.
+type token struct {
+	kind   tokenKind
+	text   string
+	column int
+}
+
+// sourceLine is a source line split according to the grammar of the original game:
+//
+//	line = [ "!" ] [ label ":" ] [ "!" ] [ mnemonic [ operand { [ "," ] operand } ] ] [ "#" comment ]
+//
+// A comment starting with `##` is the title of the node.
+type sourceLine struct {
+	breakpoint  bool
+	label       *token
+	instruction []token // mnemonic followed by its operands, without separators
+	comment     string  // text following the `#`, if any
+}
+
+// lex splits the line starting at the given column into tokens, up to the `#` starting a comment.
+// It returns the tokens and the text of the comment.
+func lex(line string, column int) ([]token, string) {
+	var tokens []token
+	start := -1
+	flush := func(end int) {
+		if start != -1 {
+			tokens = append(tokens, token{kind: tokenWord, text: line[start:end], column: column + start})
+			start = -1
+		}
+	}
+
+	for i, r := range line {
+		kind := tokenWord
+		switch {
+		case r == '#':
+			flush(i)
+			return tokens, line[i+1:]
+		case unicode.IsSpace(r):
+			flush(i)
+			continue
+		case r == ',':
+			kind = tokenComma
+		case r == ':':
+			kind = tokenColon
+		case r == '!':
+			kind = tokenBang
+		}
+
+		if kind == tokenWord {
+			if start == -1 {
+				start = i
+			}
+			continue
+		}
+		flush(i)
+		tokens = append(tokens, token{kind: kind, text: string(r), column: column + i})
+	}
+	flush(len(line))
+	return tokens, ""
+}
+
+// parseLine splits the line starting at the given column according to the grammar.
+func parseLine(line string, column int) (sourceLine, *CompileError) {
+	tokens, comment := lex(line, column)
+	sl := sourceLine{comment: comment}
+	i := 0
+	peek := func(kind tokenKind, offset int) bool {
+		return i+offset < len(tokens) && tokens[i+offset].kind == kind
+	}
+
+	if peek(tokenBang, 0) {
+		sl.breakpoint = true
+		i++
+	}
+	switch {
+	case peek(tokenWord, 0) && peek(tokenColon, 1):
+		sl.label = &tokens[i]
+		i += 2
+	case peek(tokenColon, 0):
+		return sl, tokenError(CodeEmptyLabel, tokens[i], "empty label")
+	}
+	if peek(tokenBang, 0) {
+		sl.breakpoint = true
+		i++
+	}
+
+	for ; i < len(tokens); i++ {
+		t := tokens[i]
+		switch {
+		case t.kind == tokenWord:
+			sl.instruction = append(sl.instruction, t)
+		case t.kind == tokenComma && len(sl.instruction) > 1 && peek(tokenWord, 1):
+			// a single comma may separate two operands
+		default:
+			return sl, tokenError(CodeUnexpectedToken, t, "unexpected %s", t.text)
+		}
+	}
+	return sl, nil
+}
+
+// NodeTitle returns the title of a node given by a `##` comment in its source,
+// or an empty string if it has none. The first title of the node is used.
+func NodeTitle(lines []string) string {
+	for _, line := range lines {
+		if _, comment := lex(line, 0); strings.HasPrefix(comment, "#") {
+			return strings.TrimSpace(comment[1:])
+		}
+	}
+	return ""
+}
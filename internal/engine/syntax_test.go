@@ -0,0 +1,84 @@
+package engine_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lekomish/tis-100/internal/engine"
+	"github.com/lekomish/tis-100/internal/model"
+)
+
+/* TESTS */
+
+// --- Compile ---
+func TestCompileSyntax(t *testing.T) {
+	code := &model.Code{Title: "TEST", Nodes: make([][]string, model.NodesNumber)}
+	code.Nodes[0] = []string{
+		"## DOUBLER",
+		"# read a value",
+		"START: MOV UP,ACC # first",
+		"  ADD ACC",
+		"",
+		"!MOV ACC, DOWN",
+		"!LOOP:",
+		"JMP START#again",
+		"END:#done",
+	}
+
+	compiled, err := engine.Compile(code)
+	require.NoError(t, err)
+
+	instructions := compiled[0]
+	require.Len(t, instructions, 4)
+	require.Equal(t, []int{2, 3, 5, 7}, []int{
+		instructions[0].Line, instructions[1].Line, instructions[2].Line, instructions[3].Line,
+	})
+	require.Equal(t, engine.PortUp, instructions[0].Src.Port)
+	require.Equal(t, engine.PortAcc, instructions[0].Dest.Port)
+	require.Equal(t, engine.PortDown, instructions[2].Dest.Port)
+	require.True(t, instructions[2].Breakpoint)
+	require.True(t, instructions[3].Breakpoint, "the breakpoint of a label-only line moves to the next instruction")
+	require.Equal(t, int16(0), instructions[3].Src.Value)
+}
+
+func TestCompileSyntaxErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		line     string
+		expected string
+		column   int
+	}{
+		{name: "leading comma", line: "MOV ,UP ACC", expected: "unexpected ,", column: 5},
+		{name: "double comma", line: "MOV UP,,ACC", expected: "unexpected ,", column: 7},
+		{name: "trailing comma", line: "MOV UP ACC,", expected: "unexpected ,", column: 11},
+		{name: "second label", line: "A: B: NOP", expected: "unexpected :", column: 5},
+		{name: "misplaced breakpoint", line: "NOP !", expected: "unexpected !", column: 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code := &model.Code{Title: "TEST", Nodes: make([][]string, model.NodesNumber)}
+			code.Nodes[0] = []string{tt.line}
+
+			_, err := engine.Compile(code)
+			var errs engine.CompileErrors
+			require.True(t, errors.As(err, &errs))
+			require.Len(t, errs, 1)
+			require.Equal(t, engine.CodeUnexpectedToken, errs[0].Code)
+			require.Equal(t, tt.expected, errs[0].Message)
+			require.Equal(t, tt.column, errs[0].Column)
+			require.Equal(t, tt.column+1, errs[0].EndColumn)
+		})
+	}
+}
+
+// --- NodeTitle ---
+func TestNodeTitle(t *testing.T) {
+	require.Equal(t, "Adder", engine.NodeTitle([]string{"# not a title", "MOV UP ACC ## Adder", "## Other"}))
+	require.Equal(t, "", engine.NodeTitle([]string{"# comment", "NOP"}))
+	require.Equal(t, "", engine.NodeTitle(nil))
+}
+
+// lex -> covered in previous tests
+// parseLine -> covered in previous tests
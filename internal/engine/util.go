@@ -2,30 +2,51 @@ package engine
 
 import "github.com/lekomish/tis-100/internal/model"
 
-// advancePC increments the instruction pointer to move to the next instruction.
+// advancePC moves the instruction pointer of the next cycle to the following instruction.
 // It should be called after a successful instruction execution.
 func (n *Node) advancePC() {
-	n.InstructionPointer++
+	n.next.ip = n.followingPC(n.next.ip)
 }
 
-// jumpTo sets the instruction pointer to the specified position.
+// followingPC returns the index of the instruction after `ip`,
+// wrapping around to the first instruction at the end of the program.
+func (n *Node) followingPC(ip uint8) uint8 {
+	if int(ip)+1 >= len(n.Instructions) {
+		return 0
+	}
+	return ip + 1
+}
+
+// jumpTo sets the instruction pointer of the next cycle to the specified position.
 // If the position is out of bounds, it defaults to 0.
 func (n *Node) jumpTo(pos int16) {
 	if pos >= int16(len(n.Instructions)) || pos < 0 {
 		pos = 0
 	}
-	n.InstructionPointer = uint8(pos)
+	n.next.ip = uint8(pos)
 }
 
-// clampACC ensures that the ACC register stays within the defined bounds.
-// If ACC exceeds `MaxACC` or falls below `MinACC`, it is clamped accordingly.
-func (n *Node) clampACC() {
-	if n.ACC > model.MaxACC {
-		n.ACC = model.MaxACC
-	}
-	if n.ACC < model.MinACC {
-		n.ACC = model.MinACC
-	}
+// jumpRelative sets the instruction pointer of the next cycle to the instruction at the given
+// offset from the current one. As in the original game, a target before the first instruction
+// or after the last one is clamped to it.
+func (n *Node) jumpRelative(offset int16) {
+	target := int(n.InstructionPointer) + int(offset)
+	n.next.ip = uint8(max(0, min(target, len(n.Instructions)-1)))
+}
+
+// setACC stores `value` in the ACC register of the next cycle, clamped to the range of ACC.
+// The arithmetic is done in `int` by the callers so that sums of 16-bit operands cannot wrap
+// around, and the value before clamping is kept for the observers.
+func (n *Node) setACC(value int) {
+	n.next.unclamped = value
+	n.next.acc = clampValue(value)
+	n.next.clamped = int(n.next.acc) != value
+}
+
+// clampValue narrows `value` to the range of ACC (`MinACC`..`MaxACC`), the range of every
+// value held by a node or sent through a port.
+func clampValue(value int) int16 {
+	return int16(max(model.MinACC, min(value, model.MaxACC)))
 }
 
 // resetPCIfOutOfBounds resets the instruction pointer to 0 if it points past the instruction list.
@@ -0,0 +1,113 @@
+package loader
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/yuin/gopher-lua"
+
+	"github.com/lekomish/tis-100/internal/model"
+)
+
+// checkOutputsFunction is the optional Lua function checking all the outputs of a run.
+const checkOutputsFunction = "CheckOutputs"
+
+// newOutputCheck returns the check of a puzzle loaded from the script with the given seed.
+// The Lua state the puzzle was loaded in is closed once the puzzle is loaded, so the check
+// loads the script again with the same seed in a new sandbox: as loading is deterministic,
+// the check functions see the state the script had when the streams were generated.
+//
+// The check of every stream runs first, in stream order, followed by `CheckOutputs`.
+// Inputs are passed as a table of value lists keyed by stream name, as are outputs to
+// `CheckOutputs`, while the check of a stream receives the list of its own values.
+func newOutputCheck(filePath string, seed int64, limits Limits) model.OutputCheck {
+	return func(inputs, outputs map[string][]int16) ([]model.Verdict, error) {
+		sb := newSandbox(context.Background(), seed, limits)
+		defer sb.Close()
+
+		verdicts, err := runChecks(sb.LState, filePath, inputs, outputs)
+		if err != nil {
+			if cause := sb.interrupted(); cause != nil {
+				err = cause
+			}
+			return nil, fmt.Errorf("unable to check outputs with lua script %s: %w", filePath, err)
+		}
+		return verdicts, nil
+	}
+}
+
+// runChecks loads the puzzle script in the Lua state and calls its check functions.
+func runChecks(lState *lua.LState, filePath string, inputs, outputs map[string][]int16) ([]model.Verdict, error) {
+	puzzle, checks, err := loadPuzzle(lState, filePath)
+	if err != nil {
+		return nil, err
+	}
+	luaInputs := valuesTables(lState, inputs)
+
+	var verdicts []model.Verdict
+	for i, check := range checks {
+		if check == nil {
+			continue
+		}
+		name := puzzle.Streams[i].Name
+		v, err := callCheck(lState, check, fmt.Sprintf("check of stream %q", name), luaInputs, valuesTable(lState, outputs[name]))
+		if err != nil {
+			return nil, err
+		}
+		v.Stream = name
+		verdicts = append(verdicts, v)
+	}
+
+	if check, ok := lState.GetGlobal(checkOutputsFunction).(*lua.LFunction); ok {
+		v, err := callCheck(lState, check, checkOutputsFunction, luaInputs, valuesTables(lState, outputs))
+		if err != nil {
+			return nil, err
+		}
+		verdicts = append(verdicts, v)
+	}
+	return verdicts, nil
+}
+
+// callCheck calls a check function with the given arguments. The function must return
+// whether the outputs pass, optionally followed by a message.
+func callCheck(lState *lua.LState, fn *lua.LFunction, name string, args ...lua.LValue) (model.Verdict, error) {
+	if err := lState.CallByParam(lua.P{
+		Fn:      fn,
+		NRet:    2,
+		Protect: true,
+	}, args...); err != nil {
+		return model.Verdict{}, fmt.Errorf("error while calling %s: %w", name, err)
+	}
+	passedVal, messageVal := lState.Get(-2), lState.Get(-1)
+	lState.Pop(2)
+
+	passed, err := mustBool(passedVal, name+" result")
+	if err != nil {
+		return model.Verdict{}, err
+	}
+	v := model.Verdict{Passed: passed}
+	if messageVal != lua.LNil {
+		if v.Message, err = mustString(messageVal, name+" message"); err != nil {
+			return model.Verdict{}, err
+		}
+	}
+	return v, nil
+}
+
+// valuesTables converts value lists keyed by stream name to a Lua table of lists.
+func valuesTables(lState *lua.LState, values map[string][]int16) *lua.LTable {
+	tab := lState.NewTable()
+	for name, list := range values {
+		tab.RawSetString(name, valuesTable(lState, list))
+	}
+	return tab
+}
+
+// valuesTable converts a value list to a Lua list.
+func valuesTable(lState *lua.LState, values []int16) *lua.LTable {
+	tab := lState.CreateTable(len(values), 0)
+	for _, value := range values {
+		tab.Append(lua.LNumber(value))
+	}
+	return tab
+}
@@ -0,0 +1,173 @@
+package loader_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lekomish/tis-100/internal/loader"
+	"github.com/lekomish/tis-100/internal/model"
+)
+
+/* TESTS */
+
+// --- CheckOutputs ---
+func TestLoadPuzzleWithCheckOutputs(t *testing.T) {
+	s := newScript()
+	s.Layout = append(s.Layout,
+		"function CheckOutputs(inputs, outputs)",
+		"local values = {}",
+		"for i, value in ipairs(outputs[\"OUT.TEST\"]) do values[i] = value end",
+		"table.sort(values)",
+		"for i, value in ipairs(inputs[\"IN.TEST\"]) do",
+		"if values[i] ~= value then return false, \"OUT.TEST is not a permutation of IN.TEST\" end",
+		"end",
+		"return true",
+		"end",
+	)
+	filePath, err := setupLua(t, s, "test_load_puzzle_with_check_outputs.lua")
+	require.NoError(t, err, errCreatingFileMsg)
+
+	puzzle, err := loader.LoadPuzzle(filePath)
+	require.NoError(t, err)
+	require.NotNil(t, puzzle.Check)
+	require.False(t, puzzle.Streams[0].Checked)
+	require.True(t, puzzle.Streams[1].Checked)
+
+	inputs := map[string][]int16{"IN.TEST": {1, 2, 3}}
+	verdicts, err := puzzle.Check(inputs, map[string][]int16{"OUT.TEST": {3, 1, 2}})
+	require.NoError(t, err)
+	require.Equal(t, []model.Verdict{{Passed: true}}, verdicts)
+
+	verdicts, err = puzzle.Check(inputs, map[string][]int16{"OUT.TEST": {1, 1, 2}})
+	require.NoError(t, err)
+	require.Equal(t, []model.Verdict{{Message: "OUT.TEST is not a permutation of IN.TEST"}}, verdicts)
+}
+
+func TestLoadPuzzleWithStreamCheck(t *testing.T) {
+	s := newScript()
+	s.Streams = []string{
+		"function GetStreams()",
+		"return {",
+		"{ STREAM_INPUT, \"IN.TEST\", 0, { 1, 2, 3 } },",
+		"{ STREAM_OUTPUT, \"OUT.TEST\", 0, { 6 }, function(inputs, values)",
+		"return values[1] >= 5 and values[1] <= 7, \"expected 6 give or take 1\"",
+		"end },",
+		"{ STREAM_OUTPUT, \"OUT.EXACT\", 1, { 1 } },",
+		"}",
+		"end",
+	}
+	filePath, err := setupLua(t, s, "test_load_puzzle_with_stream_check.lua")
+	require.NoError(t, err, errCreatingFileMsg)
+
+	puzzle, err := loader.LoadPuzzle(filePath)
+	require.NoError(t, err)
+	require.True(t, puzzle.Streams[1].Checked)
+	require.False(t, puzzle.Streams[2].Checked)
+
+	verdicts, err := puzzle.Check(nil, map[string][]int16{"OUT.TEST": {7}, "OUT.EXACT": {1}})
+	require.NoError(t, err)
+	require.Equal(t, []model.Verdict{{Stream: "OUT.TEST", Passed: true, Message: "expected 6 give or take 1"}}, verdicts)
+}
+
+func TestLoadPuzzleWithCheckSeesGeneratedStreams(t *testing.T) {
+	s := newScript()
+	s.Streams = []string{
+		"function GetStreams()",
+		"target = math.random(1, 999)",
+		"return { { STREAM_OUTPUT, \"OUT.TEST\", 0, { target } } }",
+		"end",
+		"function CheckOutputs(inputs, outputs)",
+		"return outputs[\"OUT.TEST\"][1] == target",
+		"end",
+	}
+	filePath, err := setupLua(t, s, "test_load_puzzle_with_check_sees_generated_streams.lua")
+	require.NoError(t, err, errCreatingFileMsg)
+
+	for seed := range int64(5) {
+		puzzle, err := loader.LoadPuzzleWithSeed(filePath, seed)
+		require.NoError(t, err)
+
+		verdicts, err := puzzle.Check(nil, map[string][]int16{"OUT.TEST": puzzle.Streams[0].Values})
+		require.NoError(t, err)
+		require.True(t, verdicts[0].Passed, "the check should run with the seed of the puzzle")
+	}
+}
+
+func TestLoadPuzzleWithoutCheck(t *testing.T) {
+	filePath, err := setupLua(t, newScript(), "test_load_puzzle_without_check.lua")
+	require.NoError(t, err, errCreatingFileMsg)
+
+	puzzle, err := loader.LoadPuzzle(filePath)
+	require.NoError(t, err)
+	require.Nil(t, puzzle.Check)
+	for _, stream := range puzzle.Streams {
+		require.False(t, stream.Checked)
+	}
+}
+
+// --- Check errors ---
+func TestLoadPuzzleWithWrongStreamCheck(t *testing.T) {
+	tests := []struct {
+		name     string
+		stream   string
+		expected string
+	}{
+		{
+			name:     "type",
+			stream:   "{ STREAM_OUTPUT, \"OUT.TEST\", 0, { 1 }, 1 }",
+			expected: "stream[5]: expected function, got number",
+		},
+		{
+			name:     "input",
+			stream:   "{ STREAM_INPUT, \"IN.TEST\", 0, { 1 }, function() return true end }",
+			expected: "stream[5]: only output streams can have a check",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newScript()
+			s.Streams = []string{"function GetStreams()", "return { " + tt.stream + " }", "end"}
+			filePath, err := setupLua(t, s, "test_load_puzzle_with_wrong_stream_check.lua")
+			require.NoError(t, err, errCreatingFileMsg)
+
+			_, err = loader.LoadPuzzle(filePath)
+			require.EqualError(t, err, tt.expected)
+		})
+	}
+}
+
+func TestCheckWithWrongResult(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		expected string
+	}{
+		{name: "result", body: "return 1", expected: "CheckOutputs result: expected boolean, got number"},
+		{name: "message", body: "return false, {}", expected: "CheckOutputs message: expected string, got table"},
+		{name: "error", body: "error(\"broken check\")", expected: "error while calling CheckOutputs"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newScript()
+			s.Layout = append(s.Layout, "function CheckOutputs(inputs, outputs)", tt.body, "end")
+			filePath, err := setupLua(t, s, "test_check_with_wrong_result.lua")
+			require.NoError(t, err, errCreatingFileMsg)
+
+			puzzle, err := loader.LoadPuzzle(filePath)
+			require.NoError(t, err)
+
+			_, err = puzzle.Check(nil, nil)
+			require.ErrorContains(t, err, "unable to check outputs with lua script")
+			require.ErrorContains(t, err, tt.expected)
+		})
+	}
+}
+
+// newOutputCheck -> covered in previous tests
+// runChecks -> covered in previous tests
+// callCheck -> covered in previous tests
+// valuesTables -> covered in previous tests
+// valuesTable -> covered in previous tests
+// mustBool -> covered in previous tests
+// mustFunction -> covered in previous tests
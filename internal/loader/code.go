@@ -76,6 +76,7 @@ func SaveCode(dirPath string, code *model.Code) (string, error) {
 
 // LoadCode loads a `.tis` file into a Code object.
 // The file is expected to have node sections prefixed with "@1", "@2", etc.
+// Lines are kept as written, except for the blank lines at the end of a node.
 // Returns a parsed Code instance or an error if loading fails.
 func LoadCode(filePath string) (*model.Code, error) {
 	// open the file
@@ -90,14 +91,12 @@ func LoadCode(filePath string) (*model.Code, error) {
 
 	curNode := -1
 	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		// skip blank lines
-		if line == "" {
-			continue
-		}
+		// keep the source text as written, blank lines and indentation included,
+		// so line numbers and columns match the editor of the original game
+		line := strings.TrimRight(scanner.Text(), " \t\r")
 
 		// detect node header
-		if strings.HasPrefix(line, nodePrefix) {
+		if strings.HasPrefix(strings.TrimSpace(line), nodePrefix) {
 			curNode++
 			if curNode >= model.NodesNumber {
 				return nil, fmt.Errorf("too many node headers in file %s", filePath)
@@ -107,6 +106,9 @@ func LoadCode(filePath string) (*model.Code, error) {
 
 		// chech that a node header has been seen before lines
 		if curNode < 0 {
+			if line == "" {
+				continue
+			}
 			return nil, fmt.Errorf("code line found before any node header in file %s", filePath)
 		}
 
@@ -114,6 +116,17 @@ func LoadCode(filePath string) (*model.Code, error) {
 		nodes[curNode] = append(nodes[curNode], line)
 	}
 
+	// drop the blank lines separating a node from the next header
+	for i, lines := range nodes {
+		for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+			lines = lines[:len(lines)-1]
+		}
+		if len(lines) == 0 {
+			lines = nil
+		}
+		nodes[i] = lines
+	}
+
 	// check for scanning error
 	if err := scanner.Err(); err != nil {
 		return nil, fmt.Errorf("error reading from file %s: %w", filePath, err)
@@ -83,6 +83,18 @@ func TestLoadCodeWithWrongHeadersNumber(t *testing.T) {
 	require.ErrorContains(t, err, "too many node headers")
 }
 
+func TestLoadCodeKeepsSourceText(t *testing.T) {
+	content := "\n@0\n## ADDER\nSTART: MOV UP, ACC # read\n\n  ADD ACC\t\r\nMOV ACC DOWN\n\n\n@1\n\n@2\n!JMP 0\n"
+	filePath := filepath.Join(t.TempDir(), "adder.tis")
+	require.NoError(t, os.WriteFile(filePath, []byte(content), 0o600))
+
+	code, err := loader.LoadCode(filePath)
+	require.NoError(t, err, errUnexpectedMsg)
+	require.Equal(t, []string{"## ADDER", "START: MOV UP, ACC # read", "", "  ADD ACC", "MOV ACC DOWN"}, code.Nodes[0])
+	require.Nil(t, code.Nodes[1])
+	require.Equal(t, []string{"!JMP 0"}, code.Nodes[2])
+}
+
 // wrapWriterError -> covered in previous tests
 
 /* BENCHMARKS */
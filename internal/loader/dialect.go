@@ -0,0 +1,66 @@
+package loader
+
+import (
+	"github.com/yuin/gopher-lua"
+
+	"github.com/lekomish/tis-100/internal/model"
+)
+
+// dialect names the functions a puzzle script defines and the limits its streams follow.
+type dialect struct {
+	title       string // function returning the title
+	description string // function returning the description lines
+	streams     string // function returning the streams
+	layout      string // function returning the layout
+	maxValues   int    // maximum number of values of an input or output stream
+}
+
+var (
+	// nativeDialect is the dialect of the puzzle files of this project (see `puzzles/template.lua`).
+	nativeDialect = dialect{
+		title:       "GetTitle",
+		description: "GetDescription",
+		streams:     "GetStreams",
+		layout:      "GetLayout",
+		maxValues:   model.MaxStreamValuesLength,
+	}
+	// originalDialect is the dialect of the puzzle files written with the specification editor
+	// of the original game.
+	originalDialect = dialect{
+		title:       "get_name",
+		description: "get_description",
+		streams:     "get_streams",
+		layout:      "get_layout",
+		maxValues:   model.MaxOriginalStreamValuesLength,
+	}
+)
+
+// luaConstants are the STREAM_* and TILE_* values predefined as globals for puzzle scripts,
+// so scripts do not have to declare them. Scripts declaring them locally shadow them.
+var luaConstants = map[string]int{
+	"STREAM_INPUT":  int(model.INPUT),
+	"STREAM_OUTPUT": int(model.OUTPUT),
+	"STREAM_IMAGE":  int(model.IMAGE),
+	"TILE_COMPUTE":  int(model.COMPUTE),
+	"TILE_DAMAGED":  int(model.DAMAGED),
+	"TILE_MEMORY":   int(model.MEMORY),
+}
+
+// setConstants defines `luaConstants` as globals of the Lua state.
+func setConstants(lState *lua.LState) {
+	for name, value := range luaConstants {
+		lState.SetGlobal(name, lua.LNumber(value))
+	}
+}
+
+// detectDialect returns the dialect of the loaded script: the original one if the script
+// defines `get_name` but not `GetTitle`, the native one otherwise.
+func detectDialect(lState *lua.LState) dialect {
+	defines := func(name string) bool {
+		return lState.GetGlobal(name).Type() == lua.LTFunction
+	}
+	if defines(originalDialect.title) && !defines(nativeDialect.title) {
+		return originalDialect
+	}
+	return nativeDialect
+}
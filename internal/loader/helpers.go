@@ -38,6 +38,24 @@ func mustTable(val lua.LValue, field string) (*lua.LTable, error) {
 	return tab, nil
 }
 
+// mustBool checks that the Lua value is a boolean and returns it.
+func mustBool(val lua.LValue, field string) (bool, error) {
+	b, ok := val.(lua.LBool)
+	if !ok {
+		return false, fmt.Errorf("%s: expected boolean, got %s", field, val.Type())
+	}
+	return bool(b), nil
+}
+
+// mustFunction checks that the Lua value is a function and returns it.
+func mustFunction(val lua.LValue, field string) (*lua.LFunction, error) {
+	fn, ok := val.(*lua.LFunction)
+	if !ok {
+		return nil, fmt.Errorf("%s: expected function, got %s", field, val.Type())
+	}
+	return fn, nil
+}
+
 // runLuaFunction calls a global Lua function by name and returns its single result.
 func runLuaFunction(lState *lua.LState, functionName string) (lua.LValue, error) {
 	fn := lState.GetGlobal(functionName)
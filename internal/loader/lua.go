@@ -4,40 +4,94 @@
 package loader
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"math/rand"
 
 	"github.com/yuin/gopher-lua"
 
 	"github.com/lekomish/tis-100/internal/model"
 )
 
+// streamElements is the number of elements of a stream: type, name, position and values.
+const streamElements = 4
+
 // LoadPuzzle loads and executes a Lua puzzle definition file and extracts
 // the puzzle's title, description, streams, and layout by calling predefined Lua functions.
+// Both the functions of this project (`GetTitle`, `GetDescription`, `GetStreams`, `GetLayout`)
+// and those of the original game (`get_name`, `get_description`, `get_streams`, `get_layout`)
+// are accepted, and the STREAM_* and TILE_* constants are predefined.
+// The random generator of the script is seeded randomly, see `LoadPuzzleWithSeed`.
+// The script runs in a sandbox with `DefaultLimits`, see `LoadPuzzleContext`.
+//
+// Puzzles accepting more than one output sequence check the outputs with Lua functions instead
+// of comparing them with the expected values: `CheckOutputs(inputs, outputs)` checks all
+// the outputs and an optional fifth element of an output stream checks that stream alone
+// with `check(inputs, values)`. Both return whether the outputs pass and an optional message,
+// see `newOutputCheck`.
 func LoadPuzzle(filePath string) (*model.Puzzle, error) {
-	lState := lua.NewState()
-	defer lState.Close()
+	return LoadPuzzleWithSeed(filePath, rand.Int63())
+}
+
+// LoadPuzzleWithSeed works like `LoadPuzzle`, but seeds the `math.random` generator
+// of the script with the given seed, so the same seed always produces the same streams.
+func LoadPuzzleWithSeed(filePath string, seed int64) (*model.Puzzle, error) {
+	return LoadPuzzleContext(context.Background(), filePath, seed, DefaultLimits)
+}
+
+// LoadPuzzleContext works like `LoadPuzzleWithSeed`, running the script in a sandbox:
+// only the `math`, `string` and `table` libraries and the base functions that neither load code
+// nor write to the terminal are available. The script is interrupted when the context is done
+// or when it exceeds the limits, with `ErrTimeBudget` or `ErrMemoryLimit`.
+func LoadPuzzleContext(ctx context.Context, filePath string, seed int64, limits Limits) (*model.Puzzle, error) {
+	sb := newSandbox(ctx, seed, limits)
+	defer sb.Close()
+
+	puzzle, checks, err := loadPuzzle(sb.LState, filePath)
+	if err != nil {
+		if cause := sb.interrupted(); cause != nil {
+			return nil, fmt.Errorf("unable to load lua script %s: %w", filePath, cause)
+		}
+		return nil, err
+	}
+
+	checkAll := sb.GetGlobal(checkOutputsFunction).Type() == lua.LTFunction
+	checked := checkAll
+	for i, stream := range puzzle.Streams {
+		stream.Checked = checks[i] != nil || (checkAll && stream.Type == model.OUTPUT)
+		checked = checked || stream.Checked
+	}
+	if checked {
+		puzzle.Check = newOutputCheck(filePath, seed, limits)
+	}
+	return puzzle, nil
+}
 
+// loadPuzzle executes the puzzle script in the Lua state and extracts the puzzle from it,
+// along with the check function of every stream (nil for streams without one).
+func loadPuzzle(lState *lua.LState, filePath string) (*model.Puzzle, []*lua.LFunction, error) {
 	if err := lState.DoFile(filePath); err != nil {
-		return nil, fmt.Errorf("unable to load lua script %s: %w", filePath, err)
+		return nil, nil, fmt.Errorf("unable to load lua script %s: %w", filePath, err)
 	}
 
 	// call individual fetchers for puzzle metadata and components
-	title, err := fetchTitle(lState)
+	d := detectDialect(lState)
+	title, err := fetchTitle(lState, d)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	description, err := fetchDescription(lState)
+	description, err := fetchDescription(lState, d)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	streams, err := fetchStreams(lState)
+	streams, checks, err := fetchStreams(lState, d)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	layout, err := fetchLayout(lState)
+	layout, err := fetchLayout(lState, d)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	return &model.Puzzle{
@@ -45,27 +99,94 @@ func LoadPuzzle(filePath string) (*model.Puzzle, error) {
 		Description: description,
 		Streams:     streams,
 		Layout:      layout,
-	}, nil
+	}, checks, nil
+}
+
+// LoadTestSets loads the puzzle `count` times with consecutive seeds starting at `seed`,
+// producing independent and reproducible test sets. The seed of the i-th set is `seed+i`.
+func LoadTestSets(filePath string, seed int64, count int) ([]*model.Puzzle, error) {
+	sets := make([]*model.Puzzle, 0, count)
+	for i := range count {
+		puzzle, err := LoadPuzzleWithSeed(filePath, seed+int64(i))
+		if err != nil {
+			return nil, err
+		}
+		sets = append(sets, puzzle)
+	}
+	return sets, nil
+}
+
+// newLuaState creates a Lua state with the libraries of the sandbox (see `openLibs`)
+// whose `math.random` and `math.randomseed` functions use a generator owned by the state
+// and seeded with the given seed, instead of the process-wide generator used by default.
+// The STREAM_* and TILE_* constants are defined as globals.
+func newLuaState(seed int64) *lua.LState {
+	lState := lua.NewState(lua.Options{
+		SkipOpenLibs:    true,
+		CallStackSize:   luaCallStackSize,
+		RegistrySize:    lua.RegistrySize,
+		RegistryMaxSize: luaRegistryMaxSize,
+	})
+	openLibs(lState)
+	setConstants(lState)
+	rng := rand.New(rand.NewSource(seed))
+
+	mathTable := lState.GetGlobal("math").(*lua.LTable)
+	mathTable.RawSetString("random", lState.NewFunction(func(l *lua.LState) int {
+		return luaRandom(l, rng)
+	}))
+	mathTable.RawSetString("randomseed", lState.NewFunction(func(l *lua.LState) int {
+		rng.Seed(int64(l.CheckNumber(1)))
+		return 0
+	}))
+
+	return lState
+}
+
+// luaRandom implements `math.random` with the semantics of the Lua reference manual:
+// without arguments it returns a float in [0, 1), with `m` an integer in [1, m]
+// and with `m, n` an integer in [m, n].
+func luaRandom(lState *lua.LState, rng *rand.Rand) int {
+	var low, high int64
+	switch lState.GetTop() {
+	case 0:
+		lState.Push(lua.LNumber(rng.Float64()))
+		return 1
+	case 1:
+		low, high = 1, int64(lState.CheckNumber(1))
+	case 2:
+		low, high = int64(lState.CheckNumber(1)), int64(lState.CheckNumber(2))
+	default:
+		lState.RaiseError("wrong number of arguments")
+		return 0
+	}
+	if low > high {
+		lState.RaiseError("interval is empty")
+		return 0
+	}
+
+	lState.Push(lua.LNumber(low + rng.Int63n(high-low+1)))
+	return 1
 }
 
-// fetchTitle retrieves the puzzle title by calling the Lua function `GetTitle`.
-func fetchTitle(lState *lua.LState) (string, error) {
-	val, err := runLuaFunction(lState, "GetTitle")
+// fetchTitle retrieves the puzzle title by calling the title function of the dialect (`GetTitle`).
+func fetchTitle(lState *lua.LState, d dialect) (string, error) {
+	val, err := runLuaFunction(lState, d.title)
 	if err != nil {
 		return "", err
 	}
-	return mustString(val, "GetTitle result")
+	return mustString(val, d.title+" result")
 }
 
-// fetchDescription retrieves the puzzle description by calling `GetDescription`
+// fetchDescription retrieves the puzzle description by calling `GetDescription` (or its equivalent)
 // and converting the returned Lua table to a slice of strings.
-func fetchDescription(lState *lua.LState) ([]string, error) {
-	val, err := runLuaFunction(lState, "GetDescription")
+func fetchDescription(lState *lua.LState, d dialect) ([]string, error) {
+	val, err := runLuaFunction(lState, d.description)
 	if err != nil {
 		return nil, err
 	}
 
-	descTable, err := mustTable(val, "GetDescription result")
+	descTable, err := mustTable(val, d.description+" result")
 	if err != nil {
 		return nil, err
 	}
@@ -91,20 +212,24 @@ func fetchDescription(lState *lua.LState) ([]string, error) {
 	return desc, nil
 }
 
-// fetchStreams retrieves a list of stream definitions by calling `GetStreams` in Lua.
+// fetchStreams retrieves a list of stream definitions by calling `GetStreams` (or its equivalent) in Lua.
 // Each stream must be a 4-element table containing stream metadata and values.
-func fetchStreams(lState *lua.LState) ([]*model.Stream, error) {
-	val, err := runLuaFunction(lState, "GetStreams")
+// The values of an image stream are the colours of all its pixels, row by row.
+// An output stream may have a fifth element, the function checking its values,
+// returned along with the streams (nil for streams without one).
+func fetchStreams(lState *lua.LState, d dialect) ([]*model.Stream, []*lua.LFunction, error) {
+	val, err := runLuaFunction(lState, d.streams)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	streamsTable, err := mustTable(val, "GetStreams result")
+	streamsTable, err := mustTable(val, d.streams+" result")
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	var streams []*model.Stream
+	var checks []*lua.LFunction
 	var iterErr error
 	// iterate over each stream entry
 	streamsTable.ForEach(func(_, streamVal lua.LValue) {
@@ -113,11 +238,12 @@ func fetchStreams(lState *lua.LState) ([]*model.Stream, error) {
 			iterErr = err
 			return
 		}
-		if sTab.Len() != model.IOPositionsNumber {
+		if sTab.Len() != streamElements && sTab.Len() != streamElements+1 {
 			iterErr = fmt.Errorf(
-				"stream must have %d elements, got %d",
-				model.IOPositionsNumber,
-				streamsTable.Len(),
+				"stream must have %d elements (%d with a check), got %d",
+				streamElements,
+				streamElements+1,
+				sTab.Len(),
 			)
 			return
 		}
@@ -136,7 +262,7 @@ func fetchStreams(lState *lua.LState) ([]*model.Stream, error) {
 		}
 
 		posNum, err := mustNumber(sTab.RawGetInt(3), "stream[3]")
-		if err != nil || posNum < 0 || posNum > model.IOPositionsNumber {
+		if err != nil || posNum < 0 || posNum >= model.IOPositionsNumber {
 			iterErr = fmt.Errorf("stream[3] out of range (0-%d)", model.IOPositionsNumber-1)
 			return
 		}
@@ -146,8 +272,16 @@ func fetchStreams(lState *lua.LState) ([]*model.Stream, error) {
 			iterErr = err
 			return
 		}
-		if valuesTable.Len() > model.MaxStreamValuesLength {
-			iterErr = fmt.Errorf("stream[4]: too many values (max %d)", model.MaxStreamValuesLength)
+		image := model.StreamType(typeNum) == model.IMAGE
+		minValue, maxValue := lua.LNumber(model.MinACC), lua.LNumber(model.MaxACC)
+		switch {
+		case image && valuesTable.Len() != model.ImageWidth*model.ImageHeight:
+			iterErr = fmt.Errorf("stream[4]: image must have %d pixels, got %d", model.ImageWidth*model.ImageHeight, valuesTable.Len())
+			return
+		case image:
+			minValue, maxValue = 0, model.ImageColours-1
+		case valuesTable.Len() > d.maxValues:
+			iterErr = fmt.Errorf("stream[4]: too many values (max %d)", d.maxValues)
 			return
 		}
 
@@ -162,11 +296,11 @@ func fetchStreams(lState *lua.LState) ([]*model.Stream, error) {
 				iterErr = err
 				return
 			}
-			if num < model.MinACC || num > model.MaxACC {
+			if num < minValue || num > maxValue {
 				iterErr = fmt.Errorf(
 					"stream[4] value out of range (%d to %d)",
-					model.MinACC,
-					model.MaxACC,
+					int(minValue),
+					int(maxValue),
 				)
 				return
 			}
@@ -177,6 +311,19 @@ func fetchStreams(lState *lua.LState) ([]*model.Stream, error) {
 			return
 		}
 
+		var check *lua.LFunction
+		if sTab.Len() > streamElements {
+			check, iterErr = mustFunction(sTab.RawGetInt(5), "stream[5]")
+			if iterErr != nil {
+				return
+			}
+			if model.StreamType(typeNum) != model.OUTPUT {
+				iterErr = errors.New("stream[5]: only output streams can have a check")
+				return
+			}
+		}
+
+		checks = append(checks, check)
 		streams = append(streams, &model.Stream{
 			Type:     model.StreamType(typeNum),
 			Name:     name,
@@ -186,20 +333,20 @@ func fetchStreams(lState *lua.LState) ([]*model.Stream, error) {
 	})
 
 	if iterErr != nil {
-		return nil, iterErr
+		return nil, nil, iterErr
 	}
-	return streams, nil
+	return streams, checks, nil
 }
 
-// fetchLayout retrievese the puzzle's node layout by calling the Lua function `GetLayout`.
+// fetchLayout retrievese the puzzle's node layout by calling the Lua function `GetLayout` (or its equivalent).
 // It expects a table with a number of entries equal to `model.NodesNumber`.
-func fetchLayout(lState *lua.LState) ([]model.NodeType, error) {
-	val, err := runLuaFunction(lState, "GetLayout")
+func fetchLayout(lState *lua.LState, d dialect) ([]model.NodeType, error) {
+	val, err := runLuaFunction(lState, d.layout)
 	if err != nil {
 		return nil, err
 	}
 
-	layoutTable, err := mustTable(val, "GetLayout result")
+	layoutTable, err := mustTable(val, d.layout+" result")
 	if err != nil {
 		return nil, err
 	}
@@ -84,6 +84,99 @@ func TestLoadPuzzleWithoutFunction(t *testing.T) {
 	require.ErrorContains(t, err, "lua function \"GetTitle\" not found")
 }
 
+// --- LoadPuzzleWithSeed ---
+func TestLoadPuzzleWithSeedIsReproducible(t *testing.T) {
+	filePath, err := setupLua(t, newRandomScript(), "test_load_puzzle_with_seed.lua")
+	require.NoError(t, err, errCreatingFileMsg)
+
+	first, err := loader.LoadPuzzleWithSeed(filePath, 7)
+	require.NoError(t, err, errUnexpectedMsg)
+	second, err := loader.LoadPuzzleWithSeed(filePath, 7)
+	require.NoError(t, err, errUnexpectedMsg)
+	other, err := loader.LoadPuzzleWithSeed(filePath, 8)
+	require.NoError(t, err, errUnexpectedMsg)
+
+	require.Equal(t, first, second)
+	require.NotEqual(t, first.Streams, other.Streams)
+}
+
+func TestLoadPuzzleWithSeedRandomArguments(t *testing.T) {
+	s := newScript()
+	s.Streams = []string{
+		"function GetStreams()",
+		"local values = {}",
+		"for i = 1, 10 do",
+		"local f = math.random()",
+		"assert(f >= 0 and f < 1)",
+		"values[i] = math.random(3) * 10 + math.random(-2, -1)",
+		"end",
+		"return { { STREAM_INPUT, \"IN.TEST\", 0, values } }",
+		"end",
+	}
+
+	filePath, err := setupLua(t, s, "test_load_puzzle_with_seed_random_arguments.lua")
+	require.NoError(t, err, errCreatingFileMsg)
+
+	puzzle, err := loader.LoadPuzzleWithSeed(filePath, 1)
+	require.NoError(t, err, errUnexpectedMsg)
+	for _, value := range puzzle.Streams[0].Values {
+		require.Contains(t, []int16{8, 9, 18, 19, 28, 29}, value)
+	}
+}
+
+func TestLoadPuzzleWithSeedRandomEmptyInterval(t *testing.T) {
+	s := newScript()
+	s.Streams = []string{"function GetStreams()", "return { math.random(5, 1) }", "end"}
+
+	filePath, err := setupLua(t, s, "test_load_puzzle_with_seed_random_empty_interval.lua")
+	require.NoError(t, err, errCreatingFileMsg)
+
+	_, err = loader.LoadPuzzleWithSeed(filePath, 1)
+	require.ErrorContains(t, err, "interval is empty")
+}
+
+func TestLoadPuzzleWithSeedRandomseed(t *testing.T) {
+	s := newRandomScript()
+	s.Beginning = append(s.Beginning, "math.randomseed(42)")
+
+	filePath, err := setupLua(t, s, "test_load_puzzle_with_seed_randomseed.lua")
+	require.NoError(t, err, errCreatingFileMsg)
+
+	first, err := loader.LoadPuzzleWithSeed(filePath, 1)
+	require.NoError(t, err, errUnexpectedMsg)
+	second, err := loader.LoadPuzzleWithSeed(filePath, 2)
+	require.NoError(t, err, errUnexpectedMsg)
+	require.Equal(t, first, second, "the script seed should override the loader seed")
+}
+
+// --- LoadTestSets ---
+func TestLoadTestSets(t *testing.T) {
+	filePath, err := setupLua(t, newRandomScript(), "test_load_test_sets.lua")
+	require.NoError(t, err, errCreatingFileMsg)
+
+	sets, err := loader.LoadTestSets(filePath, 10, 3)
+	require.NoError(t, err, errUnexpectedMsg)
+	require.Len(t, sets, 3)
+
+	for i, set := range sets {
+		expected, err := loader.LoadPuzzleWithSeed(filePath, int64(10+i))
+		require.NoError(t, err, errUnexpectedMsg)
+		require.Equal(t, expected, set)
+	}
+	require.NotEqual(t, sets[0].Streams, sets[1].Streams)
+}
+
+func TestLoadTestSetsWithWrongScript(t *testing.T) {
+	s := newScript()
+	s.Title = []string{""}
+
+	filePath, err := setupLua(t, s, "test_load_test_sets_with_wrong_script.lua")
+	require.NoError(t, err, errCreatingFileMsg)
+
+	_, err = loader.LoadTestSets(filePath, 0, 3)
+	require.ErrorContains(t, err, "lua function \"GetTitle\" not found")
+}
+
 // --- Title errors ---
 func TestLoadPuzzleWithWrongTitle(t *testing.T) {
 	s := newScript()
@@ -217,7 +310,7 @@ func TestLoadPuzzleWithWrongStreamPosition(t *testing.T) {
 	s := newScript()
 	s.Streams = []string{
 		"function GetStreams()",
-		"return { { 0, \"IN.TEST\", 5, { 1, 2, 3 } } }",
+		"return { { 0, \"IN.TEST\", 4, { 1, 2, 3 } } }",
 		"end",
 	}
 
@@ -288,6 +381,144 @@ func TestLoadPuzzleWithWrongStreamItems(t *testing.T) {
 	require.ErrorContains(t, err, "stream[4] value out of range")
 }
 
+// --- Image streams ---
+func TestLoadPuzzleWithImageStream(t *testing.T) {
+	s := newScript()
+	s.Streams = []string{
+		"function GetStreams()",
+		"local image = {}",
+		"for i = 1, 30 * 18 do",
+		"image[i] = i % 5",
+		"end",
+		"return { { 2, \"IMAGE.TEST\", 1, image } }",
+		"end",
+	}
+
+	filePath, err := setupLua(t, s, "test_load_puzzle_with_image_stream.lua")
+	require.NoError(t, err, errCreatingFileMsg)
+
+	puzzle, err := loader.LoadPuzzle(filePath)
+	require.NoError(t, err)
+	require.Len(t, puzzle.Streams, 1)
+	require.Equal(t, model.IMAGE, puzzle.Streams[0].Type)
+	require.Len(t, puzzle.Streams[0].Values, model.ImageWidth*model.ImageHeight)
+	require.Equal(t, []int16{1, 2, 3, 4, 0}, puzzle.Streams[0].Values[:5])
+}
+
+func TestLoadPuzzleWithWrongImageStream(t *testing.T) {
+	tests := []struct {
+		name     string
+		pixels   string
+		expected string
+	}{
+		{
+			name:     "pixels number",
+			pixels:   "for i = 1, 30 do image[i] = 0 end",
+			expected: "stream[4]: image must have 540 pixels, got 30",
+		},
+		{
+			name:     "colour",
+			pixels:   "for i = 1, 30 * 18 do image[i] = 0 end image[7] = 5",
+			expected: "stream[4] value out of range (0 to 4)",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newScript()
+			s.Streams = []string{
+				"function GetStreams()",
+				"local image = {}",
+				tt.pixels,
+				"return { { 2, \"IMAGE.TEST\", 1, image } }",
+				"end",
+			}
+
+			filePath, err := setupLua(t, s, "test_load_puzzle_with_wrong_image_stream.lua")
+			require.NoError(t, err, errCreatingFileMsg)
+
+			_, err = loader.LoadPuzzle(filePath)
+			require.EqualError(t, err, tt.expected)
+		})
+	}
+}
+
+// --- Layout ---
+func TestLoadPuzzleWithMemoryLayout(t *testing.T) {
+	s := newScript()
+	s.Layout = []string{
+		"function GetLayout()",
+		"return { 2, 0, 1, 0, 0, 0, 0, 0, 0, 0, 0, 2 }",
+		"end",
+	}
+
+	filePath, err := setupLua(t, s, "test_load_puzzle_with_memory_layout.lua")
+	require.NoError(t, err, errCreatingFileMsg)
+
+	puzzle, err := loader.LoadPuzzle(filePath)
+	require.NoError(t, err)
+	require.Equal(t, model.MEMORY, puzzle.Layout[0])
+	require.Equal(t, model.DAMAGED, puzzle.Layout[2])
+	require.Equal(t, model.MEMORY, puzzle.Layout[11])
+}
+
+// --- Dialects ---
+func TestLoadPuzzleWithPredefinedConstants(t *testing.T) {
+	s := newScript()
+	s.Beginning = nil
+	s.Layout = []string{
+		"function GetLayout()",
+		"return { TILE_MEMORY, TILE_DAMAGED, TILE_COMPUTE, 0, 0, 0, 0, 0, 0, 0, 0, 0 }",
+		"end",
+	}
+
+	filePath, err := setupLua(t, s, "test_load_puzzle_with_predefined_constants.lua")
+	require.NoError(t, err, errCreatingFileMsg)
+
+	puzzle, err := loader.LoadPuzzle(filePath)
+	require.NoError(t, err)
+	require.Equal(t, model.INPUT, puzzle.Streams[0].Type)
+	require.Equal(t, model.OUTPUT, puzzle.Streams[1].Type)
+	require.Equal(t, []model.NodeType{model.MEMORY, model.DAMAGED, model.COMPUTE}, puzzle.Layout[:3])
+}
+
+func TestLoadPuzzleWithOriginalDialect(t *testing.T) {
+	filePath, err := setupLua(t, newOriginalScript(), "test_load_puzzle_with_original_dialect.lua")
+	require.NoError(t, err, errCreatingFileMsg)
+
+	puzzle, err := loader.LoadPuzzle(filePath)
+	require.NoError(t, err)
+	require.Equal(t, "ORIGINAL", puzzle.Title)
+	require.Equal(t, []string{"READ A VALUE FROM IN", "WRITE IT TO OUT"}, puzzle.Description)
+	require.Len(t, puzzle.Streams, 3)
+	require.Len(t, puzzle.Streams[0].Values, model.MaxOriginalStreamValuesLength)
+	require.Equal(t, model.IMAGE, puzzle.Streams[2].Type)
+	require.Equal(t, model.MEMORY, puzzle.Layout[1])
+}
+
+func TestLoadPuzzleWithOriginalDialectErrors(t *testing.T) {
+	s := newOriginalScript()
+	s.Streams = []string{
+		"function get_streams()",
+		"local values = {}",
+		"for i = 1, 40 do values[i] = i end",
+		"return { { STREAM_INPUT, \"IN\", 0, values } }",
+		"end",
+	}
+	filePath, err := setupLua(t, s, "test_load_puzzle_with_original_dialect_errors.lua")
+	require.NoError(t, err, errCreatingFileMsg)
+
+	_, err = loader.LoadPuzzle(filePath)
+	require.ErrorContains(t, err, "too many values (max 39)")
+
+	s = newOriginalScript()
+	s.Layout = []string{"function get_layout()", "return 1", "end"}
+	filePath, err = setupLua(t, s, "test_load_puzzle_with_original_dialect_errors.lua")
+	require.NoError(t, err, errCreatingFileMsg)
+
+	_, err = loader.LoadPuzzle(filePath)
+	require.ErrorContains(t, err, "get_layout result: expected table")
+}
+
 // --- Layout errors ---
 func TestLoadPuzzleWithWrongLayoutType(t *testing.T) {
 	s := newScript()
@@ -357,6 +588,10 @@ func TestLoadPuzzleWithWrongLayoutItems(t *testing.T) {
 // mustNumber -> covered in previous tests
 // mustTable -> covered in previous tests
 // runLuaFunction -> covered in previous tests
+// newLuaState -> covered in previous tests
+// luaRandom -> covered in previous tests
+// setConstants -> covered in previous tests
+// detectDialect -> covered in previous tests
 
 /* BENCHMARKS */
 
@@ -430,6 +665,58 @@ func newScript() *script {
 	}
 }
 
+// newRandomScript creates a valid test Lua script whose streams are generated with `math.random`.
+func newRandomScript() *script {
+	s := newScript()
+	s.Streams = []string{
+		"function GetStreams()",
+		"local input = {}",
+		"for i = 1, 20 do",
+		"input[i] = math.random(1, 999)",
+		"end",
+		"return {",
+		"{ STREAM_INPUT, \"IN.TEST\", 0, input },",
+		"{ STREAM_OUTPUT, \"OUT.TEST\", 0, input },",
+		"}",
+		"end",
+	}
+	return s
+}
+
+// newOriginalScript creates a valid test Lua script written in the dialect of the original game,
+// relying on the predefined constants.
+func newOriginalScript() *script {
+	return &script{
+		Title: []string{"function get_name()", "return \"ORIGINAL\"", "end"},
+		Description: []string{
+			"function get_description()",
+			"return { \"READ A VALUE FROM IN\", \"WRITE IT TO OUT\" }",
+			"end",
+		},
+		Streams: []string{
+			"function get_streams()",
+			"local values, image = {}, {}",
+			"for i = 1, 39 do values[i] = i end",
+			"for i = 1, 30 * 18 do image[i] = 0 end",
+			"return {",
+			"{ STREAM_INPUT, \"IN\", 1, values },",
+			"{ STREAM_OUTPUT, \"OUT\", 2, values },",
+			"{ STREAM_IMAGE, \"IMAGE\", 0, image },",
+			"}",
+			"end",
+		},
+		Layout: []string{
+			"function get_layout()",
+			"return {",
+			"TILE_COMPUTE, TILE_MEMORY, TILE_COMPUTE, TILE_COMPUTE,",
+			"TILE_COMPUTE, TILE_COMPUTE, TILE_COMPUTE, TILE_COMPUTE,",
+			"TILE_COMPUTE, TILE_COMPUTE, TILE_COMPUTE, TILE_DAMAGED,",
+			"}",
+			"end",
+		},
+	}
+}
+
 // ToSlice returns the full Lua script as a slice of lines in proper order.
 func (s *script) ToSlice() []string {
 	fullScript := append(s.Beginning, s.Title...)
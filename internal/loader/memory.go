@@ -0,0 +1,248 @@
+package loader
+
+import (
+	"context"
+	"fmt"
+	"runtime/metrics"
+	"time"
+
+	"github.com/yuin/gopher-lua"
+)
+
+// memoryCheckInstructions is the smallest number of instructions run between two measures
+// of the memory used by a script.
+const memoryCheckInstructions = 10_000
+
+// Sizes the memory used by a script is measured with. They approximate the size of the values
+// in the Lua state, a string also counting the bytes of its content once per reference.
+const (
+	valueSize    = 16 // a value held by a table, a stack slot or an upvalue
+	tableSize    = 64 // a table, besides its entries
+	functionSize = 64 // a function, besides its upvalues
+)
+
+// memoryCheckInterval is the time between two checks of the heap while a script runs.
+const memoryCheckInterval = time.Millisecond
+
+// heapBackstopFactor is the growth of the heap, as a multiple of the memory limit, at which
+// a script is interrupted for the allocations the sandbox cannot count.
+const heapBackstopFactor = 4
+
+// heapMetric is the runtime metric the heap is measured with.
+const heapMetric = "/memory/classes/heap/objects:bytes"
+
+// memoryLimit counts the memory used by the script of a sandbox.
+type memoryLimit struct {
+	limit     uint64
+	exceeded  error  // cause the script is interrupted with
+	used      uint64 // size of the reachable values at the last measure, plus the strings built since
+	countdown int    // instructions left before the next measure
+}
+
+// meteredContext is the context of a sandbox with a memory limit. The Lua VM checks its context
+// before every instruction, which lets the sandbox measure the memory used by the script after
+// the same instructions in every run.
+type meteredContext struct {
+	context.Context
+	sb *sandbox
+}
+
+// Done implements `context.Context`, measuring the memory used by the script when due.
+func (c meteredContext) Done() <-chan struct{} {
+	c.sb.countInstruction()
+	return c.Context.Done()
+}
+
+// limitMemory cancels the context of the sandbox once the values of the script use more than
+// `limit` bytes. The values reachable from the globals and from the stack of the running
+// functions are measured every `memoryCheckInstructions` instructions at least, and more
+// rarely as they grow so that measuring stays proportionate to running. The strings built by
+// `string` and `table` functions are counted as they are built, before allocating them for
+// `string.rep` and `table.concat` whose results can be much larger than their arguments.
+// Everything is counted deterministically: a script exceeds the limit after the same
+// instruction in every run. As a backstop for what grows too fast between two measures, such as
+// repeated concatenations, the script is also interrupted when the heap of the process grows by
+// more than `heapBackstopFactor` times the limit, which is only approximate since the heap
+// is shared with the rest of the process.
+func (sb *sandbox) limitMemory(limit uint64) {
+	sb.memory = &memoryLimit{
+		limit:     limit,
+		exceeded:  fmt.Errorf("%w of %d MiB", ErrMemoryLimit, limit>>20),
+		countdown: memoryCheckInstructions,
+	}
+	sb.SetContext(meteredContext{Context: sb.ctx, sb: sb})
+	sb.watchHeap(limit * heapBackstopFactor)
+
+	wrap := func(lib, name string, size func(l *lua.LState) uint64) {
+		table := sb.GetGlobal(lib).(*lua.LTable)
+		fn := table.RawGetString(name).(*lua.LFunction)
+		table.RawSetString(name, sb.NewFunction(func(l *lua.LState) int {
+			if size != nil {
+				sb.allocate(l, size(l))
+			}
+			n := fn.GFunction(l)
+			if size == nil && n > 0 {
+				sb.allocate(l, uint64(len(lua.LVAsString(l.Get(-n)))))
+			}
+			return n
+		}))
+	}
+
+	wrap("string", "rep", repSize)
+	wrap("table", "concat", concatSize)
+	for _, name := range []string{"format", "gsub", "lower", "reverse", "upper"} {
+		wrap("string", name, nil)
+	}
+}
+
+// allocate counts `size` bytes built by a library function, raising an error in the script
+// if they exceed the memory limit.
+func (sb *sandbox) allocate(l *lua.LState, size uint64) {
+	m := sb.memory
+	m.used += size
+	if m.used > m.limit {
+		sb.cancel(m.exceeded)
+		l.RaiseError("%s", m.exceeded)
+	}
+}
+
+// countInstruction counts an instruction of the script and measures its memory when due,
+// interrupting the script if it exceeds the memory limit.
+func (sb *sandbox) countInstruction() {
+	m := sb.memory
+	if m.countdown--; m.countdown > 0 {
+		return
+	}
+	size, values := sb.measure()
+	m.used = size
+	m.countdown = max(memoryCheckInstructions, values)
+	if m.used > m.limit {
+		sb.cancel(m.exceeded)
+	}
+}
+
+// measure returns the size of the values reachable from the globals and from the stack of the
+// running functions, and the number of values measured.
+func (sb *sandbox) measure() (uint64, int) {
+	var size uint64
+	var values int
+	seen := make(map[lua.LValue]bool)
+	var pending []lua.LValue // tables and functions whose content is still to be measured
+
+	roots := []lua.LValue{sb.G.Global}
+	for level := 0; ; level++ {
+		dbg, ok := sb.GetStack(level)
+		if !ok {
+			break
+		}
+		if fn, err := sb.GetInfo("f", dbg, lua.LNil); err == nil {
+			roots = append(roots, fn)
+		}
+		for n := 1; ; n++ {
+			name, value := sb.GetLocal(dbg, n)
+			if name == "" {
+				break
+			}
+			roots = append(roots, value)
+		}
+	}
+
+	// scalars are measured where they are held, tables and functions once
+	add := func(value lua.LValue) {
+		size += valueSize
+		values++
+		switch v := value.(type) {
+		case lua.LString:
+			size += uint64(len(v))
+		case *lua.LTable, *lua.LFunction:
+			if !seen[v] {
+				seen[v] = true
+				pending = append(pending, v)
+			}
+		}
+	}
+	for _, root := range roots {
+		add(root)
+	}
+
+	for len(pending) > 0 {
+		value := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+
+		switch v := value.(type) {
+		case *lua.LTable:
+			size += tableSize
+			if v.Metatable != nil {
+				add(v.Metatable)
+			}
+			v.ForEach(func(key, val lua.LValue) {
+				add(key)
+				add(val)
+			})
+		case *lua.LFunction:
+			size += functionSize
+			if v.Env != nil {
+				add(v.Env)
+			}
+			for _, uv := range v.Upvalues {
+				add(uv.Value())
+			}
+		}
+	}
+	return size, values
+}
+
+// watchHeap cancels the context of the sandbox when the heap grows by more than `limit` bytes,
+// checking it every `memoryCheckInterval`.
+func (sb *sandbox) watchHeap(limit uint64) {
+	sample := []metrics.Sample{{Name: heapMetric}}
+	metrics.Read(sample)
+	baseline := sample[0].Value.Uint64()
+
+	go func() {
+		ticker := time.NewTicker(memoryCheckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-sb.ctx.Done():
+				return
+			case <-ticker.C:
+				metrics.Read(sample)
+				if used := sample[0].Value.Uint64(); used > baseline && used-baseline > limit {
+					sb.cancel(sb.memory.exceeded)
+					return
+				}
+			}
+		}
+	}()
+}
+
+// repSize returns the size of the result of `string.rep(s, n)`.
+func repSize(l *lua.LState) uint64 {
+	n := l.CheckInt(2)
+	if n <= 0 {
+		return 0
+	}
+	return uint64(len(l.CheckString(1))) * uint64(n)
+}
+
+// concatSize returns the size of the result of `table.concat(t, sep, i, j)`,
+// counting the values that are neither strings nor numbers as empty and stopping at the first
+// missing value, which `table.concat` rejects.
+func concatSize(l *lua.LState) uint64 {
+	tbl := l.CheckTable(1)
+	sep := uint64(len(l.OptString(2, "")))
+	first, last := l.OptInt(3, 1), l.OptInt(4, tbl.Len())
+	if first > last {
+		return 0
+	}
+	size := sep * uint64(last-first)
+	for i := first; i <= last; i++ {
+		value := tbl.RawGetInt(i)
+		if value == lua.LNil {
+			break
+		}
+		size += uint64(len(lua.LVAsString(value)))
+	}
+	return size
+}
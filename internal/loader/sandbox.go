@@ -0,0 +1,104 @@
+package loader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/yuin/gopher-lua"
+)
+
+var (
+	// ErrTimeBudget is returned when a puzzle script runs longer than its time budget.
+	ErrTimeBudget = errors.New("lua script exceeded its time budget")
+	// ErrMemoryLimit is returned when a puzzle script uses more memory than its limit.
+	ErrMemoryLimit = errors.New("lua script exceeded its memory limit")
+)
+
+// Limits bounds the resources a puzzle script may use while it runs.
+type Limits struct {
+	Timeout time.Duration // maximum run time of the script (0 means no limit besides the context)
+	Memory  uint64        // maximum number of bytes the values of the script may use, see `limitMemory` (0 means no limit)
+}
+
+// DefaultLimits are the limits puzzle scripts run with in `LoadPuzzle` and `LoadPuzzleWithSeed`.
+var DefaultLimits = Limits{Timeout: 5 * time.Second, Memory: 256 << 20}
+
+// luaLibs are the standard libraries available to puzzle scripts.
+var luaLibs = []struct {
+	name string
+	open lua.LGFunction
+}{
+	{lua.BaseLibName, lua.OpenBase},
+	{lua.TabLibName, lua.OpenTable},
+	{lua.StringLibName, lua.OpenString},
+	{lua.MathLibName, lua.OpenMath},
+}
+
+// unsafeBaseFunctions are the functions of the base library removed from puzzle scripts:
+// they load code or modules, write to the terminal or control the garbage collector.
+var unsafeBaseFunctions = []string{
+	"collectgarbage", "dofile", "load", "loadfile", "loadstring", "module", "print", "require", "_printregs",
+}
+
+// Sizes of the Lua state of a sandbox: the call stack bounds the depth of recursion and the
+// registry, holding the values of the stack, may grow up to `luaRegistryMaxSize` values.
+const (
+	luaCallStackSize   = 256
+	luaRegistryMaxSize = 256 * 1024
+)
+
+// sandbox is a Lua state with only the `math`, `string` and `table` libraries and the safe
+// functions of the base library, running under a context that is cancelled when the script
+// exceeds its time budget or its memory limit.
+type sandbox struct {
+	*lua.LState
+	ctx    context.Context
+	cancel context.CancelCauseFunc // interrupts the script with a cause
+	stop   context.CancelFunc      // releases the timer of the time budget
+	memory *memoryLimit            // memory used by the script, nil without a memory limit
+}
+
+// newSandbox creates a sandbox whose `math.random` generator is seeded with the given seed,
+// see `newLuaState`. The sandbox must be closed once the script is no longer needed.
+func newSandbox(ctx context.Context, seed int64, limits Limits) *sandbox {
+	ctx, cancel := context.WithCancelCause(ctx)
+	stop := context.CancelFunc(func() {})
+	if limits.Timeout > 0 {
+		ctx, stop = context.WithTimeoutCause(ctx, limits.Timeout,
+			fmt.Errorf("%w of %s", ErrTimeBudget, limits.Timeout))
+	}
+
+	sb := &sandbox{LState: newLuaState(seed), ctx: ctx, cancel: cancel, stop: stop}
+	sb.SetContext(ctx)
+	if limits.Memory > 0 {
+		sb.limitMemory(limits.Memory)
+	}
+	return sb
+}
+
+// Close releases the Lua state and stops watching its resources.
+func (sb *sandbox) Close() {
+	sb.stop()
+	sb.cancel(nil)
+	sb.LState.Close()
+}
+
+// interrupted returns the reason the script was interrupted, or nil if it was not.
+// The error raised in the script only states that its context is done.
+func (sb *sandbox) interrupted() error {
+	return context.Cause(sb.ctx)
+}
+
+// openLibs opens `luaLibs` in the Lua state and removes `unsafeBaseFunctions`.
+func openLibs(lState *lua.LState) {
+	for _, lib := range luaLibs {
+		lState.Push(lState.NewFunction(lib.open))
+		lState.Push(lua.LString(lib.name))
+		lState.Call(1, 0)
+	}
+	for _, name := range unsafeBaseFunctions {
+		lState.SetGlobal(name, lua.LNil)
+	}
+}
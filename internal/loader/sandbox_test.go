@@ -0,0 +1,174 @@
+package loader_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lekomish/tis-100/internal/loader"
+)
+
+/* TESTS */
+
+// --- Sandbox ---
+func TestLoadPuzzleWithoutUnsafeFunctions(t *testing.T) {
+	tests := []struct {
+		name string
+		call string
+	}{
+		{name: "io", call: "io.open(\"puzzle.lua\")"},
+		{name: "os", call: "os.remove(\"puzzle.lua\")"},
+		{name: "dofile", call: "dofile(\"puzzle.lua\")"},
+		{name: "loadstring", call: "loadstring(\"return 1\")"},
+		{name: "require", call: "require(\"os\")"},
+		{name: "print", call: "print(\"TEST\")"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newScript()
+			s.Title = []string{"function GetTitle()", tt.call, "return \"TEST\"", "end"}
+			filePath, err := setupLua(t, s, "test_load_puzzle_without_unsafe_functions.lua")
+			require.NoError(t, err, errCreatingFileMsg)
+
+			_, err = loader.LoadPuzzle(filePath)
+			require.ErrorContains(t, err, "error while calling \"GetTitle\" function")
+		})
+	}
+}
+
+func TestLoadPuzzleWithSafeFunctions(t *testing.T) {
+	s := newScript()
+	s.Title = []string{
+		"function GetTitle()",
+		"local words = {}",
+		"for _, word in ipairs({ \"SAFE\", \"TEST\" }) do table.insert(words, string.lower(word)) end",
+		"return string.upper(table.concat(words, \" \")) .. tostring(math.max(1, 2))",
+		"end",
+	}
+	filePath, err := setupLua(t, s, "test_load_puzzle_with_safe_functions.lua")
+	require.NoError(t, err, errCreatingFileMsg)
+
+	puzzle, err := loader.LoadPuzzle(filePath)
+	require.NoError(t, err)
+	require.Equal(t, "SAFE TEST2", puzzle.Title)
+}
+
+// --- LoadPuzzleContext ---
+func TestLoadPuzzleContextTimeBudget(t *testing.T) {
+	tests := []struct {
+		name   string
+		script *script
+	}{
+		{name: "script", script: newScript()},
+		{name: "function", script: newScript()},
+	}
+	tests[0].script.Beginning = append(tests[0].script.Beginning, "while true do end")
+	tests[1].script.Streams = []string{"function GetStreams()", "while true do end", "end"}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filePath, err := setupLua(t, tt.script, "test_load_puzzle_context_time_budget.lua")
+			require.NoError(t, err, errCreatingFileMsg)
+
+			_, err = loader.LoadPuzzleContext(context.Background(), filePath, 0, loader.Limits{Timeout: 50 * time.Millisecond})
+			require.ErrorIs(t, err, loader.ErrTimeBudget)
+			require.ErrorContains(t, err, "lua script exceeded its time budget of 50ms")
+		})
+	}
+}
+
+func TestLoadPuzzleContextCancelled(t *testing.T) {
+	filePath, err := setupLua(t, newScript(), "test_load_puzzle_context_cancelled.lua")
+	require.NoError(t, err, errCreatingFileMsg)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = loader.LoadPuzzleContext(ctx, filePath, 0, loader.DefaultLimits)
+	require.ErrorIs(t, err, context.Canceled)
+}
+
+func TestLoadPuzzleContextMemoryLimit(t *testing.T) {
+	tests := []struct {
+		name string
+		body []string
+	}{
+		{name: "table", body: []string{"local t = {}", "for i = 1, 1e9 do t[i] = { i } end"}},
+		{name: "string.rep", body: []string{"local s = string.rep(\"TEST\", 1e9)"}},
+		{name: "table.concat", body: []string{"local t = {}", "for i = 1, 1e4 do t[i] = string.rep(\"TEST\", 1e3) end", "for i = 1, 1e3 do table.concat(t) end"}},
+		{name: "string.format", body: []string{"local t, s = {}, string.rep(\"TEST\", 1e3)", "for i = 1, 1e6 do t[i] = string.format(\"%s%s\", s, s) end"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newScript()
+			s.Title = append(append([]string{"function GetTitle()"}, tt.body...), "return \"TEST\"", "end")
+			filePath, err := setupLua(t, s, "test_load_puzzle_context_memory_limit.lua")
+			require.NoError(t, err, errCreatingFileMsg)
+
+			limits := loader.Limits{Timeout: time.Minute, Memory: 16 << 20}
+			_, err = loader.LoadPuzzleContext(context.Background(), filePath, 0, limits)
+			require.ErrorIs(t, err, loader.ErrMemoryLimit)
+			require.ErrorContains(t, err, "lua script exceeded its memory limit of 16 MiB")
+		})
+	}
+}
+
+func TestLoadPuzzleContextMemoryLimitIsDeterministic(t *testing.T) {
+	s := newScript()
+	s.Title = []string{
+		"function GetTitle()",
+		"local t = {}",
+		"for i = 1, 200000 do t[i] = i end",
+		"return \"TEST\"",
+		"end",
+	}
+	filePath, err := setupLua(t, s, "test_load_puzzle_context_memory_limit_is_deterministic.lua")
+	require.NoError(t, err, errCreatingFileMsg)
+
+	// the table takes about 6 MiB, far below the growth of the heap the backstop watches
+	for range 10 {
+		_, err = loader.LoadPuzzleContext(context.Background(), filePath, 0, loader.Limits{Memory: 16 << 20})
+		require.NoError(t, err)
+		_, err = loader.LoadPuzzleContext(context.Background(), filePath, 0, loader.Limits{Memory: 4 << 20})
+		require.ErrorIs(t, err, loader.ErrMemoryLimit)
+	}
+}
+
+func TestLoadPuzzleContextMemoryOfGarbage(t *testing.T) {
+	s := newScript()
+	s.Title = []string{
+		"function GetTitle()",
+		"for i = 1, 1000 do local t = {} for j = 1, 1000 do t[j] = j end end",
+		"return \"TEST\"",
+		"end",
+	}
+	filePath, err := setupLua(t, s, "test_load_puzzle_context_memory_of_garbage.lua")
+	require.NoError(t, err, errCreatingFileMsg)
+
+	_, err = loader.LoadPuzzleContext(context.Background(), filePath, 0, loader.Limits{Memory: 4 << 20})
+	require.NoError(t, err, "tables no longer reachable do not count")
+}
+
+func TestLoadPuzzleContextStackOverflow(t *testing.T) {
+	s := newScript()
+	s.Beginning = append(s.Beginning, "local function f() return 1 + f() end", "f()")
+	filePath, err := setupLua(t, s, "test_load_puzzle_context_stack_overflow.lua")
+	require.NoError(t, err, errCreatingFileMsg)
+
+	_, err = loader.LoadPuzzleContext(context.Background(), filePath, 0, loader.DefaultLimits)
+	require.ErrorContains(t, err, "stack overflow")
+}
+
+// newSandbox -> covered in previous tests
+// Close -> covered in previous tests
+// interrupted -> covered in previous tests
+// limitMemory -> covered in previous tests
+// Done -> covered in previous tests
+// allocate -> covered in previous tests
+// countInstruction -> covered in previous tests
+// measure -> covered in previous tests
+// watchHeap -> covered in previous tests
+// repSize -> covered in previous tests
+// concatSize -> covered in previous tests
+// openLibs -> covered in previous tests
@@ -2,11 +2,18 @@ package model
 
 const (
 	// MaxACC and MinACC define the allowed value range for the accumulator.
-	MaxACC                = 999
-	MinACC                = -999
-	StreamTypesNumber     = 2  // Defines how many stream types exist (e.g., INPUT, OUTPUT).
-	IOPositionsNumber     = 4  // Defines how many input/output positions exist.
-	MaxStreamValuesLength = 30 // Defines the maximum number of values a stream can hold.
-	NodesNumber           = 12 // Defines the total number of nodes in the puzzle grid.
-	NodeTypesNumber       = 2  // Defines how many node types exist (e.g., COMPUTE, DAMAGED)
+	MaxACC                        = 999
+	MinACC                        = -999
+	StreamTypesNumber             = 3  // Defines how many stream types exist (e.g., INPUT, OUTPUT, IMAGE).
+	IOPositionsNumber             = 4  // Defines how many input/output positions exist.
+	MaxStreamValuesLength         = 30 // Defines the maximum number of values a stream can hold.
+	MaxOriginalStreamValuesLength = 39 // Defines the maximum number of values a stream of the original game's puzzle files can hold.
+	NodesNumber                   = 12 // Defines the total number of nodes in the puzzle grid.
+	NodeTypesNumber               = 3  // Defines how many node types exist (e.g., COMPUTE, DAMAGED, MEMORY)
+	MaxCodeLines                  = 15 // Defines the maximum number of source lines of a node on the original hardware.
+	MaxLineLength                 = 18 // Defines the maximum number of characters of a source line on the original hardware.
+	MemoryCapacity                = 15 // Defines the maximum number of values a stack memory node can hold.
+	ImageWidth                    = 30 // Defines the number of pixel columns of an image stream.
+	ImageHeight                   = 18 // Defines the number of pixel rows of an image stream.
+	ImageColours                  = 5  // Defines how many colours an image pixel can have (black, dark grey, light grey, white, red).
 )
@@ -1,9 +1,37 @@
 package model
 
 // Puzzle defines a playable TIS-100 puzzle, including metadata, streams, and layout.
+// Puzzles accepting more than one output sequence define a `Check` of their checked streams.
 type Puzzle struct {
 	Title       string
 	Description []string
 	Streams     []*Stream
 	Layout      []NodeType
+	Check       OutputCheck
+}
+
+// OutputCheck decides whether the values written to the output streams at the end of a run
+// solve the puzzle. Inputs and outputs are keyed by stream name.
+type OutputCheck func(inputs, outputs map[string][]int16) ([]Verdict, error)
+
+// Verdict is the decision of an output check.
+type Verdict struct {
+	Stream  string // name of the checked stream, empty for a check of all the outputs
+	Passed  bool   // the outputs solve the puzzle
+	Message string // explanation given by the check, if any
+}
+
+// String explains the verdict, e.g. "OUT: check failed: message".
+func (v *Verdict) String() string {
+	msg := "check failed"
+	if v.Passed {
+		msg = "check passed"
+	}
+	if v.Stream != "" {
+		msg = v.Stream + ": " + msg
+	}
+	if v.Message != "" {
+		msg += ": " + v.Message
+	}
+	return msg
 }
@@ -2,11 +2,15 @@ package model
 
 // Stream represents either an input or output stream in a puzzle,
 // including its type, name, position, and values.
+// The values of an image stream are the colours of its pixels, row by row.
+// The values of a checked output stream are one valid output, the values written to it being
+// decided by the check of the puzzle (see `Puzzle.Check`) instead of compared one by one.
 type Stream struct {
 	Type     StreamType
 	Name     string
 	Position uint8
 	Values   []int16
+	Checked  bool
 }
 
 // Len returns the number of values in the stream.
@@ -1,7 +1,7 @@
 package model
 
 type (
-	// StreamType defines whether a stream is an input or output.
+	// StreamType defines whether a stream is an input, an output or an image output.
 	StreamType uint8
 	// NodeType defines the type of a node used in the puzzle layout.
 	NodeType uint8
@@ -12,6 +12,8 @@ const (
 	INPUT StreamType = iota
 	// OUTPUT represents an output stream.
 	OUTPUT
+	// IMAGE represents an output stream drawn on a display of `ImageWidth` by `ImageHeight` pixels.
+	IMAGE
 )
 
 const (
@@ -19,4 +21,6 @@ const (
 	COMPUTE NodeType = iota
 	// DAMAGED represents a broken or unusable node.
 	DAMAGED
+	// MEMORY represents a stack memory node (T30) holding values written by its neighbours.
+	MEMORY
 )
@@ -0,0 +1,134 @@
+// Package profile collects utilisation statistics of the grid nodes during a run,
+// to find out which node holds a solution back.
+package profile
+
+import (
+	"github.com/lekomish/tis-100/internal/engine"
+	"github.com/lekomish/tis-100/internal/model"
+)
+
+// NodeStats are the statistics of a single grid node. Every cycle of the run is counted
+// in exactly one of `Executing`, `BlockedRead`, `BlockedWrite` and `Idle`.
+type NodeStats struct {
+	Executing    int    // cycles in which the node completed an instruction
+	BlockedRead  int    // cycles spent waiting for a value to read
+	BlockedWrite int    // cycles spent waiting for a written value to be read
+	Idle         int    // cycles of a node without instructions
+	Lines        []int  // number of executions of every source line
+	Sent         [4]int // values sent per port, indexed by `engine.PortUp` to `engine.PortRight`
+}
+
+// Active reports whether the node has instructions.
+func (s *NodeStats) Active() bool {
+	return s.Executing+s.BlockedRead+s.BlockedWrite > 0
+}
+
+// Utilisation returns the share of cycles in which the node completed an instruction.
+func (s *NodeStats) Utilisation() float64 {
+	total := s.Executing + s.BlockedRead + s.BlockedWrite + s.Idle
+	if total == 0 {
+		return 0
+	}
+	return float64(s.Executing) / float64(total)
+}
+
+// Stats are the statistics of a run.
+type Stats struct {
+	Cycles int                          // number of cycles observed
+	Nodes  [model.NodesNumber]NodeStats // statistics of the grid nodes in grid order
+}
+
+// Busiest returns the grid index of the active node that completed the most instructions,
+// or -1 if no node is active.
+func (s *Stats) Busiest() int {
+	busiest := -1
+	for i := range s.Nodes {
+		if s.Nodes[i].Active() && (busiest == -1 || s.Nodes[i].Executing > s.Nodes[busiest].Executing) {
+			busiest = i
+		}
+	}
+	return busiest
+}
+
+// MaxLine returns the highest number of executions of a single source line over all nodes.
+func (s *Stats) MaxLine() int {
+	highest := 0
+	for i := range s.Nodes {
+		for _, count := range s.Nodes[i].Lines {
+			highest = max(highest, count)
+		}
+	}
+	return highest
+}
+
+// Profiler is an observer collecting the statistics of the grid nodes of an engine.
+type Profiler struct {
+	engine.NopObserver
+
+	eng      *engine.Engine
+	stats    Stats
+	executed [model.NodesNumber]bool // nodes which completed an instruction in the current cycle
+}
+
+// NewProfiler creates a profiler and attaches it to the engine.
+func NewProfiler(eng *engine.Engine) *Profiler {
+	p := &Profiler{eng: eng}
+	for i, n := range eng.Nodes {
+		lines := 0
+		for _, ins := range n.Instructions {
+			lines = max(lines, ins.Line+1)
+		}
+		p.stats.Nodes[i].Lines = make([]int, lines)
+	}
+	eng.AddObserver(p)
+	return p
+}
+
+// Stats returns the statistics collected so far.
+func (p *Profiler) Stats() *Stats {
+	return &p.stats
+}
+
+// InstructionExecuted implements `engine.Observer`.
+func (p *Profiler) InstructionExecuted(n *engine.Node, ins *engine.Instruction) {
+	if i, ok := p.gridIndex(n); ok {
+		p.executed[i] = true
+		p.stats.Nodes[i].Lines[ins.Line]++
+	}
+}
+
+// ValueTransferred implements `engine.Observer`.
+func (p *Profiler) ValueTransferred(from, _ *engine.Node, port engine.Port, _ int16) {
+	if i, ok := p.gridIndex(from); ok && int(port) < len(p.stats.Nodes[i].Sent) {
+		p.stats.Nodes[i].Sent[port]++
+	}
+}
+
+// CycleFinished implements `engine.Observer`: it classifies the cycle of every node.
+func (p *Profiler) CycleFinished(int) {
+	p.stats.Cycles++
+	for i, n := range p.eng.Nodes {
+		s := &p.stats.Nodes[i]
+		switch {
+		case len(n.Instructions) == 0:
+			s.Idle++
+		case p.executed[i]:
+			s.Executing++
+		case n.IsWriting:
+			s.BlockedWrite++
+		default:
+			s.BlockedRead++
+		}
+	}
+	p.executed = [model.NodesNumber]bool{}
+}
+
+// gridIndex returns the grid index of the node, which is not found for stream nodes.
+func (p *Profiler) gridIndex(n *engine.Node) (int, bool) {
+	for i, node := range p.eng.Nodes {
+		if node == n {
+			return i, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,89 @@
+package profile_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lekomish/tis-100/internal/engine"
+	"github.com/lekomish/tis-100/internal/model"
+	"github.com/lekomish/tis-100/internal/profile"
+)
+
+/* TESTS */
+
+// --- NewProfiler ---
+func TestProfilerPipe(t *testing.T) {
+	eng := newEngine(t, map[int][]string{0: {"MOV UP ACC", "ADD 1", "MOV ACC DOWN"}, 4: {"MOV UP DOWN"}, 8: {"MOV UP DOWN"}})
+	p := profile.NewProfiler(eng)
+
+	res := eng.Run(engine.RunOptions{})
+	require.Equal(t, engine.Completed, res.Reason)
+
+	stats := p.Stats()
+	require.Equal(t, res.Cycles, stats.Cycles)
+	for i := range stats.Nodes {
+		s := stats.Nodes[i]
+		require.Equal(t, stats.Cycles, s.Executing+s.BlockedRead+s.BlockedWrite+s.Idle, "node %d", i)
+	}
+
+	first := stats.Nodes[0]
+	require.Equal(t, []int{2, 2, 2}, first.Lines)
+	require.Equal(t, 6, first.Executing)
+	require.Equal(t, [4]int{0, 2, 0, 0}, first.Sent)
+	require.Positive(t, first.BlockedRead)
+	require.Positive(t, first.BlockedWrite)
+
+	second := stats.Nodes[4]
+	require.Equal(t, []int{2}, second.Lines)
+	require.Equal(t, [4]int{0, 2, 0, 0}, second.Sent)
+
+	idle := stats.Nodes[1]
+	require.False(t, idle.Active())
+	require.Equal(t, stats.Cycles, idle.Idle)
+	require.Empty(t, idle.Lines)
+	require.Zero(t, idle.Utilisation())
+
+	require.Equal(t, 0, stats.Busiest())
+	require.Equal(t, 2, stats.MaxLine())
+	require.InDelta(t, float64(first.Executing)/float64(stats.Cycles), first.Utilisation(), 1e-9)
+}
+
+func TestProfilerWithoutCode(t *testing.T) {
+	eng := newEngine(t, nil)
+	p := profile.NewProfiler(eng)
+	eng.Run(engine.RunOptions{MaxCycles: 3})
+
+	require.Equal(t, -1, p.Stats().Busiest())
+	require.Zero(t, p.Stats().MaxLine())
+}
+
+// Stats -> covered in previous tests
+// InstructionExecuted -> covered in previous tests
+// ValueTransferred -> covered in previous tests
+// CycleFinished -> covered in previous tests
+// gridIndex -> covered in previous tests
+// Active -> covered in previous tests
+// Utilisation -> covered in previous tests
+// Busiest -> covered in previous tests
+// MaxLine -> covered in previous tests
+
+/* UTILS */
+
+// newEngine creates an engine with the given code per node index, reading the values 1 and 2
+// above the first node and expecting them incremented below the first column.
+func newEngine(tb testing.TB, nodes map[int][]string) *engine.Engine {
+	tb.Helper()
+
+	code := &model.Code{Title: "PROFILE", Nodes: make([][]string, model.NodesNumber)}
+	for i, lines := range nodes {
+		code.Nodes[i] = lines
+	}
+	streams := []*model.Stream{
+		{Type: model.INPUT, Position: 0, Values: []int16{1, 2}},
+		{Type: model.OUTPUT, Position: 0, Values: []int16{2, 3}},
+	}
+	eng, err := engine.NewEngine(streams, nil, code)
+	require.NoError(tb, err)
+	return eng
+}
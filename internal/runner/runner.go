@@ -0,0 +1,150 @@
+// Package runner executes a solution against several test sets of a puzzle.
+// Test sets are generated by loading the puzzle script with different seeds,
+// so solutions that pass only for lucky stream values are caught.
+package runner
+
+import (
+	"math/rand"
+
+	"github.com/lekomish/tis-100/internal/engine"
+	"github.com/lekomish/tis-100/internal/loader"
+	"github.com/lekomish/tis-100/internal/model"
+)
+
+// DefaultTests is the number of fixed test sets, as in the original game.
+const DefaultTests = 3
+
+// Options configures `Run`.
+type Options struct {
+	Seed      int64       // seed of the first fixed test set
+	Tests     int         // number of fixed test sets, seeded with `Seed`, `Seed+1`, ...
+	Random    int         // number of additional validation sets with random seeds
+	MaxCycles int         // cycle limit of every run (0 means `engine.DefaultMaxCycles`)
+	Mode      engine.Mode // limits the code is compiled with
+
+	StopOnFirstWrong bool // stop every run as soon as an output receives a wrong value
+	DetectLivelock   bool // stop every run as soon as the machine state repeats
+	LivelockInterval int  // number of cycles between two recorded states (0 means every cycle)
+}
+
+// TestRun describes the execution of a solution against a single test set.
+type TestRun struct {
+	Seed   int64          // seed the test set was generated with
+	Puzzle *model.Puzzle  // the test set: the puzzle loaded with `Seed`
+	Engine *engine.Engine // the engine in its state at the end of the run
+	Result *engine.Result // outcome of the run
+
+	Verdicts []model.Verdict // decisions of the check of the puzzle, if it has one and the run completed
+}
+
+// Run loads the test sets of the puzzle and runs the code against each of them.
+// When the puzzle checks its outputs (see `model.Puzzle.Check`), a completed run is checked
+// and ends with a mismatch if a check fails.
+// It returns an error if a test set cannot be loaded, the code cannot be loaded into an engine
+// or the outputs cannot be checked.
+func Run(puzzlePath string, code *model.Code, opts Options) ([]*TestRun, error) {
+	seeds := make([]int64, 0, opts.Tests+opts.Random)
+	for i := range opts.Tests {
+		seeds = append(seeds, opts.Seed+int64(i))
+	}
+	for range opts.Random {
+		seeds = append(seeds, rand.Int63())
+	}
+
+	runs := make([]*TestRun, 0, len(seeds))
+	for _, seed := range seeds {
+		puzzle, err := loader.LoadPuzzleWithSeed(puzzlePath, seed)
+		if err != nil {
+			return nil, err
+		}
+		eng, err := engine.NewEngineWithMode(puzzle.Streams, puzzle.Layout, code, opts.Mode)
+		if err != nil {
+			return nil, err
+		}
+
+		run := &TestRun{
+			Seed:   seed,
+			Puzzle: puzzle,
+			Engine: eng,
+			Result: eng.Run(engine.RunOptions{
+				MaxCycles:        opts.MaxCycles,
+				StopOnFirstWrong: opts.StopOnFirstWrong,
+				DetectLivelock:   opts.DetectLivelock,
+				LivelockInterval: opts.LivelockInterval,
+			}),
+		}
+		if puzzle.Check != nil && run.Result.Passed() {
+			if err := run.check(); err != nil {
+				return nil, err
+			}
+		}
+		runs = append(runs, run)
+	}
+
+	return runs, nil
+}
+
+// check calls the check of the puzzle with the inputs and the outputs of the run.
+// The run becomes a mismatch if any verdict fails.
+func (r *TestRun) check() error {
+	verdicts, err := Check(r.Puzzle, r.Engine)
+	if err != nil {
+		return err
+	}
+	r.Verdicts = verdicts
+	if r.FailedCheck() != nil {
+		r.Result.Reason = engine.Mismatch
+	}
+	return nil
+}
+
+// FailedCheck returns the first verdict of the check of the puzzle that failed,
+// or nil if every verdict passed.
+func (r *TestRun) FailedCheck() *model.Verdict {
+	return FailedVerdict(r.Verdicts)
+}
+
+// Check calls the check of the puzzle (see `model.Puzzle.Check`) with its inputs
+// and the outputs of the engine, which must have run the puzzle.
+func Check(puzzle *model.Puzzle, eng *engine.Engine) ([]model.Verdict, error) {
+	inputs := make(map[string][]int16)
+	for _, stream := range puzzle.Streams {
+		if stream.Type == model.INPUT {
+			inputs[stream.Name] = stream.Values
+		}
+	}
+	outputs := make(map[string][]int16)
+	for _, c := range eng.CompareOutputs() {
+		outputs[c.Stream] = c.Actual
+	}
+	return puzzle.Check(inputs, outputs)
+}
+
+// FailedVerdict returns the first verdict that failed, or nil if every verdict passed.
+func FailedVerdict(verdicts []model.Verdict) *model.Verdict {
+	for i := range verdicts {
+		if !verdicts[i].Passed {
+			return &verdicts[i]
+		}
+	}
+	return nil
+}
+
+// Passed reports whether every test run passed.
+func Passed(runs []*TestRun) bool {
+	for _, run := range runs {
+		if !run.Result.Passed() {
+			return false
+		}
+	}
+	return true
+}
+
+// Results returns the results of the test runs in order, e.g. to be scored.
+func Results(runs []*TestRun) []*engine.Result {
+	results := make([]*engine.Result, 0, len(runs))
+	for _, run := range runs {
+		results = append(results, run.Result)
+	}
+	return results
+}
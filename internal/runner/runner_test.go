@@ -0,0 +1,108 @@
+package runner_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lekomish/tis-100/internal/engine"
+	"github.com/lekomish/tis-100/internal/loader"
+	"github.com/lekomish/tis-100/internal/model"
+	"github.com/lekomish/tis-100/internal/runner"
+)
+
+const (
+	templatePuzzle   = "../../puzzles/template.lua"
+	selfTestPuzzle   = "../../puzzles/self-test diagnostic.lua"
+	selfTestSolution = "../../puzzles/self-test diagnostic.tis"
+	checkedPuzzle    = "../../puzzles/unordered echo.lua"
+	checkedSolution  = "../../puzzles/unordered echo.tis"
+)
+
+/* TESTS */
+
+// --- Run ---
+func TestRunAllTestSets(t *testing.T) {
+	code, err := loader.LoadCode(selfTestSolution)
+	require.NoError(t, err)
+
+	runs, err := runner.Run(selfTestPuzzle, code, runner.Options{Seed: 5, Tests: 3, Random: 2})
+	require.NoError(t, err)
+	require.Len(t, runs, 5)
+	require.True(t, runner.Passed(runs))
+
+	for i, run := range runs[:3] {
+		require.Equal(t, int64(5+i), run.Seed)
+		expected, err := loader.LoadPuzzleWithSeed(selfTestPuzzle, run.Seed)
+		require.NoError(t, err)
+		require.Equal(t, expected, run.Puzzle)
+	}
+	require.NotEqual(t, runs[0].Puzzle.Streams, runs[1].Puzzle.Streams)
+
+	results := runner.Results(runs)
+	require.Len(t, results, 5)
+	for i, res := range results {
+		require.Same(t, runs[i].Result, res)
+	}
+}
+
+func TestRunFailingSolution(t *testing.T) {
+	code, err := loader.LoadCode(selfTestSolution)
+	require.NoError(t, err)
+
+	runs, err := runner.Run(templatePuzzle, code, runner.Options{Tests: 2, MaxCycles: 500})
+	require.NoError(t, err)
+	require.Len(t, runs, 2)
+	require.False(t, runner.Passed(runs))
+	require.NotEqual(t, engine.Completed, runs[0].Result.Reason)
+}
+
+func TestRunWithMissingPuzzle(t *testing.T) {
+	code, err := loader.LoadCode(selfTestSolution)
+	require.NoError(t, err)
+
+	_, err = runner.Run("/notexisting.lua", code, runner.Options{Tests: 1})
+	require.ErrorContains(t, err, "unable to load lua script")
+}
+
+func TestRunCheckedPuzzle(t *testing.T) {
+	code, err := loader.LoadCode(checkedSolution)
+	require.NoError(t, err)
+
+	runs, err := runner.Run(checkedPuzzle, code, runner.Options{Tests: 2})
+	require.NoError(t, err)
+	require.True(t, runner.Passed(runs))
+	for _, run := range runs {
+		require.Equal(t, []model.Verdict{{Stream: "OUT", Passed: true}}, run.Verdicts)
+		require.Nil(t, run.FailedCheck())
+	}
+
+	code.Nodes[9] = []string{"MOV UP ACC", "ADD 1", "MOV ACC DOWN"}
+	runs, err = runner.Run(checkedPuzzle, code, runner.Options{Tests: 1})
+	require.NoError(t, err)
+	require.False(t, runner.Passed(runs))
+	require.Equal(t, engine.Mismatch, runs[0].Result.Reason)
+	require.Equal(t, &model.Verdict{Stream: "OUT", Message: "OUT does not hold the values of IN"}, runs[0].FailedCheck())
+}
+
+func TestRunWithMode(t *testing.T) {
+	code, err := loader.LoadCode(selfTestSolution)
+	require.NoError(t, err)
+	code.Nodes[0] = append([]string{"ADD 1000", "SUB 1000"}, code.Nodes[0]...)
+
+	_, err = runner.Run(selfTestPuzzle, code, runner.Options{Tests: 1, Mode: engine.ModeStrict})
+	require.ErrorContains(t, err, "value 1000 is out of range -999..999")
+
+	runs, err := runner.Run(selfTestPuzzle, code, runner.Options{Tests: 1, Mode: engine.ModeSandbox})
+	require.NoError(t, err)
+	require.Len(t, runs, 1)
+}
+
+func TestRunWithCodeInDamagedNode(t *testing.T) {
+	code, err := loader.LoadCode(selfTestSolution)
+	require.NoError(t, err)
+	code.Nodes[1] = []string{"NOP"}
+
+	_, err = runner.Run(selfTestPuzzle, code, runner.Options{Tests: 1})
+	require.ErrorContains(t, err, "node @2 is damaged")
+}
@@ -0,0 +1,78 @@
+// Package score computes the metrics the original TIS-100 uses to rank solutions:
+// the number of cycles a solution needs, the number of nodes it uses and
+// the number of instructions it consists of.
+package score
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/lekomish/tis-100/internal/engine"
+	"github.com/lekomish/tis-100/internal/model"
+)
+
+// Aggregation defines how the cycles of several test runs are combined into one value.
+type Aggregation uint8
+
+// Supported Aggregation values.
+const (
+	Average Aggregation = iota // average of the runs, rounded up
+	Maximum                    // slowest of the runs
+)
+
+// aggregationNames maps the names accepted by `ParseAggregation` to Aggregation values.
+var aggregationNames = map[string]Aggregation{
+	"average": Average,
+	"max":     Maximum,
+}
+
+// ParseAggregation converts a name ("average" or "max") into an Aggregation.
+func ParseAggregation(name string) (Aggregation, error) {
+	agg, ok := aggregationNames[name]
+	if !ok {
+		return 0, fmt.Errorf("unknown aggregation: %q", name)
+	}
+	return agg, nil
+}
+
+// Score holds the metrics of a solution.
+type Score struct {
+	Cycles       int `json:"cycles"`       // cycles needed to pass, aggregated over the test runs
+	Nodes        int `json:"nodes"`        // number of nodes holding at least one instruction
+	Instructions int `json:"instructions"` // number of compiled instructions, label-only lines excluded
+}
+
+// Compute returns the score of the given code based on its finished runs.
+// Every run must have passed, since failing solutions have no score.
+func Compute(code *model.Code, results []*engine.Result, agg Aggregation) (*Score, error) {
+	if len(results) == 0 {
+		return nil, errors.New("no runs to score")
+	}
+
+	compiled, err := engine.Compile(code)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Score{}
+	for _, instructions := range compiled {
+		if len(instructions) > 0 {
+			s.Nodes++
+			s.Instructions += len(instructions)
+		}
+	}
+
+	total := 0
+	for i, res := range results {
+		if !res.Passed() {
+			return nil, fmt.Errorf("run %d did not pass: %s", i+1, res.Reason)
+		}
+		total += res.Cycles
+		s.Cycles = max(s.Cycles, res.Cycles)
+	}
+	if agg == Average {
+		s.Cycles = (total + len(results) - 1) / len(results)
+	}
+
+	return s, nil
+}
@@ -0,0 +1,87 @@
+package score_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lekomish/tis-100/internal/engine"
+	"github.com/lekomish/tis-100/internal/model"
+	"github.com/lekomish/tis-100/internal/score"
+)
+
+/* TESTS */
+
+// --- Compute ---
+func TestComputeAverage(t *testing.T) {
+	results := []*engine.Result{
+		{Reason: engine.Completed, Cycles: 10},
+		{Reason: engine.Completed, Cycles: 11},
+	}
+
+	s, err := score.Compute(newCode(), results, score.Average)
+	require.NoError(t, err)
+	require.Equal(t, &score.Score{Cycles: 11, Nodes: 2, Instructions: 4}, s)
+}
+
+func TestComputeMaximum(t *testing.T) {
+	results := []*engine.Result{
+		{Reason: engine.Completed, Cycles: 10},
+		{Reason: engine.Completed, Cycles: 14},
+		{Reason: engine.Completed, Cycles: 12},
+	}
+
+	s, err := score.Compute(newCode(), results, score.Maximum)
+	require.NoError(t, err)
+	require.Equal(t, 14, s.Cycles)
+}
+
+func TestComputeWithFailedRun(t *testing.T) {
+	results := []*engine.Result{
+		{Reason: engine.Completed, Cycles: 10},
+		{Reason: engine.Deadlock, Cycles: 3},
+	}
+
+	_, err := score.Compute(newCode(), results, score.Average)
+	require.ErrorContains(t, err, "run 2 did not pass: deadlock")
+}
+
+func TestComputeWithoutRuns(t *testing.T) {
+	_, err := score.Compute(newCode(), nil, score.Average)
+	require.ErrorContains(t, err, "no runs to score")
+}
+
+func TestComputeWithInvalidCode(t *testing.T) {
+	code := newCode()
+	code.Nodes[3] = []string{"FOO"}
+	results := []*engine.Result{{Reason: engine.Completed, Cycles: 10}}
+
+	_, err := score.Compute(code, results, score.Average)
+	require.ErrorContains(t, err, "invalid instruction")
+}
+
+// --- ParseAggregation ---
+func TestParseAggregation(t *testing.T) {
+	agg, err := score.ParseAggregation("average")
+	require.NoError(t, err)
+	require.Equal(t, score.Average, agg)
+
+	agg, err = score.ParseAggregation("max")
+	require.NoError(t, err)
+	require.Equal(t, score.Maximum, agg)
+
+	_, err = score.ParseAggregation("min")
+	require.ErrorContains(t, err, "unknown aggregation: \"min\"")
+}
+
+/* UTILS */
+
+// newCode returns a code using two nodes with four instructions in total,
+// including label-only lines and blank lines that must not be counted.
+func newCode() *model.Code {
+	nodes := make([][]string, model.NodesNumber)
+	nodes[0] = []string{"START:", "MOV UP ACC", "ADD ACC", "MOV ACC DOWN", ""}
+	nodes[4] = []string{"MOV UP DOWN"}
+	nodes[8] = []string{"  ", "EMPTY:"}
+	return &model.Code{Title: "TEST", Nodes: nodes}
+}
@@ -0,0 +1,137 @@
+package trace
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/lekomish/tis-100/internal/engine"
+)
+
+// checkpointInterval is the number of cycles between two states kept by `Read`.
+const checkpointInterval = engine.DefaultHistoryInterval
+
+// Trace is a trace file read back into memory.
+type Trace struct {
+	Header Header
+	Cycles []Cycle            // one entry per executed cycle, starting with cycle 1
+	Result *Result            // outcome of the run, nil if the trace was cut short
+	states []*engine.Snapshot // machine state every `checkpointInterval` cycles
+}
+
+// Read reads a trace file written by `Recorder`.
+func Read(r io.Reader) (*Trace, error) {
+	dec := json.NewDecoder(r)
+
+	t := &Trace{}
+	if err := dec.Decode(&t.Header); err != nil {
+		return nil, fmt.Errorf("invalid trace header: %w", err)
+	}
+	if t.Header.Version != Version {
+		return nil, fmt.Errorf("unsupported trace version %d", t.Header.Version)
+	}
+	if t.Header.Initial == nil {
+		return nil, errors.New("trace has no initial state")
+	}
+
+	state := t.Header.Initial
+	t.states = append(t.states, state)
+	for {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("invalid trace line: %w", err)
+		}
+		if t.Result != nil {
+			return nil, errors.New("trace continues after its result")
+		}
+
+		var f footer
+		if err := json.Unmarshal(raw, &f); err != nil {
+			return nil, fmt.Errorf("invalid trace line: %w", err)
+		}
+		if f.Result != nil {
+			t.Result = f.Result
+			continue
+		}
+
+		var c Cycle
+		if err := json.Unmarshal(raw, &c); err != nil {
+			return nil, fmt.Errorf("invalid trace line: %w", err)
+		}
+		if c.Cycle != state.Cycle+1 {
+			return nil, fmt.Errorf("expected cycle %d, got %d", state.Cycle+1, c.Cycle)
+		}
+		next, err := apply(state, &c)
+		if err != nil {
+			return nil, fmt.Errorf("cycle %d: %w", c.Cycle, err)
+		}
+		t.Cycles = append(t.Cycles, c)
+		if next.Cycle%checkpointInterval == 0 {
+			t.states = append(t.states, next)
+		}
+		state = next
+	}
+	return t, nil
+}
+
+// Len returns the number of recorded cycles.
+func (t *Trace) Len() int {
+	return len(t.Cycles)
+}
+
+// Snapshot returns the state of the machine at the end of the given cycle,
+// or before the first one for cycle 0.
+func (t *Trace) Snapshot(cycle int) (*engine.Snapshot, error) {
+	if cycle < 0 || cycle > t.Len() {
+		return nil, fmt.Errorf("no cycle %d in the trace", cycle)
+	}
+
+	state := t.states[cycle/checkpointInterval]
+	for state.Cycle < cycle {
+		next, err := apply(state, &t.Cycles[state.Cycle])
+		if err != nil {
+			return nil, err
+		}
+		state = next
+	}
+	return state, nil
+}
+
+// apply returns the state following the given one after the cycle.
+func apply(s *engine.Snapshot, c *Cycle) (*engine.Snapshot, error) {
+	next := &engine.Snapshot{Version: s.Version, Cycle: c.Cycle, Nodes: append([]engine.NodeSnapshot{}, s.Nodes...)}
+
+	nodes := make(map[uint8]int, len(next.Nodes))
+	for i, ns := range next.Nodes {
+		nodes[ns.Index] = i
+	}
+	for _, ns := range c.Changed {
+		i, ok := nodes[ns.Index]
+		if !ok {
+			return nil, fmt.Errorf("unknown node %d", ns.Index)
+		}
+		next.Nodes[i] = engine.NodeSnapshot(ns)
+	}
+
+	outputs := make(map[uint8]int, len(s.Outputs))
+	for i, out := range s.Outputs {
+		outputs[out.Index] = i
+		next.Outputs = append(next.Outputs, engine.OutputSnapshot{
+			Index:  out.Index,
+			Values: append([]int16{}, out.Values...),
+			Cycles: append([]int{}, out.Cycles...),
+		})
+	}
+	for _, em := range c.Emitted {
+		i, ok := outputs[em.Output]
+		if !ok {
+			return nil, fmt.Errorf("unknown output %d", em.Output)
+		}
+		next.Outputs[i].Values = append(next.Outputs[i].Values, em.Value)
+		next.Outputs[i].Cycles = append(next.Outputs[i].Cycles, c.Cycle)
+	}
+	return next, nil
+}
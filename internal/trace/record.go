@@ -0,0 +1,137 @@
+package trace
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+
+	"github.com/lekomish/tis-100/internal/engine"
+	"github.com/lekomish/tis-100/internal/model"
+	"github.com/lekomish/tis-100/internal/runner"
+)
+
+// Recorder writes the trace of a run, one line per cycle. It observes the engine
+// it records, so the run can be driven by any caller.
+type Recorder struct {
+	engine.NopObserver
+
+	enc      *json.Encoder
+	eng      *engine.Engine
+	prev     *engine.Snapshot // state at the end of the previous cycle
+	cycle    Cycle            // events of the current cycle
+	executed map[uint8]bool   // nodes which completed an instruction in the current cycle
+	err      error            // first error met while writing
+}
+
+// NewRecorder writes the header of a trace of the engine, which must not have run yet,
+// and starts recording its cycles until `Finish` is called.
+// The engine must have been built from the given puzzle and code.
+func NewRecorder(w io.Writer, eng *engine.Engine, puzzle *model.Puzzle, code *model.Code) (*Recorder, error) {
+	if eng.Cycle != 0 {
+		return nil, errors.New("the engine has already run")
+	}
+
+	h := Header{
+		Version: Version,
+		Title:   puzzle.Title,
+		Layout:  puzzle.Layout,
+		Code:    code.Nodes,
+		Initial: eng.Snapshot(),
+	}
+	for _, s := range puzzle.Streams {
+		h.Streams = append(h.Streams, Stream{
+			Type: s.Type, Name: s.Name, Position: s.Position, Values: s.Values, Checked: s.Checked,
+		})
+	}
+
+	r := &Recorder{enc: json.NewEncoder(w), eng: eng, prev: h.Initial, executed: make(map[uint8]bool)}
+	if err := r.enc.Encode(h); err != nil {
+		return nil, err
+	}
+	eng.AddObserver(r)
+	return r, nil
+}
+
+// InstructionExecuted implements `engine.Observer`.
+func (r *Recorder) InstructionExecuted(n *engine.Node, _ *engine.Instruction) {
+	r.executed[n.Index] = true
+}
+
+// ValueTransferred implements `engine.Observer`.
+func (r *Recorder) ValueTransferred(from, to *engine.Node, _ engine.Port, value int16) {
+	r.cycle.Moves = append(r.cycle.Moves, Move{From: from.Index, To: to.Index, Value: value})
+}
+
+// ValueEmitted implements `engine.Observer`.
+func (r *Recorder) ValueEmitted(out *engine.Output, value int16) {
+	r.cycle.Emitted = append(r.cycle.Emitted, Emission{Output: out.Index, Value: value})
+}
+
+// CycleFinished implements `engine.Observer`: it writes the line of the cycle.
+func (r *Recorder) CycleFinished(cycle int) {
+	cur := r.eng.Snapshot()
+	r.cycle.Cycle = cycle
+	for i, ns := range cur.Nodes {
+		before := r.prev.Nodes[i]
+		if !ns.Equal(before) {
+			r.cycle.Changed = append(r.cycle.Changed, NodeState(ns))
+		}
+		if i < len(r.eng.Nodes) && len(r.eng.Nodes[i].Instructions) > 0 {
+			r.cycle.Ran = append(r.cycle.Ran, Ran{Node: ns.Index, IP: before.IP, Blocked: !r.executed[ns.Index]})
+		}
+	}
+
+	if r.err == nil {
+		r.err = r.enc.Encode(r.cycle)
+	}
+	r.prev = cur
+	r.cycle = Cycle{}
+	clear(r.executed)
+}
+
+// Finish stops recording and writes the result of the run as the last line of the trace,
+// along with the verdict of the check of the puzzle that failed, if any.
+// It returns the first error met while writing the trace.
+func (r *Recorder) Finish(res *engine.Result, failed *model.Verdict) error {
+	r.eng.RemoveObserver(r)
+	if r.err != nil {
+		return r.err
+	}
+
+	result := &Result{Reason: res.Reason.String(), Cycles: res.Cycles}
+	if res.Err != nil {
+		result.Error = res.Err.Error()
+	}
+	if failed != nil {
+		result.Check = failed.String()
+	}
+	return r.enc.Encode(footer{Result: result})
+}
+
+// Record runs the code compiled in the given mode on the puzzle and writes the trace of the whole run.
+// Breakpoints are ignored. As in `runner.Run`, a completed run is checked when the puzzle checks
+// its outputs, and ends with a mismatch if a check fails.
+func Record(w io.Writer, puzzle *model.Puzzle, code *model.Code, mode engine.Mode, opts engine.RunOptions) (*engine.Result, error) {
+	eng, err := engine.NewEngineWithMode(puzzle.Streams, puzzle.Layout, code, mode)
+	if err != nil {
+		return nil, err
+	}
+	rec, err := NewRecorder(w, eng, puzzle, code)
+	if err != nil {
+		return nil, err
+	}
+
+	opts.Debug = false
+	res := eng.Run(opts)
+	var failed *model.Verdict
+	if puzzle.Check != nil && res.Passed() {
+		verdicts, err := runner.Check(puzzle, eng)
+		if err != nil {
+			return nil, err
+		}
+		if failed = runner.FailedVerdict(verdicts); failed != nil {
+			res.Reason = engine.Mismatch
+		}
+	}
+	return res, rec.Finish(res, failed)
+}
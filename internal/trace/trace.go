@@ -0,0 +1,199 @@
+// Package trace records runs of the engine into compact, versioned trace files
+// and reads them back, so runs can be reviewed offline without the puzzle's Lua file.
+//
+// A trace file is a sequence of JSON lines: a header describing the puzzle and the solution,
+// one line per cycle and a final line with the result of the run. The header holds the initial
+// state of the machine; each cycle line holds the nodes whose state changed during the cycle,
+// the instruction every node executed or was blocked on, the values moved between nodes and
+// the values emitted to outputs.
+package trace
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/lekomish/tis-100/internal/engine"
+	"github.com/lekomish/tis-100/internal/model"
+)
+
+// Version is the version of the trace format written by `Recorder`.
+const Version = 1
+
+// Header is the first line of a trace file.
+type Header struct {
+	Version int              `json:"version"`
+	Title   string           `json:"title"`
+	Layout  []model.NodeType `json:"layout"`
+	Code    [][]string       `json:"code"` // source of every grid node
+	Streams []Stream         `json:"streams"`
+	Initial *engine.Snapshot `json:"initial"` // machine state before the first cycle
+}
+
+// Stream is a puzzle stream as stored in a trace file.
+type Stream struct {
+	Type     model.StreamType `json:"type"`
+	Name     string           `json:"name"`
+	Position uint8            `json:"position"`
+	Values   []int16          `json:"values"`
+	Checked  bool             `json:"checked,omitempty"`
+}
+
+// Cycle is what happened during a single cycle. To keep trace files compact,
+// the entries of its lists are written as JSON arrays of numbers.
+type Cycle struct {
+	Cycle   int         `json:"cycle"`
+	Changed []NodeState `json:"changed,omitempty"` // end-of-cycle state of the nodes that changed
+	Ran     []Ran       `json:"ran,omitempty"`     // instruction attempted by every grid node with code
+	Moves   []Move      `json:"moves,omitempty"`   // values read by one node from another
+	Emitted []Emission  `json:"emitted,omitempty"` // values received by outputs
+}
+
+// NodeState is the state of a node, written as
+// `[index, blocked, ip, acc, bak, writing, port, target, value, last]`
+// followed by the values held by a stack memory node, the top one last.
+type NodeState engine.NodeSnapshot
+
+// Ran is the instruction a node attempted during a cycle, written as `[node, ip, blocked]`.
+type Ran struct {
+	Node    uint8 // engine index of the node
+	IP      uint8 // index of the instruction
+	Blocked bool  // the instruction did not complete
+}
+
+// Move is a value read by a node from one of its neighbours, written as `[from, to, value]`.
+type Move struct {
+	From  uint8 // engine index of the writer
+	To    uint8 // engine index of the reader
+	Value int16
+}
+
+// Emission is a value received by an output, written as `[output, value]`.
+type Emission struct {
+	Output uint8 // position of the output stream
+	Value  int16
+}
+
+// Result is the outcome of the recorded run, stored in the last line of a trace file.
+type Result struct {
+	Reason string `json:"reason"`
+	Cycles int    `json:"cycles"`
+	Error  string `json:"error,omitempty"`
+	Check  string `json:"check,omitempty"` // failed check of the puzzle that made the run a mismatch
+}
+
+// footer is the last line of a trace file.
+type footer struct {
+	Result *Result `json:"result"`
+}
+
+// Puzzle returns the puzzle the trace was recorded on.
+func (h *Header) Puzzle() *model.Puzzle {
+	p := &model.Puzzle{Title: h.Title, Layout: h.Layout}
+	for _, s := range h.Streams {
+		p.Streams = append(p.Streams, &model.Stream{
+			Type: s.Type, Name: s.Name, Position: s.Position, Values: s.Values, Checked: s.Checked,
+		})
+	}
+	return p
+}
+
+// Solution returns the code the trace was recorded with.
+func (h *Header) Solution() *model.Code {
+	return &model.Code{Title: h.Title, Nodes: h.Code}
+}
+
+// MarshalJSON implements `json.Marshaler`.
+func (s NodeState) MarshalJSON() ([]byte, error) {
+	v := []int{
+		int(s.Index), boolInt(s.Blocked), int(s.IP), int(s.ACC), int(s.BAK),
+		boolInt(s.Writing), int(s.OutboundPort), s.OutboundTarget, int(s.OutboundValue), s.Last,
+	}
+	for _, value := range s.Stack {
+		v = append(v, int(value))
+	}
+	return json.Marshal(v)
+}
+
+// UnmarshalJSON implements `json.Unmarshaler`.
+func (s *NodeState) UnmarshalJSON(data []byte) error {
+	var v []int
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	if len(v) < 10 {
+		return fmt.Errorf("expected at least 10 numbers, got %d", len(v))
+	}
+	*s = NodeState{
+		Index: uint8(v[0]), Blocked: v[1] != 0, IP: uint8(v[2]), ACC: int16(v[3]), BAK: int16(v[4]),
+		Writing: v[5] != 0, OutboundPort: engine.Port(v[6]), OutboundTarget: v[7], OutboundValue: int16(v[8]), Last: v[9],
+	}
+	for _, value := range v[10:] {
+		s.Stack = append(s.Stack, int16(value))
+	}
+	return nil
+}
+
+// MarshalJSON implements `json.Marshaler`.
+func (r Ran) MarshalJSON() ([]byte, error) {
+	return json.Marshal([]int{int(r.Node), int(r.IP), boolInt(r.Blocked)})
+}
+
+// UnmarshalJSON implements `json.Unmarshaler`.
+func (r *Ran) UnmarshalJSON(data []byte) error {
+	v, err := unmarshalInts(data, 3)
+	if err != nil {
+		return err
+	}
+	*r = Ran{Node: uint8(v[0]), IP: uint8(v[1]), Blocked: v[2] != 0}
+	return nil
+}
+
+// MarshalJSON implements `json.Marshaler`.
+func (m Move) MarshalJSON() ([]byte, error) {
+	return json.Marshal([]int{int(m.From), int(m.To), int(m.Value)})
+}
+
+// UnmarshalJSON implements `json.Unmarshaler`.
+func (m *Move) UnmarshalJSON(data []byte) error {
+	v, err := unmarshalInts(data, 3)
+	if err != nil {
+		return err
+	}
+	*m = Move{From: uint8(v[0]), To: uint8(v[1]), Value: int16(v[2])}
+	return nil
+}
+
+// MarshalJSON implements `json.Marshaler`.
+func (e Emission) MarshalJSON() ([]byte, error) {
+	return json.Marshal([]int{int(e.Output), int(e.Value)})
+}
+
+// UnmarshalJSON implements `json.Unmarshaler`.
+func (e *Emission) UnmarshalJSON(data []byte) error {
+	v, err := unmarshalInts(data, 2)
+	if err != nil {
+		return err
+	}
+	*e = Emission{Output: uint8(v[0]), Value: int16(v[1])}
+	return nil
+}
+
+// unmarshalInts decodes a JSON array of exactly n integers.
+func unmarshalInts(data []byte, n int) ([]int, error) {
+	var v []int
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+	if len(v) != n {
+		return nil, fmt.Errorf("expected %d numbers, got %d", n, len(v))
+	}
+	return v, nil
+}
+
+// boolInt converts a boolean to 0 or 1.
+func boolInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
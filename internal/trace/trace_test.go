@@ -0,0 +1,247 @@
+package trace_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lekomish/tis-100/internal/engine"
+	"github.com/lekomish/tis-100/internal/model"
+	"github.com/lekomish/tis-100/internal/trace"
+)
+
+/* TESTS */
+
+// --- Record --- Read ---
+func TestRecordAndRead(t *testing.T) {
+	puzzle, code := newPipe([]int16{1, 2, 3}, []int16{1, 5, 3})
+
+	var buf bytes.Buffer
+	res, err := trace.Record(&buf, puzzle, code, engine.ModeSandbox, engine.RunOptions{})
+	require.NoError(t, err)
+	require.Equal(t, engine.Mismatch, res.Reason)
+
+	tr, err := trace.Read(&buf)
+	require.NoError(t, err)
+	require.Equal(t, trace.Version, tr.Header.Version)
+	require.Equal(t, puzzle, tr.Header.Puzzle())
+	require.Equal(t, code, tr.Header.Solution())
+	require.Equal(t, &trace.Result{Reason: "output mismatch", Cycles: res.Cycles}, tr.Result)
+	require.Equal(t, res.Cycles, tr.Len())
+
+	eng, err := engine.NewEngine(puzzle.Streams, puzzle.Layout, code)
+	require.NoError(t, err)
+	for cycle := range tr.Len() + 1 {
+		s, err := tr.Snapshot(cycle)
+		require.NoError(t, err)
+		require.Equal(t, eng.Snapshot(), s, "cycle %d", cycle)
+		_, err = eng.Tick()
+		require.NoError(t, err)
+	}
+
+	_, err = tr.Snapshot(tr.Len() + 1)
+	require.ErrorContains(t, err, "no cycle")
+}
+
+func TestRecordChecked(t *testing.T) {
+	puzzle, code := newPipe([]int16{1, 2, 3}, []int16{3, 2, 1})
+	puzzle.Streams[1].Checked = true
+
+	var buf bytes.Buffer
+	res, err := trace.Record(&buf, puzzle, code, engine.ModeSandbox, engine.RunOptions{})
+	require.NoError(t, err)
+	require.Equal(t, engine.Completed, res.Reason)
+
+	tr, err := trace.Read(&buf)
+	require.NoError(t, err)
+	require.Equal(t, puzzle, tr.Header.Puzzle())
+	require.Equal(t, &trace.Result{Reason: "completed", Cycles: res.Cycles}, tr.Result)
+}
+
+func TestRecordCheckRejectsOutput(t *testing.T) {
+	puzzle, code := newPipe([]int16{1, 2, 3}, []int16{3, 2, 1})
+	puzzle.Streams[1].Checked = true
+	puzzle.Check = func(inputs, outputs map[string][]int16) ([]model.Verdict, error) {
+		require.Equal(t, []int16{1, 2, 3}, inputs["IN"])
+		require.Equal(t, []int16{1, 2, 3}, outputs["OUT"])
+		return []model.Verdict{{Stream: "OUT", Message: "not sorted in descending order"}}, nil
+	}
+
+	var buf bytes.Buffer
+	res, err := trace.Record(&buf, puzzle, code, engine.ModeSandbox, engine.RunOptions{})
+	require.NoError(t, err)
+	require.Equal(t, engine.Mismatch, res.Reason)
+
+	tr, err := trace.Read(&buf)
+	require.NoError(t, err)
+	require.Equal(t, &trace.Result{
+		Reason: "output mismatch", Cycles: res.Cycles, Check: "OUT: check failed: not sorted in descending order",
+	}, tr.Result)
+}
+
+func TestRecordEvents(t *testing.T) {
+	puzzle, code := newPipe([]int16{1, 2, 3}, []int16{1, 2, 3})
+
+	var buf bytes.Buffer
+	res, err := trace.Record(&buf, puzzle, code, engine.ModeSandbox, engine.RunOptions{})
+	require.NoError(t, err)
+	require.Equal(t, engine.Completed, res.Reason)
+	tr, err := trace.Read(&buf)
+	require.NoError(t, err)
+
+	// the input writes in cycle 1, the first node reads and writes in cycle 2
+	require.Empty(t, tr.Cycles[0].Moves)
+	require.Equal(t, []trace.Ran{{Node: 4, IP: 0, Blocked: true}, {Node: 8, IP: 0, Blocked: true}, {Node: 12, IP: 0, Blocked: true}}, tr.Cycles[0].Ran)
+	require.Equal(t, []trace.Move{{From: 0, To: 4, Value: 1}}, tr.Cycles[1].Moves)
+
+	var emitted []trace.Emission
+	for _, c := range tr.Cycles {
+		emitted = append(emitted, c.Emitted...)
+	}
+	require.Equal(t, []trace.Emission{{Output: 0, Value: 1}, {Output: 0, Value: 2}, {Output: 0, Value: 3}}, emitted)
+}
+
+func TestRecordWriteToAny(t *testing.T) {
+	code := &model.Code{Title: "ANY", Nodes: make([][]string, model.NodesNumber)}
+	code.Nodes[0] = []string{"MOV 1 ANY"}
+	code.Nodes[1] = []string{"MOV ANY ACC"}
+	puzzle := &model.Puzzle{
+		Title:   "ANY",
+		Streams: []*model.Stream{{Type: model.OUTPUT, Position: 3, Values: []int16{1}}},
+		Layout:  make([]model.NodeType, model.NodesNumber),
+	}
+
+	var buf bytes.Buffer
+	_, err := trace.Record(&buf, puzzle, code, engine.ModeSandbox, engine.RunOptions{MaxCycles: 3})
+	require.NoError(t, err)
+	tr, err := trace.Read(&buf)
+	require.NoError(t, err)
+	require.Equal(t, &trace.Result{Reason: "cycle limit reached", Cycles: 3}, tr.Result)
+	require.Equal(t, []trace.Move{{From: 4, To: 5, Value: 1}}, tr.Cycles[1].Moves)
+}
+
+func TestRecordMemory(t *testing.T) {
+	code := &model.Code{Title: "MEMORY", Nodes: make([][]string, model.NodesNumber)}
+	code.Nodes[0] = []string{"MOV ACC RIGHT", "ADD 1", "MOV RIGHT NIL"}
+	puzzle := &model.Puzzle{
+		Title:   "MEMORY",
+		Streams: []*model.Stream{{Type: model.OUTPUT, Position: 3, Values: []int16{1}}},
+		Layout:  make([]model.NodeType, model.NodesNumber),
+	}
+	puzzle.Layout[1] = model.MEMORY
+
+	var buf bytes.Buffer
+	_, err := trace.Record(&buf, puzzle, code, engine.ModeSandbox, engine.RunOptions{MaxCycles: 20})
+	require.NoError(t, err)
+	tr, err := trace.Read(&buf)
+	require.NoError(t, err)
+
+	eng, err := engine.NewEngine(puzzle.Streams, puzzle.Layout, code)
+	require.NoError(t, err)
+	for cycle := range tr.Len() + 1 {
+		s, err := tr.Snapshot(cycle)
+		require.NoError(t, err)
+		require.Equal(t, eng.Snapshot(), s, "cycle %d", cycle)
+		_, err = eng.Tick()
+		require.NoError(t, err)
+	}
+
+	var stacks [][]int16
+	for _, c := range tr.Cycles {
+		for _, ns := range c.Changed {
+			if ns.Index == 5 {
+				stacks = append(stacks, ns.Stack)
+			}
+		}
+	}
+	require.Contains(t, stacks, []int16{3}, "the stack should be part of the node states")
+}
+
+func TestRecordWithMode(t *testing.T) {
+	puzzle, code := newPipe([]int16{1}, []int16{1})
+	code.Nodes[0] = []string{"ADD 1000", "SUB 1000", "MOV UP DOWN"}
+
+	_, err := trace.Record(&bytes.Buffer{}, puzzle, code, engine.ModeStrict, engine.RunOptions{})
+	require.ErrorContains(t, err, "value 1000 is out of range -999..999")
+
+	res, err := trace.Record(&bytes.Buffer{}, puzzle, code, engine.ModeSandbox, engine.RunOptions{})
+	require.NoError(t, err)
+	require.Equal(t, engine.Completed, res.Reason)
+}
+
+func TestNewRecorderAfterRun(t *testing.T) {
+	puzzle, code := newPipe([]int16{1}, []int16{1})
+	eng, err := engine.NewEngine(puzzle.Streams, puzzle.Layout, code)
+	require.NoError(t, err)
+	eng.Run(engine.RunOptions{MaxCycles: 1})
+
+	_, err = trace.NewRecorder(&bytes.Buffer{}, eng, puzzle, code)
+	require.ErrorContains(t, err, "the engine has already run")
+}
+
+func TestReadErrors(t *testing.T) {
+	puzzle, code := newPipe([]int16{1}, []int16{1})
+	var buf bytes.Buffer
+	_, err := trace.Record(&buf, puzzle, code, engine.ModeSandbox, engine.RunOptions{})
+	require.NoError(t, err)
+	lines := strings.SplitAfter(buf.String(), "\n")
+	header := lines[0]
+
+	tests := []struct {
+		name     string
+		data     string
+		expected string
+	}{
+		{name: "empty", data: "", expected: "invalid trace header"},
+		{name: "wrong version", data: `{"version":2}`, expected: "unsupported trace version 2"},
+		{name: "no initial state", data: `{"version":1}`, expected: "trace has no initial state"},
+		{name: "skipped cycle", data: header + `{"cycle":2}`, expected: "expected cycle 1, got 2"},
+		{name: "unknown node", data: header + `{"cycle":1,"changed":[[42,0,0,0,0,0,0,-1,0,-1]]}`, expected: "cycle 1: unknown node 42"},
+		{name: "unknown output", data: header + `{"cycle":1,"emitted":[[3,1]]}`, expected: "cycle 1: unknown output 3"},
+		{name: "short entry", data: header + `{"cycle":1,"moves":[[0,4]]}`, expected: "expected 3 numbers, got 2"},
+		{name: "after result", data: strings.Join(lines, "") + `{"cycle":99}`, expected: "trace continues after its result"},
+		{name: "broken line", data: header + `{"cycle":`, expected: "invalid trace line"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := trace.Read(strings.NewReader(tt.data))
+			require.ErrorContains(t, err, tt.expected)
+		})
+	}
+}
+
+// NewRecorder -> covered in previous tests
+// InstructionExecuted -> covered in previous tests
+// ValueTransferred -> covered in previous tests
+// ValueEmitted -> covered in previous tests
+// CycleFinished -> covered in previous tests
+// Finish -> covered in previous tests
+// Len -> covered in previous tests
+// Snapshot -> covered in previous tests
+// apply -> covered in previous tests
+// MarshalJSON -> covered in previous tests
+// UnmarshalJSON -> covered in previous tests
+// unmarshalInts -> covered in previous tests
+// boolInt -> covered in previous tests
+
+/* UTILS */
+
+// newPipe creates a puzzle passing the input values down the first column of the grid
+// and the code to solve it.
+func newPipe(input, expected []int16) (*model.Puzzle, *model.Code) {
+	code := &model.Code{Title: "PIPE", Nodes: make([][]string, model.NodesNumber)}
+	code.Nodes[0] = []string{"MOV UP DOWN"}
+	code.Nodes[4] = []string{"MOV UP DOWN"}
+	code.Nodes[8] = []string{"MOV UP DOWN"}
+	puzzle := &model.Puzzle{
+		Title: "PIPE",
+		Streams: []*model.Stream{
+			{Type: model.INPUT, Name: "IN", Position: 0, Values: input},
+			{Type: model.OUTPUT, Name: "OUT", Position: 0, Values: expected},
+		},
+		Layout: make([]model.NodeType, model.NodesNumber),
+	}
+	return puzzle, code
+}
@@ -0,0 +1,156 @@
+// Package tui renders the state of a TIS-100 machine in the terminal
+// and drives interactive debugging sessions.
+package tui
+
+import (
+	"github.com/lekomish/tis-100/internal/engine"
+	"github.com/lekomish/tis-100/internal/model"
+)
+
+// Node modes shown in the side panel of a node, as in the original game.
+const (
+	ModeIdle  = "IDLE" // the node has no instructions
+	ModeRun   = "RUN"  // the node executes its instructions
+	ModeRead  = "READ" // the node waits for a value
+	ModeWrite = "WRTE" // the node waits for its value to be read
+)
+
+// Outcomes of the last cycle of a node, shown when replaying a trace.
+const (
+	RanExecuted = "RAN"   // the node completed the instruction it attempted
+	RanStalled  = "STALL" // the node was blocked on the instruction it attempted
+)
+
+// directions lists the ports connecting a node to its neighbours.
+var directions = []engine.Port{engine.PortUp, engine.PortDown, engine.PortLeft, engine.PortRight}
+
+// NodeFrame is the state of a single grid node as displayed.
+type NodeFrame struct {
+	Damaged bool     // the node is damaged and holds no code
+	Memory  bool     // the node is a stack memory node and holds no code
+	Stack   []int16  // values of a stack memory node, the top one last
+	Title   string   // title given by a `##` comment of the source, if any
+	Source  []string // source lines of the node, as written in the solution
+	Line    int      // source line of the instruction to execute next (-1 if none)
+	ACC     int16    // accumulator
+	BAK     int16    // backup register
+	Mode    string   // one of the Mode constants
+	Ran     string   // outcome of the last cycle, one of the Ran constants (replays only, empty otherwise)
+	RanLine int      // source line of the instruction attempted in the last cycle (replays only)
+
+	Writes []engine.Port // directions of the pending write (empty if the node is not writing)
+	Value  int16         // value of the pending write
+
+	Heat  []int    // heat level of every source line, from 0 to `HeatLevels` (profiles only)
+	Panel []string // side panel replacing the registers and the mode (profiles only)
+}
+
+// StreamFrame is the state of an input or output stream as displayed.
+type StreamFrame struct {
+	Name     string  // stream name
+	Position uint8   // column of the grid the stream is attached to
+	Values   []int16 // input values or expected output values
+	Actual   []int16 // values emitted so far (outputs only), or pixels drawn so far (images only)
+	Cursor   int     // index of the next value to be sent (inputs only)
+	Checked  bool    // the values are checked by the puzzle, `Values` being one valid output (outputs only)
+
+	Writing bool  // a value is being sent into the grid (inputs only)
+	Value   int16 // value being sent into the grid (inputs only)
+}
+
+// Frame is a snapshot of everything drawn on the screen for one cycle.
+type Frame struct {
+	Title   string                       // puzzle title
+	Cycle   int                          // number of executed cycles
+	Status  string                       // free form status line (e.g., why the run stopped)
+	Nodes   [model.NodesNumber]NodeFrame // grid nodes in grid order
+	Inputs  []StreamFrame                // input streams in puzzle order
+	Outputs []StreamFrame                // output streams in puzzle order
+	Images  []StreamFrame                // image streams in puzzle order, with the pixels as values
+}
+
+// NewFrame captures the current state of the engine running the given puzzle and code.
+func NewFrame(eng *engine.Engine, puzzle *model.Puzzle, code *model.Code) *Frame {
+	f := &Frame{Title: puzzle.Title, Cycle: eng.Cycle}
+	for i, n := range eng.Nodes {
+		f.Nodes[i] = newNodeFrame(n, code.Nodes[i])
+	}
+
+	// input and output nodes are identified by their position index
+	streamNodes := make(map[uint8]*engine.Node)
+	for list := eng.NodeList; list != nil; list = list.Next {
+		streamNodes[list.Node.Index] = list.Node
+	}
+	outputs := make(map[uint8]*engine.Output)
+	for _, out := range eng.Outputs {
+		outputs[out.Index] = out
+	}
+
+	for _, stream := range puzzle.Streams {
+		sf := StreamFrame{Name: stream.Name, Position: stream.Position, Values: stream.Values, Checked: stream.Checked}
+		switch stream.Type {
+		case model.INPUT:
+			if n, ok := streamNodes[stream.Position]; ok {
+				sf.Cursor = int(n.InstructionPointer)
+				sf.Writing = n.IsWriting
+				sf.Value = n.OutboundValue
+			}
+			f.Inputs = append(f.Inputs, sf)
+		case model.OUTPUT:
+			if out, ok := outputs[stream.Position]; ok {
+				sf.Actual = out.Values
+			}
+			f.Outputs = append(f.Outputs, sf)
+		case model.IMAGE:
+			if out, ok := outputs[stream.Position]; ok && out.Image != nil {
+				sf.Actual = append([]int16(nil), out.Image.Pixels...)
+			}
+			f.Images = append(f.Images, sf)
+		}
+	}
+
+	return f
+}
+
+// newNodeFrame captures the state of a grid node with the given source.
+func newNodeFrame(n *engine.Node, source []string) NodeFrame {
+	nf := NodeFrame{
+		Damaged: n.Type == model.DAMAGED,
+		Memory:  n.Type == model.MEMORY,
+		Stack:   append([]int16(nil), n.Stack...),
+		Title:   engine.NodeTitle(source),
+		Source:  source,
+		Line:    -1,
+		ACC:     n.ACC,
+		BAK:     n.BAK,
+		Mode:    nodeMode(n),
+	}
+	if ip := int(n.InstructionPointer); ip < len(n.Instructions) {
+		nf.Line = n.Instructions[ip].Line
+	}
+	if n.IsWriting {
+		nf.Value = n.OutboundValue
+		// ANY offers the value to every neighbour, LAST resolves to the port of its node
+		for _, port := range directions {
+			target := n.Ports[port]
+			if target != nil && (n.OutboundPort == engine.PortAny || target == n.OutboundTarget) {
+				nf.Writes = append(nf.Writes, port)
+			}
+		}
+	}
+	return nf
+}
+
+// nodeMode returns the mode of the node as shown by the original game.
+func nodeMode(n *engine.Node) string {
+	switch {
+	case len(n.Instructions) == 0:
+		return ModeIdle
+	case n.IsWriting:
+		return ModeWrite
+	case n.IsBlocked:
+		return ModeRead
+	default:
+		return ModeRun
+	}
+}
@@ -0,0 +1,104 @@
+package tui_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lekomish/tis-100/internal/engine"
+	"github.com/lekomish/tis-100/internal/model"
+	"github.com/lekomish/tis-100/internal/tui"
+)
+
+/* TESTS */
+
+// --- NewFrame ---
+func TestNewFrameInitialState(t *testing.T) {
+	eng, puzzle, code := newPipe(t)
+
+	f := tui.NewFrame(eng, puzzle, code)
+	require.Equal(t, "PIPE", f.Title)
+	require.Equal(t, 0, f.Cycle)
+	require.Equal(t, 1, f.Nodes[0].Line, "the label-only line should be skipped")
+	require.Equal(t, tui.ModeRun, f.Nodes[0].Mode)
+	require.Equal(t, tui.ModeIdle, f.Nodes[1].Mode)
+	require.Equal(t, -1, f.Nodes[1].Line)
+	require.True(t, f.Nodes[5].Damaged)
+
+	require.Len(t, f.Inputs, 1)
+	require.Equal(t, "IN", f.Inputs[0].Name)
+	require.Equal(t, 0, f.Inputs[0].Cursor)
+	require.Len(t, f.Outputs, 1)
+	require.Empty(t, f.Outputs[0].Actual)
+}
+
+func TestNewFramePendingWrites(t *testing.T) {
+	eng, puzzle, code := newPipe(t)
+	for range 2 {
+		_, err := eng.Tick()
+		require.NoError(t, err)
+	}
+
+	f := tui.NewFrame(eng, puzzle, code)
+	require.Equal(t, 1, f.Inputs[0].Cursor)
+	require.False(t, f.Inputs[0].Writing, "the input sends its next value in the following cycle")
+	require.Equal(t, tui.ModeWrite, f.Nodes[0].Mode)
+	require.Equal(t, []engine.Port{engine.PortDown}, f.Nodes[0].Writes)
+	require.Equal(t, int16(1), f.Nodes[0].Value)
+	require.Equal(t, tui.ModeRead, f.Nodes[4].Mode)
+
+	_, err := eng.Tick()
+	require.NoError(t, err)
+
+	f = tui.NewFrame(eng, puzzle, code)
+	require.True(t, f.Inputs[0].Writing)
+	require.Equal(t, int16(2), f.Inputs[0].Value)
+}
+
+func TestNewFrameWriteToAny(t *testing.T) {
+	code := &model.Code{Title: "ANY", Nodes: make([][]string, model.NodesNumber)}
+	code.Nodes[5] = []string{"MOV 1 ANY"}
+	puzzle := &model.Puzzle{Title: "ANY"}
+
+	eng, err := engine.NewEngine(nil, nil, code)
+	require.NoError(t, err)
+	_, err = eng.Tick()
+	require.NoError(t, err)
+
+	f := tui.NewFrame(eng, puzzle, code)
+	require.ElementsMatch(
+		t,
+		[]engine.Port{engine.PortUp, engine.PortDown, engine.PortLeft, engine.PortRight},
+		f.Nodes[5].Writes,
+	)
+}
+
+// newNodeFrame -> covered in previous tests
+// nodeMode -> covered in previous tests
+
+/* UTILS */
+
+// newPipe creates an engine passing the input values down the first column of the grid,
+// next to a damaged node, together with its puzzle and code.
+func newPipe(tb testing.TB) (*engine.Engine, *model.Puzzle, *model.Code) {
+	tb.Helper()
+
+	code := &model.Code{Title: "PIPE", Nodes: make([][]string, model.NodesNumber)}
+	code.Nodes[0] = []string{"START:", "MOV UP DOWN"}
+	code.Nodes[4] = []string{"MOV UP DOWN"}
+	code.Nodes[8] = []string{"MOV UP DOWN"}
+	layout := make([]model.NodeType, model.NodesNumber)
+	layout[5] = model.DAMAGED
+	puzzle := &model.Puzzle{
+		Title: "PIPE",
+		Streams: []*model.Stream{
+			{Type: model.INPUT, Name: "IN", Position: 0, Values: []int16{1, 2, 3}},
+			{Type: model.OUTPUT, Name: "OUT", Position: 0, Values: []int16{1, 5, 3}},
+		},
+		Layout: layout,
+	}
+
+	eng, err := engine.NewEngine(puzzle.Streams, puzzle.Layout, code)
+	require.NoError(tb, err)
+	return eng, puzzle, code
+}
@@ -0,0 +1,47 @@
+package tui
+
+import (
+	"strings"
+
+	"github.com/lekomish/tis-100/internal/model"
+)
+
+// imageGlyphs and imageColours draw the pixels of image streams by colour:
+// black, dark grey, light grey, white and red.
+var (
+	imageGlyphs  = [model.ImageColours]string{" ", "░", "▒", "█", "▓"}
+	imageColours = [model.ImageColours]string{ansiReset, "\x1b[90m", "\x1b[37m", "\x1b[97m", ansiRed}
+)
+
+// RenderImage draws the pixels of an image stream, row by row, one character per pixel
+// inside a frame showing the edges of the display. Missing pixels are drawn black.
+func RenderImage(pixels []int16, opts Options) []string {
+	lines := make([]string, 0, model.ImageHeight+2)
+	lines = append(lines, "┌"+strings.Repeat("─", model.ImageWidth)+"┐")
+	for y := range model.ImageHeight {
+		var row strings.Builder
+		for x := range model.ImageWidth {
+			colour := 0
+			if i := y*model.ImageWidth + x; i < len(pixels) && pixels[i] >= 0 && pixels[i] < model.ImageColours {
+				colour = int(pixels[i])
+			}
+			row.WriteString(highlight(imageGlyphs[colour], imageColours[colour], opts))
+		}
+		lines = append(lines, "│"+row.String()+"│")
+	}
+	return append(lines, "└"+strings.Repeat("─", model.ImageWidth)+"┘")
+}
+
+// renderImages draws the expected image of every image stream next to the image drawn so far.
+func renderImages(f *Frame, opts Options) []string {
+	var lines []string
+	for _, sf := range f.Images {
+		width := model.ImageWidth + 2
+		lines = append(lines, "", pad(sf.Name+" (expected)", width)+"  "+sf.Name+" (actual)")
+		expected, actual := RenderImage(sf.Values, opts), RenderImage(sf.Actual, opts)
+		for i := range expected {
+			lines = append(lines, expected[i]+"  "+actual[i])
+		}
+	}
+	return lines
+}
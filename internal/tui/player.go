@@ -0,0 +1,114 @@
+package tui
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/lekomish/tis-100/internal/engine"
+	"github.com/lekomish/tis-100/internal/model"
+	"github.com/lekomish/tis-100/internal/trace"
+)
+
+// Player plays a recorded trace back: every cycle is restored from the trace
+// into an engine built from its header, without executing anything.
+type Player struct {
+	Trace   *trace.Trace
+	Delay   time.Duration // pause between two frames while running
+	Options Options       // rendering options
+
+	engine *engine.Engine
+	puzzle *model.Puzzle
+	code   *model.Code
+}
+
+// NewPlayer prepares the playback of the trace, positioned before its first cycle.
+func NewPlayer(t *trace.Trace) (*Player, error) {
+	puzzle, code := t.Header.Puzzle(), t.Header.Solution()
+	eng, err := engine.NewEngine(puzzle.Streams, puzzle.Layout, code)
+	if err != nil {
+		return nil, err
+	}
+	p := &Player{Trace: t, Delay: DefaultDelay, engine: eng, puzzle: puzzle, code: code}
+	if err := p.Seek(0); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Cycle returns the cycle the playback is at.
+func (p *Player) Cycle() int {
+	return p.engine.Cycle
+}
+
+// Seek moves the playback to the end of the given cycle, or before the first one for cycle 0.
+func (p *Player) Seek(cycle int) error {
+	s, err := p.Trace.Snapshot(cycle)
+	if err != nil {
+		return err
+	}
+	return p.engine.Restore(s)
+}
+
+// Step moves the playback one cycle forward and reports whether it reached the end of the trace.
+func (p *Player) Step() (bool, error) {
+	if p.Cycle() < p.Trace.Len() {
+		if err := p.Seek(p.Cycle() + 1); err != nil {
+			return true, err
+		}
+	}
+	return p.Cycle() >= p.Trace.Len(), nil
+}
+
+// Back moves the playback the given number of cycles backwards, or to its start if it is shorter.
+func (p *Player) Back(cycles int) error {
+	return p.Seek(max(p.Cycle()-cycles, 0))
+}
+
+// Frame captures the current state of the playback. The nodes show the outcome of the last
+// cycle recorded in the trace: whether they executed the instruction they attempted or stalled on it.
+func (p *Player) Frame() *Frame {
+	f := NewFrame(p.engine, p.puzzle, p.code)
+	if cycle := p.Cycle(); cycle > 0 {
+		p.markRan(f, p.Trace.Cycles[cycle-1].Ran)
+	}
+	if res := p.Trace.Result; res != nil && p.Cycle() >= p.Trace.Len() {
+		f.Status = fmt.Sprintf("stopped: %s", res.Reason)
+		if res.Error != "" {
+			f.Status += fmt.Sprintf(": %s", res.Error)
+		}
+		if res.Check != "" {
+			f.Status += fmt.Sprintf(": %s", res.Check)
+		}
+	}
+	return f
+}
+
+// markRan marks the grid nodes of the frame with the instructions they attempted in a cycle.
+func (p *Player) markRan(f *Frame, ran []trace.Ran) {
+	for _, r := range ran {
+		for i, n := range p.engine.Nodes {
+			if n.Index != r.Node || int(r.IP) >= len(n.Instructions) {
+				continue
+			}
+			f.Nodes[i].Ran = RanExecuted
+			if r.Blocked {
+				f.Nodes[i].Ran = RanStalled
+			}
+			f.Nodes[i].RanLine = n.Instructions[r.IP].Line
+		}
+	}
+}
+
+// Loop reads commands from in until it is exhausted or `q` is entered, drawing to out.
+// While running or fast-forwarding, entering any line stops the playback.
+func (p *Player) Loop(in io.Reader, out io.Writer) {
+	loop(p, in, out, p.Options, p.Delay)
+}
+
+// step moves one cycle forward and reports whether the playback stopped.
+// The trace is validated when read, so a failure here stops the playback silently.
+func (p *Player) step() bool {
+	done, err := p.Step()
+	return done || err != nil
+}
@@ -0,0 +1,127 @@
+package tui_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lekomish/tis-100/internal/engine"
+	"github.com/lekomish/tis-100/internal/trace"
+	"github.com/lekomish/tis-100/internal/tui"
+)
+
+/* TESTS */
+
+// --- Step --- Back ---
+func TestPlayerStep(t *testing.T) {
+	p := newPlayer(t)
+	require.Equal(t, 0, p.Cycle())
+
+	done, err := p.Step()
+	require.NoError(t, err)
+	require.False(t, done)
+	require.Equal(t, 1, p.Cycle())
+	require.Empty(t, p.Frame().Status)
+
+	for !done {
+		done, err = p.Step()
+		require.NoError(t, err)
+	}
+	require.Equal(t, p.Trace.Len(), p.Cycle())
+	require.Equal(t, "stopped: output mismatch", p.Frame().Status)
+
+	done, err = p.Step()
+	require.NoError(t, err)
+	require.True(t, done, "the playback should not go past the end of the trace")
+	require.Equal(t, p.Trace.Len(), p.Cycle())
+
+	require.NoError(t, p.Back(2))
+	require.Equal(t, p.Trace.Len()-2, p.Cycle())
+	require.NoError(t, p.Back(100))
+	require.Equal(t, 0, p.Cycle())
+}
+
+func TestPlayerFrameMatchesSession(t *testing.T) {
+	p := newPlayer(t)
+	s := newSession(t)
+
+	for range 5 {
+		_, err := p.Step()
+		require.NoError(t, err)
+		s.Step()
+	}
+	f := p.Frame()
+	for i := range f.Nodes {
+		f.Nodes[i].Ran, f.Nodes[i].RanLine = "", 0
+	}
+	require.Equal(t, s.Frame(), f, "the replay should only add the outcome of the last cycle")
+}
+
+func TestPlayerFrameShowsRan(t *testing.T) {
+	p := newPlayer(t)
+	require.Empty(t, p.Frame().Nodes[0].Ran, "no cycle has run yet")
+
+	for range 5 {
+		_, err := p.Step()
+		require.NoError(t, err)
+	}
+	f := p.Frame()
+	require.Equal(t, tui.RanExecuted, f.Nodes[0].Ran)
+	require.Equal(t, 1, f.Nodes[0].RanLine)
+	require.Equal(t, tui.RanStalled, f.Nodes[4].Ran)
+	require.Empty(t, f.Nodes[1].Ran, "nodes without code do not run")
+
+	out := tui.Render(f, tui.Options{})
+	require.Contains(t, out, "LAST")
+	require.Contains(t, out, "STALL")
+}
+
+func TestPlayerFrameShowsFailedCheck(t *testing.T) {
+	p := newPlayer(t)
+	p.Trace.Result.Check = "OUT: check failed: not sorted"
+
+	err := p.Seek(p.Trace.Len())
+	require.NoError(t, err)
+	require.Equal(t, "stopped: output mismatch: OUT: check failed: not sorted", p.Frame().Status)
+}
+
+// --- Loop ---
+func TestPlayerLoop(t *testing.T) {
+	p := newPlayer(t)
+
+	var out strings.Builder
+	p.Loop(strings.NewReader("s 3\nu 1\nb @1:1\nq\n"), &out)
+	require.Equal(t, 2, p.Cycle())
+	require.Contains(t, out.String(), "PIPE - cycle 3")
+	require.Contains(t, out.String(), "PIPE - cycle 2")
+	require.Contains(t, out.String(), "unknown command \"b\"")
+	require.NotContains(t, out.String(), "breakpoint")
+}
+
+// NewPlayer -> covered in previous tests
+// Cycle -> covered in previous tests
+// Seek -> covered in previous tests
+// Frame -> covered in previous tests
+// markRan -> covered in previous tests
+// step -> covered in previous tests
+// loop -> covered in previous tests
+
+/* UTILS */
+
+// newPlayer records a run of the pipe engine and creates a player for its trace.
+func newPlayer(tb testing.TB) *tui.Player {
+	tb.Helper()
+
+	_, puzzle, code := newPipe(tb)
+	var buf bytes.Buffer
+	_, err := trace.Record(&buf, puzzle, code, engine.ModeSandbox, engine.RunOptions{})
+	require.NoError(tb, err)
+	t, err := trace.Read(&buf)
+	require.NoError(tb, err)
+
+	p, err := tui.NewPlayer(t)
+	require.NoError(tb, err)
+	return p
+}
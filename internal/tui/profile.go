@@ -0,0 +1,62 @@
+package tui
+
+import (
+	"fmt"
+
+	"github.com/lekomish/tis-100/internal/engine"
+	"github.com/lekomish/tis-100/internal/model"
+	"github.com/lekomish/tis-100/internal/profile"
+)
+
+// HeatLevels is the number of heat levels of the lines executed at least once.
+const HeatLevels = 4
+
+// heatGlyphs and heatColours mark the source lines by heat level, from never executed to the hottest.
+var (
+	heatGlyphs  = [HeatLevels + 1]string{" ", "░", "▒", "▓", "█"}
+	heatColours = [HeatLevels + 1]string{ansiReset, ansiBlue, ansiGreen, ansiYellow, ansiRed}
+)
+
+// NewProfileFrame captures the engine at the end of a profiled run: every source line is marked
+// with its heat level, relative to the most executed line of the grid, and the side panel shows
+// the share of cycles each node spent executing, reading and writing.
+// Nodes without instructions are shown as idle.
+func NewProfileFrame(eng *engine.Engine, puzzle *model.Puzzle, code *model.Code, stats *profile.Stats) *Frame {
+	f := NewFrame(eng, puzzle, code)
+	hottest := stats.MaxLine()
+	for i := range f.Nodes {
+		nf, s := &f.Nodes[i], &stats.Nodes[i]
+		if !s.Active() {
+			nf.Panel = []string{ModeIdle}
+			continue
+		}
+		nf.Line = -1
+		nf.Writes = nil
+		nf.Heat = make([]int, len(s.Lines))
+		for line, count := range s.Lines {
+			nf.Heat[line] = heatLevel(count, hottest)
+		}
+		nf.Panel = []string{
+			"EXEC", percent(s.Executing, stats.Cycles),
+			"READ", percent(s.BlockedRead, stats.Cycles),
+			"WRTE", percent(s.BlockedWrite, stats.Cycles),
+		}
+	}
+	return f
+}
+
+// heatLevel returns the heat level of a line executed count times, out of hottest at most.
+func heatLevel(count, hottest int) int {
+	if count == 0 || hottest == 0 {
+		return 0
+	}
+	return (count*HeatLevels + hottest - 1) / hottest
+}
+
+// percent formats part of total as a rounded percentage.
+func percent(part, total int) string {
+	if total == 0 {
+		return "0%"
+	}
+	return fmt.Sprintf("%d%%", (part*100+total/2)/total)
+}
@@ -0,0 +1,57 @@
+package tui_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lekomish/tis-100/internal/engine"
+	"github.com/lekomish/tis-100/internal/profile"
+	"github.com/lekomish/tis-100/internal/tui"
+)
+
+/* TESTS */
+
+// --- NewProfileFrame ---
+func TestNewProfileFrame(t *testing.T) {
+	eng, puzzle, code := newPipe(t)
+	p := profile.NewProfiler(eng)
+	eng.Run(engine.RunOptions{})
+	stats := p.Stats()
+
+	f := tui.NewProfileFrame(eng, puzzle, code, stats)
+	nf := f.Nodes[0]
+	require.Equal(t, []int{0, tui.HeatLevels}, nf.Heat, "the label line is never executed")
+	require.Equal(t, -1, nf.Line)
+	require.Empty(t, nf.Writes)
+	require.Equal(t, "EXEC", nf.Panel[0])
+	require.Nil(t, f.Nodes[1].Heat, "nodes without code have no heatmap")
+
+	text := tui.Render(f, tui.Options{})
+	require.Contains(t, text, "│ START:")
+	require.Contains(t, text, "│█MOV UP DOWN")
+	require.Contains(t, text, "WRTE")
+	require.NotContains(t, text, "ACC")
+
+	text = tui.Render(f, tui.Options{ANSI: true})
+	require.Contains(t, text, "\x1b[31m█\x1b[0mMOV UP DOWN")
+}
+
+func TestNewProfileFrameHeatLevels(t *testing.T) {
+	eng, puzzle, code := newPipe(t)
+	stats := &profile.Stats{Cycles: 8}
+	stats.Nodes[0] = profile.NodeStats{Executing: 8, Lines: []int{0, 8}}
+	stats.Nodes[4] = profile.NodeStats{Executing: 1, BlockedRead: 7, Lines: []int{1}}
+	stats.Nodes[8] = profile.NodeStats{Executing: 4, BlockedWrite: 4, Lines: []int{4}}
+
+	f := tui.NewProfileFrame(eng, puzzle, code, stats)
+	require.Equal(t, []int{0, 4}, f.Nodes[0].Heat)
+	require.Equal(t, []int{1}, f.Nodes[4].Heat)
+	require.Equal(t, []int{2}, f.Nodes[8].Heat)
+	require.Equal(t, []string{"EXEC", "13%", "READ", "88%", "WRTE", "0%"}, f.Nodes[4].Panel)
+	require.True(t, strings.Contains(tui.Render(f, tui.Options{}), "│▒MOV UP DOWN"))
+}
+
+// heatLevel -> covered in previous tests
+// percent -> covered in previous tests
@@ -0,0 +1,367 @@
+package tui
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/lekomish/tis-100/internal/engine"
+	"github.com/lekomish/tis-100/internal/model"
+)
+
+// Layout of a node box: `│` + code area + `│` + side panel + `│`.
+const (
+	codeWidth   = 19 // marker column followed by up to 18 characters of source
+	panelWidth  = 6  // ACC, BAK and MODE values
+	boxWidth    = codeWidth + panelWidth + 3
+	gapWidth    = 6 // horizontal space between boxes, wide enough for an arrow and a value
+	minBodySize = 6 // the side panel needs six lines
+	streamWidth = 6 // width of a single stream value column
+	gridCols    = 4
+	gridRows    = model.NodesNumber / gridCols
+)
+
+// ANSI escape sequences used when colours are enabled.
+const (
+	ansiInverse = "\x1b[7m"
+	ansiRed     = "\x1b[31m"
+	ansiGreen   = "\x1b[32m"
+	ansiYellow  = "\x1b[33m"
+	ansiBlue    = "\x1b[34m"
+	ansiReset   = "\x1b[0m"
+	ansiClear   = "\x1b[H\x1b[2J"
+)
+
+// Options configures how frames are rendered.
+type Options struct {
+	ANSI bool // highlight with ANSI escape sequences instead of plain markers only
+}
+
+// Render draws the frame as text: the stream columns on the left and the 4x3 grid of nodes
+// on the right, with pending writes drawn as arrows between neighbours. The expected and
+// actual images of image streams follow below.
+func Render(f *Frame, opts Options) string {
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "%s - cycle %d\n", f.Title, f.Cycle)
+	if f.Status != "" {
+		fmt.Fprintln(&builder, f.Status)
+	}
+	builder.WriteString("\n")
+
+	streams := renderStreams(f, opts)
+	grid := renderGrid(f, opts)
+	streamsWidth := 0
+	for _, line := range streams {
+		streamsWidth = max(streamsWidth, displayWidth(line))
+	}
+
+	for i := range max(len(streams), len(grid)) {
+		left, right := "", ""
+		if i < len(streams) {
+			left = streams[i]
+		}
+		if i < len(grid) {
+			right = grid[i]
+		}
+		line := pad(left, streamsWidth) + "  " + right
+		builder.WriteString(strings.TrimRight(line, " ") + "\n")
+	}
+	for _, line := range renderImages(f, opts) {
+		builder.WriteString(line + "\n")
+	}
+	return builder.String()
+}
+
+// renderStreams draws one column per input stream with the next value marked,
+// followed by one expected/actual column pair per output stream.
+func renderStreams(f *Frame, opts Options) []string {
+	length := 0
+	for _, sf := range append(append([]StreamFrame{}, f.Inputs...), f.Outputs...) {
+		length = max(length, len(sf.Values), len(sf.Actual))
+	}
+
+	lines := make([]string, length+1)
+	for _, sf := range f.Inputs {
+		lines[0] += pad(sf.Name, streamWidth+1)
+		for i := range length {
+			cell := ""
+			if i < len(sf.Values) {
+				cell = fmt.Sprint(sf.Values[i])
+			}
+			marker := " "
+			if i == sf.Cursor {
+				marker = ">"
+			}
+			lines[i+1] += marker + padLeft(cell, streamWidth-1) + " "
+		}
+	}
+	for _, sf := range f.Outputs {
+		lines[0] += pad(sf.Name, 2*streamWidth+1)
+		for i := range length {
+			expected, actual := "", ""
+			if i < len(sf.Values) {
+				expected = fmt.Sprint(sf.Values[i])
+			}
+			if i < len(sf.Actual) {
+				actual = fmt.Sprint(sf.Actual[i])
+				// checked streams accept other values than the expected ones, only extra values are wrong
+				if i >= len(sf.Values) || (!sf.Checked && sf.Actual[i] != sf.Values[i]) {
+					actual = highlight(actual+"!", ansiRed, opts)
+				}
+			}
+			lines[i+1] += padLeft(expected, streamWidth) + padLeft(actual, streamWidth) + " "
+		}
+	}
+	return lines
+}
+
+// renderGrid draws the input arrows, the rows of node boxes with the arrows between them
+// and the output arrows.
+func renderGrid(f *Frame, opts Options) []string {
+	bodySize := minBodySize
+	for _, nf := range f.Nodes {
+		bodySize = max(bodySize, len(nf.Source))
+		if nf.Memory {
+			// keep the grid from growing and shrinking with the stack
+			bodySize = max(bodySize, model.MemoryCapacity)
+		}
+		if nf.Ran != "" {
+			// room for the outcome of the last cycle below the mode
+			bodySize = max(bodySize, minBodySize+2)
+		}
+	}
+
+	lines := []string{renderInputArrows(f)}
+	for row := range gridRows {
+		if row > 0 {
+			lines = append(lines, renderVerticalArrows(f, row))
+		}
+		lines = append(lines, renderBoxRow(f, row, bodySize, opts)...)
+	}
+	return append(lines, renderOutputArrows(f))
+}
+
+// renderInputArrows draws the input streams entering the top row of the grid.
+func renderInputArrows(f *Frame) string {
+	cells := make([]string, gridCols)
+	for _, sf := range f.Inputs {
+		cell := sf.Name
+		if sf.Writing {
+			cell += fmt.Sprintf(" ↓%d", sf.Value)
+		}
+		cells[sf.Position] = cell
+	}
+	return joinColumns(cells)
+}
+
+// renderOutputArrows draws the values leaving the bottom row of the grid toward the output streams.
+func renderOutputArrows(f *Frame) string {
+	cells := make([]string, gridCols)
+	for _, sf := range append(append([]StreamFrame{}, f.Outputs...), f.Images...) {
+		cell := sf.Name
+		if nf := f.Nodes[(gridRows-1)*gridCols+int(sf.Position)]; writesTo(nf, engine.PortDown) {
+			cell = fmt.Sprintf("↓%d %s", nf.Value, sf.Name)
+		}
+		cells[sf.Position] = cell
+	}
+	return joinColumns(cells)
+}
+
+// renderVerticalArrows draws the values written between the given row and the row above it.
+func renderVerticalArrows(f *Frame, row int) string {
+	cells := make([]string, gridCols)
+	for col := range gridCols {
+		above, below := f.Nodes[(row-1)*gridCols+col], f.Nodes[row*gridCols+col]
+		arrows := make([]string, 0, 2)
+		if writesTo(above, engine.PortDown) {
+			arrows = append(arrows, fmt.Sprintf("↓%d", above.Value))
+		}
+		if writesTo(below, engine.PortUp) {
+			arrows = append(arrows, fmt.Sprintf("↑%d", below.Value))
+		}
+		cells[col] = strings.Join(arrows, " ")
+	}
+	return joinColumns(cells)
+}
+
+// renderBoxRow draws a row of node boxes with the values written between horizontal neighbours.
+func renderBoxRow(f *Frame, row, bodySize int, opts Options) []string {
+	border := func(left, title, middle, right string) string {
+		if title != "" {
+			title = " " + strings.TrimRight(pad(title, codeWidth-3), " ") + " "
+		}
+		return left + title + strings.Repeat("─", codeWidth-displayWidth(title)) + middle + strings.Repeat("─", panelWidth) + right
+	}
+
+	lines := make([]string, bodySize+2)
+	for col := range gridCols {
+		nf := f.Nodes[row*gridCols+col]
+		body := renderBody(nf, bodySize, opts)
+
+		gaps := make([]string, bodySize+2)
+		if col < gridCols-1 {
+			right := f.Nodes[row*gridCols+col+1]
+			if writesTo(nf, engine.PortRight) {
+				gaps[2] = fmt.Sprintf("→%d", nf.Value)
+			}
+			if writesTo(right, engine.PortLeft) {
+				gaps[4] = fmt.Sprintf("←%d", right.Value)
+			}
+		}
+
+		lines[0] += border("┌", nf.Title, "┬", "┐")
+		for i, line := range body {
+			lines[i+1] += line
+		}
+		lines[bodySize+1] += border("└", "", "┴", "┘")
+		if col < gridCols-1 {
+			for i := range lines {
+				lines[i] += " " + pad(gaps[i], gapWidth-1)
+			}
+		}
+	}
+	return lines
+}
+
+// renderBody draws the inner lines of a node box: the source with the current line
+// or the heat of every line marked and the side panel with the registers and the mode.
+// In replays, the panel also shows whether the node executed or stalled in the last cycle
+// and a `+` marks the line it executed.
+// Stack memory nodes show their values from the top of the stack down instead.
+func renderBody(nf NodeFrame, bodySize int, opts Options) []string {
+	source := nf.Source
+	panel := []string{"ACC", fmt.Sprint(nf.ACC), "BAK", fmt.Sprintf("(%d)", nf.BAK), "MODE", nf.Mode}
+	if nf.Panel != nil {
+		panel = nf.Panel
+	}
+	ranRow, ranColour := -1, ansiGreen
+	if nf.Ran != "" && nf.Panel == nil {
+		panel = append(panel, "LAST", nf.Ran)
+		ranRow = len(panel) - 1
+		if nf.Ran == RanStalled {
+			ranColour = ansiRed
+		}
+	}
+	if nf.Damaged {
+		source = []string{"", " COMMUNICATION", " FAILURE"}
+		panel = nil
+	}
+	if nf.Memory {
+		source = make([]string, 0, len(nf.Stack))
+		for i := len(nf.Stack) - 1; i >= 0; i-- {
+			source = append(source, fmt.Sprint(nf.Stack[i]))
+		}
+		panel = []string{"STACK", fmt.Sprintf("%d/%d", len(nf.Stack), model.MemoryCapacity)}
+	}
+
+	lines := make([]string, bodySize)
+	for i := range bodySize {
+		code, side := "", ""
+		if i < len(source) {
+			code = source[i]
+		}
+		if i < len(panel) {
+			side = panel[i]
+		}
+
+		marker := " "
+		if nf.Ran == RanExecuted && i == nf.RanLine {
+			marker = highlight("+", ansiGreen, opts)
+		}
+		if i == nf.Line {
+			marker = ">"
+		}
+		if i < len(nf.Heat) && nf.Heat[i] > 0 {
+			marker = highlight(heatGlyphs[nf.Heat[i]], heatColours[nf.Heat[i]], opts)
+		}
+		code = marker + pad(code, codeWidth-1)
+		if i == nf.Line {
+			code = highlight(code, ansiInverse, opts)
+		}
+		side = center(side, panelWidth)
+		if i == ranRow {
+			side = highlight(side, ranColour, opts)
+		}
+		lines[i] = "│" + code + "│" + side + "│"
+	}
+	return lines
+}
+
+// writesTo reports whether the node has a pending write in the given direction.
+func writesTo(nf NodeFrame, port engine.Port) bool {
+	for _, p := range nf.Writes {
+		if p == port {
+			return true
+		}
+	}
+	return false
+}
+
+// joinColumns lays out one cell per grid column, aligned with the node boxes.
+func joinColumns(cells []string) string {
+	var builder strings.Builder
+	for i, cell := range cells {
+		builder.WriteString(center(cell, boxWidth))
+		if i < len(cells)-1 {
+			builder.WriteString(strings.Repeat(" ", gapWidth))
+		}
+	}
+	return builder.String()
+}
+
+// highlight wraps the text in the given ANSI sequence if colours are enabled.
+func highlight(s, sequence string, opts Options) string {
+	if !opts.ANSI {
+		return s
+	}
+	return sequence + s + ansiReset
+}
+
+// pad cuts or right-pads the string to the given display width.
+func pad(s string, width int) string {
+	w := displayWidth(s)
+	if w > width && !strings.Contains(s, "\x1b") {
+		return string([]rune(s)[:width])
+	}
+	if w >= width {
+		return s
+	}
+	return s + strings.Repeat(" ", width-w)
+}
+
+// padLeft left-pads the string to the given display width.
+func padLeft(s string, width int) string {
+	if w := displayWidth(s); w < width {
+		return strings.Repeat(" ", width-w) + s
+	}
+	return s
+}
+
+// center centers the string within the given display width.
+func center(s string, width int) string {
+	w := displayWidth(s)
+	if w >= width {
+		return pad(s, width)
+	}
+	left := (width - w) / 2
+	return strings.Repeat(" ", left) + s + strings.Repeat(" ", width-w-left)
+}
+
+// displayWidth returns the number of terminal cells the string occupies, ignoring ANSI sequences.
+func displayWidth(s string) int {
+	width := 0
+	for i := 0; i < len(s); {
+		if s[i] == '\x1b' {
+			end := strings.IndexByte(s[i:], 'm')
+			if end == -1 {
+				break
+			}
+			i += end + 1
+			continue
+		}
+		_, size := utf8.DecodeRuneInString(s[i:])
+		i += size
+		width++
+	}
+	return width
+}
@@ -0,0 +1,169 @@
+package tui_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lekomish/tis-100/internal/engine"
+	"github.com/lekomish/tis-100/internal/model"
+	"github.com/lekomish/tis-100/internal/tui"
+)
+
+/* TESTS */
+
+// --- Render ---
+func TestRenderGrid(t *testing.T) {
+	eng, puzzle, code := newPipe(t)
+	for range 3 {
+		_, err := eng.Tick()
+		require.NoError(t, err)
+	}
+
+	text := tui.Render(tui.NewFrame(eng, puzzle, code), tui.Options{})
+	require.True(t, strings.HasPrefix(text, "PIPE - cycle 3\n"))
+	require.Contains(t, text, ">MOV UP DOWN", "the current line should be marked")
+	require.Contains(t, text, "COMMUNICATION", "the damaged node should be drawn")
+	require.Contains(t, text, "IN ↓2", "the input should be sending its second value")
+	require.Contains(t, text, "↓1", "the pending write should be drawn between the nodes")
+	require.Contains(t, text, "WRTE")
+	require.Len(t, strings.Split(strings.TrimSpace(text), "\n"), 30)
+	require.NotContains(t, text, "\x1b")
+}
+
+func TestRenderStreams(t *testing.T) {
+	eng, puzzle, code := newPipe(t)
+	eng.Run(engine.RunOptions{})
+
+	text := tui.Render(tui.NewFrame(eng, puzzle, code), tui.Options{})
+	lines := strings.Split(text, "\n")
+	require.True(t, strings.HasPrefix(lines[2], "IN     OUT"))
+	require.True(t, strings.HasPrefix(lines[3], "     1      1     1"))
+	require.True(t, strings.HasPrefix(lines[4], "     2      5    2!"), "wrong values should be flagged")
+}
+
+func TestRenderCheckedStreams(t *testing.T) {
+	eng, puzzle, code := newPipe(t)
+	puzzle.Streams[1].Checked = true
+	eng.Run(engine.RunOptions{})
+
+	f := tui.NewFrame(eng, puzzle, code)
+	require.True(t, f.Outputs[0].Checked)
+
+	lines := strings.Split(tui.Render(f, tui.Options{}), "\n")
+	require.True(t, strings.HasPrefix(lines[4], "     2      5     2 "), "values of checked streams are not compared one by one")
+}
+
+func TestRenderWithANSI(t *testing.T) {
+	eng, puzzle, code := newPipe(t)
+	eng.Run(engine.RunOptions{})
+
+	text := tui.Render(tui.NewFrame(eng, puzzle, code), tui.Options{ANSI: true})
+	require.Contains(t, text, "\x1b[7m>MOV UP DOWN")
+	require.Contains(t, text, "\x1b[31m2!\x1b[0m")
+}
+
+func TestRenderNodeTitle(t *testing.T) {
+	_, puzzle, code := newPipe(t)
+	code.Nodes[4] = []string{"## relay to the output", "MOV UP DOWN # pass"}
+	eng, err := engine.NewEngine(puzzle.Streams, puzzle.Layout, code)
+	require.NoError(t, err)
+
+	text := tui.Render(tui.NewFrame(eng, puzzle, code), tui.Options{})
+	require.Contains(t, text, "┌ relay to the out ─┬", "the title should be cut to fit the border")
+	require.Contains(t, text, "│ ## relay to the ou│", "the title should stay in the source")
+	require.Contains(t, text, "MOV UP DOWN # pass", "comments should be displayed as written")
+}
+
+func TestRenderMemory(t *testing.T) {
+	_, puzzle, code := newPipe(t)
+	puzzle.Layout[1] = model.MEMORY
+	code.Nodes[2] = []string{"MOV 4 LEFT", "MOV 7 LEFT"}
+	eng, err := engine.NewEngine(puzzle.Streams, puzzle.Layout, code)
+	require.NoError(t, err)
+	for range 4 {
+		_, err := eng.Tick()
+		require.NoError(t, err)
+	}
+
+	f := tui.NewFrame(eng, puzzle, code)
+	require.True(t, f.Nodes[1].Memory)
+	require.Equal(t, []int16{4, 7}, f.Nodes[1].Stack)
+
+	text := tui.Render(f, tui.Options{})
+	require.Contains(t, text, "│ 7                 │STACK │", "the top of the stack should come first")
+	require.Contains(t, text, "│ 4                 │ 2/15 │")
+	require.Len(t, strings.Split(strings.TrimSpace(text), "\n"), 57, "the boxes should have room for a full stack")
+}
+
+func TestRenderRan(t *testing.T) {
+	eng, puzzle, code := newPipe(t)
+	f := tui.NewFrame(eng, puzzle, code)
+	f.Nodes[2] = tui.NodeFrame{Source: []string{"MOV UP ACC", "ADD 1"}, Line: 1, Mode: tui.ModeRun, Ran: tui.RanExecuted, RanLine: 0}
+	f.Nodes[3] = tui.NodeFrame{Source: []string{"MOV UP ACC"}, Line: 0, Mode: tui.ModeRead, Ran: tui.RanStalled}
+
+	text := tui.Render(f, tui.Options{})
+	require.Contains(t, text, "│+MOV UP ACC        │", "the executed line should be marked")
+	require.Contains(t, text, "│>MOV UP ACC        │ ACC  │", "a stalled node keeps the current line marker")
+	require.Contains(t, text, "│ RAN  │")
+	require.Contains(t, text, "│STALL │")
+
+	text = tui.Render(f, tui.Options{ANSI: true})
+	require.Contains(t, text, "\x1b[31mSTALL \x1b[0m", "stalled nodes should be highlighted in red")
+}
+
+func TestRenderImage(t *testing.T) {
+	pixels := make([]int16, model.ImageWidth*model.ImageHeight)
+	copy(pixels[model.ImageWidth:], []int16{1, 2, 3, 4, 9})
+
+	lines := tui.RenderImage(pixels, tui.Options{})
+	require.Len(t, lines, model.ImageHeight+2)
+	require.Equal(t, "┌"+strings.Repeat("─", model.ImageWidth)+"┐", lines[0])
+	require.Equal(t, "│"+strings.Repeat(" ", model.ImageWidth)+"│", lines[1])
+	require.Equal(t, "│░▒█▓"+strings.Repeat(" ", model.ImageWidth-4)+"│", lines[2])
+
+	lines = tui.RenderImage(pixels[:model.ImageWidth], tui.Options{ANSI: true})
+	require.Len(t, lines, model.ImageHeight+2, "missing pixels should be drawn black")
+	require.Contains(t, tui.RenderImage(pixels, tui.Options{ANSI: true})[2], "\x1b[31m▓\x1b[0m")
+}
+
+func TestRenderImageStream(t *testing.T) {
+	expected := make([]int16, model.ImageWidth*model.ImageHeight)
+	expected[0] = 3
+	code := &model.Code{Title: "IMAGE", Nodes: make([][]string, model.NodesNumber)}
+	code.Nodes[9] = []string{"MOV 1 DOWN", "MOV 0 DOWN", "MOV 4 DOWN"}
+	puzzle := &model.Puzzle{
+		Title:   "IMAGE",
+		Streams: []*model.Stream{{Type: model.IMAGE, Name: "PICTURE", Position: 1, Values: expected}},
+		Layout:  make([]model.NodeType, model.NodesNumber),
+	}
+	eng, err := engine.NewEngine(puzzle.Streams, puzzle.Layout, code)
+	require.NoError(t, err)
+	eng.Run(engine.RunOptions{MaxCycles: 7})
+
+	f := tui.NewFrame(eng, puzzle, code)
+	require.Empty(t, f.Outputs)
+	require.Len(t, f.Images, 1)
+
+	text := tui.Render(f, tui.Options{})
+	require.Contains(t, text, "PICTURE (expected)                PICTURE (actual)")
+	require.Contains(t, text, "│█"+strings.Repeat(" ", model.ImageWidth-1)+"│  │ ▓")
+	require.Contains(t, text, "↓1 PICTURE", "the value written to the image should be drawn")
+}
+
+// renderStreams -> covered in previous tests
+// renderGrid -> covered in previous tests
+// renderInputArrows -> covered in previous tests
+// renderOutputArrows -> covered in previous tests
+// renderVerticalArrows -> covered in previous tests
+// renderBoxRow -> covered in previous tests
+// renderBody -> covered in previous tests
+// renderImages -> covered in previous tests
+// writesTo -> covered in previous tests
+// joinColumns -> covered in previous tests
+// highlight -> covered in previous tests
+// pad -> covered in previous tests
+// padLeft -> covered in previous tests
+// center -> covered in previous tests
+// displayWidth -> covered in previous tests
@@ -0,0 +1,276 @@
+package tui
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lekomish/tis-100/internal/engine"
+	"github.com/lekomish/tis-100/internal/model"
+)
+
+// DefaultDelay is the pause between two frames while a session is running.
+const DefaultDelay = 100 * time.Millisecond
+
+// prompt lists the commands understood by `Session.Loop`.
+const prompt = "[enter] step | s N | u N back | r run | f fast-forward | b/w/c set breakpoint/watchpoint/condition | q quit > "
+
+// replayPrompt lists the commands understood by `Player.Loop`.
+const replayPrompt = "[enter] step | s N | u N back | r run | f fast-forward | q quit > "
+
+// Session drives an engine interactively: it executes the commands read line by line
+// and redraws the machine after each of them.
+type Session struct {
+	Engine    *engine.Engine
+	Puzzle    *model.Puzzle
+	Code      *model.Code
+	MaxCycles int           // cycle limit of the run (0 means `engine.DefaultMaxCycles`)
+	Delay     time.Duration // pause between two frames while running
+	Options   Options       // rendering options
+
+	result  *engine.Result  // outcome of the run, set once it terminated
+	stop    *engine.Stop    // what paused the run in the last step, if anything
+	history *engine.History // periodic snapshots to step backwards, created by the first step
+}
+
+// Step executes a single cycle. It returns the outcome of the run once it has terminated,
+// a paused result when a breakpoint, condition or watchpoint triggered and nil otherwise.
+// A run paused before the cycle executes it in the next step; a terminated run is not advanced anymore.
+func (s *Session) Step() *engine.Result {
+	if s.result != nil {
+		return s.result
+	}
+	s.record()
+
+	maxCycles := s.MaxCycles
+	if maxCycles <= 0 {
+		maxCycles = engine.DefaultMaxCycles
+	}
+	res := s.Engine.Run(engine.RunOptions{MaxCycles: min(s.Engine.Cycle+1, maxCycles), Debug: true})
+	s.record()
+	s.stop = res.Stop
+	switch {
+	case res.Reason == engine.Paused:
+		return res
+	case res.Reason != engine.CycleLimit || res.Cycles >= maxCycles:
+		s.result = res
+	}
+	return s.result
+}
+
+// Back rewinds the run by the given number of cycles, or to its start if it is shorter.
+func (s *Session) Back(cycles int) error {
+	s.record()
+	if err := s.history.Rewind(s.Engine, max(s.Engine.Cycle-cycles, 0)); err != nil {
+		return err
+	}
+	s.result = nil
+	s.stop = nil
+	return nil
+}
+
+// record snapshots the engine for `Back` when a snapshot is due.
+func (s *Session) record() {
+	if s.history == nil {
+		s.history = engine.NewHistory(engine.DefaultHistoryInterval)
+	}
+	s.history.Record(s.Engine)
+}
+
+// Break parses a breakpoint such as `@5:3 if @5 ACC < 0` and adds it to the engine.
+func (s *Session) Break(spec string) error {
+	b, err := engine.ParseBreakpoint(spec)
+	if err != nil {
+		return err
+	}
+	return s.Engine.AddBreakpoint(*b)
+}
+
+// Watch parses a watchpoint such as `@5 ACC` or `@5 LEFT` and adds it to the engine.
+func (s *Session) Watch(spec string) error {
+	w, err := engine.ParseWatchpoint(spec)
+	if err != nil {
+		return err
+	}
+	return s.Engine.AddWatchpoint(*w)
+}
+
+// When parses a condition such as `@5 ACC < 0` and adds it to the engine.
+func (s *Session) When(spec string) error {
+	c, err := engine.ParseCondition(spec)
+	if err != nil {
+		return err
+	}
+	return s.Engine.AddCondition(*c)
+}
+
+// Frame captures the current state of the session.
+func (s *Session) Frame() *Frame {
+	f := NewFrame(s.Engine, s.Puzzle, s.Code)
+	switch {
+	case s.result != nil:
+		f.Status = fmt.Sprintf("stopped: %s", s.result.Reason)
+		if s.result.Err != nil {
+			f.Status += fmt.Sprintf(": %v", s.result.Err)
+		}
+	case s.stop != nil:
+		f.Status = fmt.Sprintf("paused: %s", s.stop)
+	}
+	return f
+}
+
+// Loop reads commands from in until it is exhausted or `q` is entered, drawing to out.
+// While running or fast-forwarding, entering any line stops the execution.
+func (s *Session) Loop(in io.Reader, out io.Writer) {
+	loop(s, in, out, s.Options, s.Delay)
+}
+
+// step executes a single cycle and reports whether the run stopped.
+func (s *Session) step() bool {
+	return s.Step() != nil
+}
+
+// machine is what `loop` drives: a live session or a recorded run.
+type machine interface {
+	step() bool
+	Back(cycles int) error
+	Frame() *Frame
+}
+
+// debuggable is a machine accepting breakpoints, watchpoints and conditions.
+type debuggable interface {
+	Break(spec string) error
+	Watch(spec string) error
+	When(spec string) error
+}
+
+// loop reads commands from in until it is exhausted or `q` is entered, drawing m to out.
+func loop(m machine, in io.Reader, out io.Writer, opts Options, delay time.Duration) {
+	lines := make(chan string)
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(in)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+	}()
+
+	d, debug := m.(debuggable)
+	p := replayPrompt
+	if debug {
+		p = prompt
+	}
+
+	draw(out, m, opts)
+	for {
+		fmt.Fprint(out, p)
+		line, ok := <-lines
+		if !ok {
+			fmt.Fprintln(out)
+			return
+		}
+
+		fields := strings.Fields(line)
+		cmd, args := "", ""
+		if len(fields) > 0 {
+			cmd = fields[0]
+			args = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), cmd))
+		}
+
+		switch {
+		case cmd == "" || cmd == "s" || cmd == "step":
+			count, ok := parseCount(out, fields)
+			if !ok {
+				continue
+			}
+			for range count {
+				if m.step() {
+					break
+				}
+			}
+			draw(out, m, opts)
+		case cmd == "u" || cmd == "back":
+			count, ok := parseCount(out, fields)
+			if !ok {
+				continue
+			}
+			if err := m.Back(count); err != nil {
+				fmt.Fprintf(out, "%v\n", err)
+				continue
+			}
+			draw(out, m, opts)
+		case cmd == "r" || cmd == "run":
+			run(m, lines, out, opts, delay, true)
+		case cmd == "f" || cmd == "fast-forward":
+			run(m, lines, out, opts, delay, false)
+		case debug && (cmd == "b" || cmd == "break"):
+			set(out, d.Break, args)
+		case debug && (cmd == "w" || cmd == "watch"):
+			set(out, d.Watch, args)
+		case debug && (cmd == "c" || cmd == "when"):
+			set(out, d.When, args)
+		case cmd == "q" || cmd == "quit":
+			return
+		default:
+			fmt.Fprintf(out, "unknown command %q\n", cmd)
+		}
+	}
+}
+
+// parseCount parses the optional number of cycles following a command, 1 by default.
+// Invalid numbers are reported.
+func parseCount(out io.Writer, fields []string) (int, bool) {
+	if len(fields) < 2 {
+		return 1, true
+	}
+	n, err := strconv.Atoi(fields[1])
+	if err != nil || n <= 0 {
+		fmt.Fprintf(out, "invalid number of cycles: %q\n", fields[1])
+		return 0, false
+	}
+	return n, true
+}
+
+// set adds a breakpoint, watchpoint or condition and reports the outcome.
+func set(out io.Writer, add func(spec string) error, spec string) {
+	if err := add(spec); err != nil {
+		fmt.Fprintf(out, "%v\n", err)
+		return
+	}
+	fmt.Fprintf(out, "set %s\n", spec)
+}
+
+// run executes cycles until the run stops or a line is entered.
+// When animated, every cycle is drawn followed by a pause of delay.
+func run(m machine, lines <-chan string, out io.Writer, opts Options, delay time.Duration, animated bool) {
+	for !m.step() {
+		if animated {
+			draw(out, m, opts)
+			fmt.Fprintln(out, "running, press enter to stop")
+			time.Sleep(delay)
+		}
+
+		select {
+		case _, ok := <-lines:
+			if ok {
+				draw(out, m, opts)
+				return
+			}
+			// the input is exhausted and cannot stop the run anymore
+			lines = nil
+		default:
+		}
+	}
+	draw(out, m, opts)
+}
+
+// draw renders the current frame, clearing the screen first if ANSI sequences are enabled.
+func draw(out io.Writer, m machine, opts Options) {
+	if opts.ANSI {
+		fmt.Fprint(out, ansiClear)
+	}
+	fmt.Fprint(out, Render(m.Frame(), opts))
+}
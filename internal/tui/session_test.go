@@ -0,0 +1,156 @@
+package tui_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/lekomish/tis-100/internal/engine"
+	"github.com/lekomish/tis-100/internal/tui"
+)
+
+/* TESTS */
+
+// --- Step ---
+func TestSessionStep(t *testing.T) {
+	s := newSession(t)
+
+	require.Nil(t, s.Step())
+	require.Equal(t, 1, s.Engine.Cycle)
+
+	var res *engine.Result
+	for res == nil {
+		res = s.Step()
+	}
+	require.Equal(t, engine.Mismatch, res.Reason)
+
+	cycles := s.Engine.Cycle
+	require.Same(t, res, s.Step(), "a terminated run should not advance")
+	require.Equal(t, cycles, s.Engine.Cycle)
+	require.Equal(t, "stopped: output mismatch", s.Frame().Status)
+}
+
+func TestSessionStepCycleLimit(t *testing.T) {
+	s := newSession(t)
+	s.MaxCycles = 2
+
+	require.Nil(t, s.Step())
+	res := s.Step()
+	require.NotNil(t, res)
+	require.Equal(t, engine.CycleLimit, res.Reason)
+}
+
+// --- Loop ---
+func TestSessionLoopStep(t *testing.T) {
+	s := newSession(t)
+
+	var out strings.Builder
+	s.Loop(strings.NewReader("\ns 3\ns x\nq\n"), &out)
+	require.Equal(t, 4, s.Engine.Cycle)
+	require.Contains(t, out.String(), "PIPE - cycle 0")
+	require.Contains(t, out.String(), "PIPE - cycle 1")
+	require.Contains(t, out.String(), "PIPE - cycle 4")
+	require.Contains(t, out.String(), "invalid number of cycles: \"x\"")
+}
+
+func TestSessionLoopRun(t *testing.T) {
+	s := newSession(t)
+
+	var out strings.Builder
+	s.Loop(strings.NewReader("r\n"), &out)
+	require.Contains(t, out.String(), "running, press enter to stop")
+	require.Contains(t, out.String(), "stopped: output mismatch")
+}
+
+func TestSessionLoopFastForward(t *testing.T) {
+	s := newSession(t)
+
+	var out strings.Builder
+	s.Loop(strings.NewReader("f\n"), &out)
+	require.NotContains(t, out.String(), "running")
+	require.Contains(t, out.String(), "stopped: output mismatch")
+	require.Equal(t, 2, strings.Count(out.String(), "PIPE - cycle"), "only the first and the last frame should be drawn")
+}
+
+func TestSessionLoopBreakpoint(t *testing.T) {
+	s := newSession(t)
+
+	var out strings.Builder
+	s.Loop(strings.NewReader("b @5:1\nb @2:1\nf\n"), &out)
+	require.Contains(t, out.String(), "set @5:1")
+	require.Contains(t, out.String(), "no instruction at line 1 of node @2")
+	require.Contains(t, out.String(), "paused: breakpoint @5:1")
+	require.Nil(t, s.Step(), "the paused cycle should be executed by the next step")
+}
+
+func TestSessionWatchAndWhen(t *testing.T) {
+	s := newSession(t)
+	require.NoError(t, s.Watch("@9 DOWN"))
+	require.NoError(t, s.When("@1 ACC > 0"))
+	require.Error(t, s.Watch("@9"))
+	require.Error(t, s.When("@1 ACC"))
+
+	var res *engine.Result
+	for res == nil {
+		res = s.Step()
+	}
+	require.Equal(t, engine.Paused, res.Reason)
+	require.Equal(t, "paused: watchpoint @9 DOWN: value 1", s.Frame().Status)
+}
+
+// --- Back ---
+func TestSessionBack(t *testing.T) {
+	s := newSession(t)
+
+	var res *engine.Result
+	for res == nil {
+		res = s.Step()
+	}
+	cycles := s.Engine.Cycle
+
+	require.NoError(t, s.Back(2))
+	require.Equal(t, cycles-2, s.Engine.Cycle)
+	require.Empty(t, s.Frame().Status)
+
+	for res = nil; res == nil; {
+		res = s.Step()
+	}
+	require.Equal(t, cycles, res.Cycles, "the run should end again at the same cycle")
+}
+
+func TestSessionLoopBack(t *testing.T) {
+	s := newSession(t)
+
+	var out strings.Builder
+	s.Loop(strings.NewReader("s 5\nu 2\nu 10\nu x\n"), &out)
+	require.Contains(t, out.String(), "PIPE - cycle 3")
+	require.Equal(t, 0, s.Engine.Cycle)
+	require.Contains(t, out.String(), "invalid number of cycles: \"x\"")
+}
+
+func TestSessionLoopUnknownCommand(t *testing.T) {
+	s := newSession(t)
+
+	var out strings.Builder
+	s.Loop(strings.NewReader("jump\n"), &out)
+	require.Contains(t, out.String(), "unknown command \"jump\"")
+}
+
+// record -> covered in previous tests
+// Break -> covered in previous tests
+// Frame -> covered in previous tests
+// parseCount -> covered in previous tests
+// set -> covered in previous tests
+// run -> covered in previous tests
+// draw -> covered in previous tests
+
+/* UTILS */
+
+// newSession creates a session over the pipe engine without delays.
+func newSession(tb testing.TB) *tui.Session {
+	tb.Helper()
+
+	eng, puzzle, code := newPipe(tb)
+	return &tui.Session{Engine: eng, Puzzle: puzzle, Code: code}
+}
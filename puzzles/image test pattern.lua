@@ -0,0 +1,49 @@
+local STREAM_INPUT = 0
+local STREAM_OUTPUT = 1
+local STREAM_IMAGE = 2
+
+local TILE_COMPUTE = 0
+local TILE_DAMAGED = 1
+local TILE_MEMORY = 2
+
+function GetTitle()
+	return "IMAGE TEST PATTERN"
+end
+
+function GetDescription()
+	return {
+		"> DRAW A WHITE LINE ALONG THE",
+		"  TOP ROW OF THE IMAGE",
+	}
+end
+
+function GetStreams()
+	local image = {}
+	for i = 1, 30 * 18 do
+		image[i] = 0
+	end
+	for x = 1, 30 do
+		image[x] = 3
+	end
+
+	return {
+		{ STREAM_IMAGE, "IMAGE", 0, image },
+	}
+end
+
+function GetLayout()
+	return {
+		TILE_COMPUTE,
+		TILE_COMPUTE,
+		TILE_COMPUTE,
+		TILE_COMPUTE,
+		TILE_COMPUTE,
+		TILE_COMPUTE,
+		TILE_COMPUTE,
+		TILE_COMPUTE,
+		TILE_COMPUTE,
+		TILE_COMPUTE,
+		TILE_COMPUTE,
+		TILE_COMPUTE,
+	}
+end
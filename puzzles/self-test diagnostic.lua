@@ -1,8 +1,10 @@
 local STREAM_INPUT = 0
 local STREAM_OUTPUT = 1
+local STREAM_IMAGE = 2
 
 local TILE_COMPUTE = 0
 local TILE_DAMAGED = 1
+local TILE_MEMORY = 2
 
 function GetTitle()
 	return "SELF-TEST DIAGNOSTIC"
@@ -1,8 +1,11 @@
+-- The STREAM_* and TILE_* constants are predefined; they are declared here for reference.
 local STREAM_INPUT = 0
 local STREAM_OUTPUT = 1
+local STREAM_IMAGE = 2
 
 local TILE_COMPUTE = 0
 local TILE_DAMAGED = 1
+local TILE_MEMORY = 2
 
 -- The function GetTitle should return a string that is the title of the puzzle.
 function GetTitle()
@@ -21,10 +24,20 @@ end
 --
 -- STREAM_INPUT: An input stream containing up to 30 numerical values.
 -- STREAM_OUTPUT: An output stream containing up to 30 numerical values.
+-- STREAM_IMAGE: An image output stream containing the 540 pixels of a 30x18 image,
+-- row by row, with colours between 0 and 4 (black, dark grey, light grey, white, red).
+-- The image is drawn by writing X and Y followed by the colours of consecutive pixels
+-- of the row, until a negative value.
 --
 -- Position values should be between 0 and 3, which correspoind to the far
 -- left and far right of the TIS-100 segment grid. Input streams will be automatically
 -- placed on the top, while output streams will be placed on the bottom.
+--
+-- An output stream may have a fifth value, a function(inputs, values) checking
+-- the values written to the stream instead of comparing them with the expected ones,
+-- for puzzles accepting more than one output (see `puzzles/unordered echo.lua`).
+-- Inputs are the values of every input stream keyed by name. The function returns
+-- whether the values pass, optionally followed by a message explaining why.
 function GetStreams()
 	local input = {}
 	local output = {}
@@ -43,6 +56,8 @@ end
 --
 -- TILE_COMPUTE: A basic execution node.
 -- TILE_DAMAGED: A damaged execution node, which acts as an obstacle.
+-- TILE_MEMORY: A stack memory node, which holds up to 15 values written by its neighbours
+-- and serves them back last in, first out.
 function GetLayout()
 	return {
 		TILE_COMPUTE,
@@ -59,3 +74,7 @@ function GetLayout()
 		TILE_COMPUTE,
 	}
 end
+
+-- The optional function CheckOutputs(inputs, outputs) checks the values written to all
+-- the output streams, keyed by name, like the check of a single stream. Every output stream
+-- is then checked by it instead of being compared with its expected values.
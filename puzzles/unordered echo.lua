@@ -0,0 +1,72 @@
+local STREAM_INPUT = 0
+local STREAM_OUTPUT = 1
+
+local TILE_COMPUTE = 0
+local TILE_DAMAGED = 1
+local TILE_MEMORY = 2
+
+function GetTitle()
+	return "UNORDERED ECHO"
+end
+
+function GetDescription()
+	return {
+		"> READ VALUES FROM IN AND",
+		"  WRITE THEM TO OUT IN ANY ORDER",
+	}
+end
+
+-- isPermutation reports whether values holds the expected values in any order.
+local function isPermutation(values, expected)
+	if #values ~= #expected then
+		return false
+	end
+	local counts = {}
+	for _, value in ipairs(expected) do
+		counts[value] = (counts[value] or 0) + 1
+	end
+	for _, value in ipairs(values) do
+		if not counts[value] or counts[value] == 0 then
+			return false
+		end
+		counts[value] = counts[value] - 1
+	end
+	return true
+end
+
+function GetStreams()
+	local input = {}
+	local output = {}
+	for i = 1, 15 do
+		input[i] = math.random(10, 99)
+		output[i] = input[i]
+	end
+	table.sort(output)
+
+	return {
+		{ STREAM_INPUT, "IN", 1, input },
+		{ STREAM_OUTPUT, "OUT", 1, output, function(inputs, values)
+			if isPermutation(values, inputs["IN"]) then
+				return true
+			end
+			return false, "OUT does not hold the values of IN"
+		end },
+	}
+end
+
+function GetLayout()
+	return {
+		TILE_COMPUTE,
+		TILE_COMPUTE,
+		TILE_COMPUTE,
+		TILE_DAMAGED,
+		TILE_COMPUTE,
+		TILE_COMPUTE,
+		TILE_MEMORY,
+		TILE_COMPUTE,
+		TILE_COMPUTE,
+		TILE_COMPUTE,
+		TILE_COMPUTE,
+		TILE_COMPUTE,
+	}
+end